@@ -1,16 +1,46 @@
 package main
 
 import (
+	"api/internal/config"
+	"api/internal/lifecycle"
+	"api/internal/logging"
 	server "api/internal/src"
-	"fmt"
+	"api/internal/tracing"
+	"context"
+	"log"
+	"log/slog"
+	"os"
 )
 
 func main() {
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// the log package goes through the same JSON handler from here on
+	level, _ := logging.ParseLevel(cfg.Log.Level)
+	slog.SetDefault(logging.New(os.Stdout, level))
+
+	lc := lifecycle.New(cfg.Server.ShutdownTimeout, cfg.Server.ShutdownDelay)
 
-	server := server.NewServer()
+	shutdownTracing, err := tracing.Setup(context.Background(), cfg.Tracing)
+	if err != nil {
+		log.Fatal(err)
+	}
+	// registered first so it is closed last, after the spans of draining
+	lc.OnClose("tracing", func() error {
+		ctx, cancel := context.WithTimeout(context.Background(), cfg.Server.ShutdownTimeout)
+		defer cancel()
+		return shutdownTracing(ctx)
+	})
 
-	err := server.ListenAndServe()
+	server := server.NewServer(cfg, lc)
+
+	err = lc.Run(server)
 	if err != nil {
-		panic(fmt.Sprintf("cannot start server: %s", err))
+		log.Fatalf("server stopped: %v", err)
 	}
+
+	slog.Info("server-stopped")
 }
@@ -0,0 +1,120 @@
+package apperr
+
+import (
+	"errors"
+	"net/http"
+)
+
+// Code classifies an Error. Each code maps to one HTTP status and is part of
+// the public API, clients may switch on it.
+type Code string
+
+const (
+	CodeValidation   Code = "validation"
+	CodeUnauthorized Code = "unauthorized"
+	CodeForbidden    Code = "forbidden"
+	CodeNotFound     Code = "not_found"
+	CodeConflict     Code = "conflict"
+	CodeTooLarge     Code = "too_large"
+	CodeInternal     Code = "internal"
+)
+
+var statuses = map[Code]int{
+	CodeValidation:   http.StatusBadRequest,
+	CodeUnauthorized: http.StatusUnauthorized,
+	CodeForbidden:    http.StatusForbidden,
+	CodeNotFound:     http.StatusNotFound,
+	CodeConflict:     http.StatusConflict,
+	CodeTooLarge:     http.StatusRequestEntityTooLarge,
+	CodeInternal:     http.StatusInternalServerError,
+}
+
+func (c Code) Status() int {
+	if status, ok := statuses[c]; ok {
+		return status
+	}
+	return http.StatusInternalServerError
+}
+
+type FieldError struct {
+	Field  string
+	Reason string
+}
+
+// Error is an application error. Message is a stable kebab-case identifier
+// such as "missing-car-name", Err keeps the underlying cause for logging and
+// is never shown to clients.
+type Error struct {
+	Code    Code
+	Message string
+	Fields  []FieldError
+	Err     error
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Validation reports a bad input on field, which may be empty when the
+// problem is not tied to a single field.
+func Validation(message, field string) *Error {
+	e := &Error{Code: CodeValidation, Message: message}
+	if field != "" {
+		e.Fields = []FieldError{{Field: field, Reason: message}}
+	}
+	return e
+}
+
+// InvalidRequest reports a body or query string that could not be bound to
+// the request model at all.
+func InvalidRequest(err error) *Error {
+	return &Error{
+		Code:    CodeValidation,
+		Message: "invalid-request",
+		Fields:  []FieldError{{Field: "request", Reason: err.Error()}},
+		Err:     err,
+	}
+}
+
+func Unauthorized(message string) *Error {
+	return &Error{Code: CodeUnauthorized, Message: message}
+}
+
+func Forbidden(message string) *Error {
+	return &Error{Code: CodeForbidden, Message: message}
+}
+
+func NotFound(message string) *Error {
+	return &Error{Code: CodeNotFound, Message: message}
+}
+
+func Conflict(message string) *Error {
+	return &Error{Code: CodeConflict, Message: message}
+}
+
+func TooLarge(message string) *Error {
+	return &Error{Code: CodeTooLarge, Message: message}
+}
+
+func Internal(err error) *Error {
+	return &Error{Code: CodeInternal, Message: "internal-error", Err: err}
+}
+
+// Wrap attaches cause to e, for errors translated from a lower layer.
+func (e *Error) Wrap(cause error) *Error {
+	e.Err = cause
+	return e
+}
+
+// From returns err as an *Error, treating anything unclassified as internal.
+func From(err error) *Error {
+	var appErr *Error
+	if errors.As(err, &appErr) {
+		return appErr
+	}
+	return Internal(err)
+}
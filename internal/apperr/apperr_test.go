@@ -0,0 +1,57 @@
+package apperr_test
+
+import (
+	"api/internal/apperr"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Middleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	cases := []struct {
+		err     error
+		status  int
+		code    string
+		message string
+		details int
+	}{
+		{apperr.Validation("missing-car-name", "car_name"), http.StatusBadRequest, "validation", "missing-car-name", 1},
+		{apperr.NotFound("car-not-found"), http.StatusNotFound, "not_found", "car-not-found", 0},
+		{apperr.Conflict("car-already-occupied").Wrap(sql.ErrTxDone), http.StatusConflict, "conflict", "car-already-occupied", 0},
+		{fmt.Errorf("booking: %w", apperr.Forbidden("forbidden")), http.StatusForbidden, "forbidden", "forbidden", 0},
+		{sql.ErrNoRows, http.StatusInternalServerError, "internal", "internal-error", 0},
+	}
+
+	for _, tc := range cases {
+		r := gin.New()
+		r.Use(apperr.Middleware())
+		r.GET("/", func(c *gin.Context) {
+			c.Error(tc.err)
+		})
+
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+
+		var body struct {
+			Code    string `json:"code"`
+			Message string `json:"message"`
+			Details []struct {
+				Field  string `json:"field"`
+				Reason string `json:"reason"`
+			} `json:"details"`
+		}
+		assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &body))
+		assert.Equal(t, tc.status, w.Code, tc.message)
+		assert.Equal(t, tc.code, body.Code)
+		assert.Equal(t, tc.message, body.Message)
+		assert.Len(t, body.Details, tc.details)
+	}
+}
@@ -0,0 +1,39 @@
+package apperr
+
+import (
+	"api/internal/logging"
+	"api/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Middleware renders the last error a handler attached with c.Error as the
+// shared error envelope, with the status of its Code. Internal errors are
+// logged here and nowhere else.
+func Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Next()
+
+		if len(c.Errors) == 0 || c.Writer.Written() {
+			return
+		}
+
+		appErr := From(c.Errors.Last().Err)
+		if appErr.Code == CodeInternal {
+			logging.From(c).Error("internal-error", "error", appErr.Err)
+		}
+
+		resp := &models.ResponseError{
+			Code:    string(appErr.Code),
+			Message: appErr.Message,
+		}
+		for _, f := range appErr.Fields {
+			resp.Details = append(resp.Details, &models.ResponseErrorDetail{
+				Field:  f.Field,
+				Reason: f.Reason,
+			})
+		}
+
+		c.AbortWithStatusJSON(appErr.Code.Status(), resp)
+	}
+}
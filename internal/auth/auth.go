@@ -0,0 +1,99 @@
+package auth
+
+import (
+	"api/internal/apperr"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"golang.org/x/crypto/bcrypt"
+)
+
+type Role string
+
+const (
+	RoleAdmin    Role = "admin"
+	RoleStaff    Role = "staff"
+	RoleCustomer Role = "customer"
+)
+
+func ParseRole(s string) (Role, error) {
+	switch r := Role(s); r {
+	case RoleAdmin, RoleStaff, RoleCustomer:
+		return r, nil
+	}
+	return "", apperr.Validation("invalid-role", "role")
+}
+
+// Claims is the payload of the access tokens issued at login. CustomerId is
+// set for customer accounts only and scopes what they can see.
+type Claims struct {
+	UserId     int  `json:"uid"`
+	Role       Role `json:"role"`
+	CustomerId int  `json:"cid,omitempty"`
+	jwt.RegisteredClaims
+}
+
+type Manager struct {
+	secret []byte
+	ttl    time.Duration
+}
+
+func NewManager(secret string, ttl time.Duration) *Manager {
+	return &Manager{
+		secret: []byte(secret),
+		ttl:    ttl,
+	}
+}
+
+func (m *Manager) Issue(userId int, role Role, customerId int) (string, time.Time, error) {
+	if role == RoleCustomer && customerId == 0 {
+		return "", time.Time{}, errors.New("missing-customer-id")
+	}
+
+	now := time.Now()
+	expiresAt := now.Add(m.ttl)
+
+	claims := &Claims{
+		UserId:     userId,
+		Role:       role,
+		CustomerId: customerId,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   strconv.Itoa(userId),
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
+		},
+	}
+
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(m.secret)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	return token, expiresAt, nil
+}
+
+func (m *Manager) Verify(token string) (*Claims, error) {
+	claims := &Claims{}
+	_, err := jwt.ParseWithClaims(token, claims, func(t *jwt.Token) (interface{}, error) {
+		return m.secret, nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}), jwt.WithExpirationRequired())
+	if err != nil {
+		return nil, err
+	}
+
+	return claims, nil
+}
+
+func HashPassword(password string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return "", err
+	}
+	return string(hash), nil
+}
+
+func CheckPassword(hash, password string) bool {
+	return bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)) == nil
+}
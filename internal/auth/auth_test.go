@@ -0,0 +1,41 @@
+package auth_test
+
+import (
+	"api/internal/auth"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_IssueAndVerify(t *testing.T) {
+	m := auth.NewManager("secret", time.Minute)
+
+	token, expiresAt, err := m.Issue(7, auth.RoleCustomer, 42)
+	assert.Nil(t, err)
+	assert.True(t, expiresAt.After(time.Now()))
+
+	claims, err := m.Verify(token)
+	assert.Nil(t, err)
+	assert.Equal(t, 7, claims.UserId)
+	assert.Equal(t, auth.RoleCustomer, claims.Role)
+	assert.Equal(t, 42, claims.CustomerId)
+
+	_, err = auth.NewManager("other-secret", time.Minute).Verify(token)
+	assert.NotNil(t, err)
+
+	expired, _, err := auth.NewManager("secret", -time.Minute).Issue(7, auth.RoleAdmin, 0)
+	assert.Nil(t, err)
+	_, err = m.Verify(expired)
+	assert.NotNil(t, err)
+
+	_, _, err = m.Issue(7, auth.RoleCustomer, 0)
+	assert.EqualError(t, err, "missing-customer-id")
+}
+
+func Test_Password(t *testing.T) {
+	hash, err := auth.HashPassword("correct horse")
+	assert.Nil(t, err)
+	assert.True(t, auth.CheckPassword(hash, "correct horse"))
+	assert.False(t, auth.CheckPassword(hash, "battery staple"))
+}
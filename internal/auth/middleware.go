@@ -0,0 +1,76 @@
+package auth
+
+import (
+	"api/internal/apperr"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+const claimsKey = "auth.claims"
+
+// Middleware rejects requests without a valid bearer token and stores the
+// token claims on the gin context for RequireRole and the controllers.
+func (m *Manager) Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		header := c.GetHeader("Authorization")
+		token, found := strings.CutPrefix(header, "Bearer ")
+		if !found || token == "" {
+			c.Error(apperr.Unauthorized("missing-token"))
+			c.Abort()
+			return
+		}
+
+		claims, err := m.Verify(token)
+		if err != nil {
+			c.Error(apperr.Unauthorized("invalid-token").Wrap(err))
+			c.Abort()
+			return
+		}
+
+		c.Set(claimsKey, claims)
+		c.Next()
+	}
+}
+
+// RequireRole lets the request through only when the authenticated user has
+// one of roles. It must run after Middleware.
+func RequireRole(roles ...Role) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		claims, ok := ClaimsFrom(c)
+		if !ok {
+			c.Error(apperr.Unauthorized("missing-token"))
+			c.Abort()
+			return
+		}
+
+		for _, role := range roles {
+			if claims.Role == role {
+				c.Next()
+				return
+			}
+		}
+
+		c.Error(apperr.Forbidden("forbidden"))
+		c.Abort()
+	}
+}
+
+func ClaimsFrom(c *gin.Context) (*Claims, bool) {
+	v, ok := c.Get(claimsKey)
+	if !ok {
+		return nil, false
+	}
+	claims, ok := v.(*Claims)
+	return claims, ok
+}
+
+// CustomerScope reports whether the caller is a customer, and if so the id of
+// the customer whose data the request is restricted to.
+func CustomerScope(c *gin.Context) (int, bool) {
+	claims, ok := ClaimsFrom(c)
+	if !ok || claims.Role != RoleCustomer {
+		return 0, false
+	}
+	return claims.CustomerId, true
+}
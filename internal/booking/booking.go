@@ -0,0 +1,97 @@
+package booking
+
+import (
+	"api/internal/apperr"
+	"fmt"
+	"time"
+)
+
+const DateLayout = "2006-01-02"
+
+// TimeLayout is how pickup and dropoff times are read and written.
+const TimeLayout = time.RFC3339
+
+// Range is a half-open rental interval [Start, End): a car returned at some
+// time can be picked up again by the next customer from that time on.
+type Range struct {
+	Start time.Time
+	End   time.Time
+	// WholeDays is set when both ends were given as bare dates, the rental
+	// then runs from the start of one day to the start of another.
+	WholeDays bool
+}
+
+// ParseTime reads an RFC3339 timestamp, or a bare date meaning midnight UTC,
+// and drops anything finer than a minute.
+func ParseTime(value string) (time.Time, error) {
+	t, err := time.Parse(TimeLayout, value)
+	if err != nil {
+		t, err = time.Parse(DateLayout, value)
+	}
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	return t.Truncate(time.Minute), nil
+}
+
+// FormatTime writes t in TimeLayout, in UTC.
+func FormatTime(t time.Time) string {
+	return t.UTC().Format(TimeLayout)
+}
+
+func isDate(value string) bool {
+	_, err := time.Parse(DateLayout, value)
+	return err == nil
+}
+
+func ParseRange(pickup, dropoff string) (Range, error) {
+	start, err := ParseTime(pickup)
+	if err != nil {
+		return Range{}, apperr.Validation("failed-parsing-pickup-date", "pickup_date")
+	}
+
+	end, err := ParseTime(dropoff)
+	if err != nil {
+		return Range{}, apperr.Validation("failed-parsing-dropoff-date", "dropoff_date")
+	}
+
+	r, err := NewRange(start, end)
+	if err != nil {
+		return Range{}, err
+	}
+
+	r.WholeDays = isDate(pickup) && isDate(dropoff)
+	return r, nil
+}
+
+func NewRange(start, end time.Time) (Range, error) {
+	if !end.After(start) {
+		return Range{}, apperr.Validation("invalid-date-range", "dropoff_date")
+	}
+
+	return Range{Start: start, End: end}, nil
+}
+
+// Pad widens r by buffer on both sides. Checking a padded range against the
+// stored ones keeps buffer free between consecutive rentals of a car, for
+// the car to be cleaned and checked. Whole day ranges stay as they are, a
+// car dropped off on a day can be picked up that day.
+func (r Range) Pad(buffer time.Duration) Range {
+	if r.WholeDays {
+		return r
+	}
+
+	return Range{Start: r.Start.Add(-buffer), End: r.End.Add(buffer)}
+}
+
+func (r Range) Overlaps(other Range) bool {
+	return r.Start.Before(other.End) && other.Start.Before(r.End)
+}
+
+// OverlapCondition renders the SQL equivalent of Overlaps for rows holding a
+// [startCol, endCol) range, compared against the range bound to the given
+// placeholders.
+func OverlapCondition(startCol, endCol string, startArg, endArg int) string {
+	return fmt.Sprintf("%s < $%d AND %s > $%d", startCol, endArg, endCol, startArg)
+}
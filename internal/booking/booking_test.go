@@ -0,0 +1,90 @@
+package booking_test
+
+import (
+	"api/internal/booking"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_ParseRange(t *testing.T) {
+	r, err := booking.ParseRange("2024-01-03", "2024-01-10")
+	assert.Nil(t, err)
+	assert.Equal(t, 7*24.0, r.End.Sub(r.Start).Hours())
+
+	_, err = booking.ParseRange("2024-01-10", "2024-01-10")
+	assert.EqualError(t, err, "invalid-date-range")
+
+	_, err = booking.ParseRange("2024-01-10", "2024-01-03")
+	assert.EqualError(t, err, "invalid-date-range")
+
+	_, err = booking.ParseRange("03-01-2024", "2024-01-10")
+	assert.EqualError(t, err, "failed-parsing-pickup-date")
+
+	_, err = booking.ParseRange("2024-01-03", "")
+	assert.EqualError(t, err, "failed-parsing-dropoff-date")
+}
+
+func Test_Overlaps(t *testing.T) {
+	existing, _ := booking.ParseRange("2024-01-05", "2024-01-10")
+
+	cases := []struct {
+		pickup, dropoff string
+		overlaps        bool
+	}{
+		{"2024-01-01", "2024-01-05", false}, // ends the day the other starts
+		{"2024-01-10", "2024-01-12", false}, // starts the day the other ends
+		{"2024-01-01", "2024-01-04", false},
+		{"2024-01-04", "2024-01-06", true},
+		{"2024-01-09", "2024-01-12", true},
+		{"2024-01-06", "2024-01-07", true},
+		{"2024-01-01", "2024-01-20", true},
+	}
+
+	for _, tc := range cases {
+		r, err := booking.ParseRange(tc.pickup, tc.dropoff)
+		assert.Nil(t, err)
+		assert.Equal(t, tc.overlaps, r.Overlaps(existing), "%s..%s", tc.pickup, tc.dropoff)
+		assert.Equal(t, tc.overlaps, existing.Overlaps(r), "%s..%s", tc.pickup, tc.dropoff)
+	}
+}
+
+func Test_OverlapCondition(t *testing.T) {
+	assert.Equal(t, "pickup_date < $3 AND dropoff_date > $2", booking.OverlapCondition("pickup_date", "dropoff_date", 2, 3))
+}
+
+func Test_ParseRangeTimestamps(t *testing.T) {
+	r, err := booking.ParseRange("2024-01-03T09:30:45+07:00", "2024-01-03T14:00:00+07:00")
+	assert.Nil(t, err)
+	assert.False(t, r.WholeDays)
+	assert.Equal(t, "2024-01-03T02:30:00Z", booking.FormatTime(r.Start))
+	assert.Equal(t, 270.0, r.End.Sub(r.Start).Minutes())
+
+	r, err = booking.ParseRange("2024-01-03", "2024-01-04")
+	assert.Nil(t, err)
+	assert.True(t, r.WholeDays)
+
+	_, err = booking.ParseRange("2024-01-03T10:00:00Z", "2024-01-03T10:00:30Z")
+	assert.EqualError(t, err, "invalid-date-range")
+}
+
+func Test_Pad(t *testing.T) {
+	existing, _ := booking.ParseRange("2024-01-05T10:00:00Z", "2024-01-05T12:00:00Z")
+
+	next, _ := booking.ParseRange("2024-01-05T12:00:00Z", "2024-01-05T15:00:00Z")
+	assert.False(t, next.Overlaps(existing))
+	assert.True(t, next.Pad(30*time.Minute).Overlaps(existing))
+
+	later, _ := booking.ParseRange("2024-01-05T12:30:00Z", "2024-01-05T15:00:00Z")
+	assert.False(t, later.Pad(30*time.Minute).Overlaps(existing))
+
+	before, _ := booking.ParseRange("2024-01-05T08:00:00Z", "2024-01-05T09:45:00Z")
+	assert.True(t, before.Pad(30*time.Minute).Overlaps(existing))
+
+	// back to back whole day rentals take no turnaround
+	days, _ := booking.ParseRange("2024-01-01", "2024-01-05")
+	nextDays, _ := booking.ParseRange("2024-01-05", "2024-01-07")
+	assert.Equal(t, nextDays, nextDays.Pad(30*time.Minute))
+	assert.False(t, nextDays.Pad(30*time.Minute).Overlaps(days))
+}
@@ -0,0 +1,35 @@
+package buildinfo
+
+import (
+	"api/internal/models"
+	"runtime/debug"
+	"sync"
+)
+
+// Version is stamped at build time, see the Makefile.
+var Version = "dev"
+
+// Get reports Version together with what the Go toolchain recorded about
+// the build.
+var Get = sync.OnceValue(func() models.BuildInfo {
+	info := models.BuildInfo{Version: Version}
+
+	build, ok := debug.ReadBuildInfo()
+	if !ok {
+		return info
+	}
+
+	info.GoVersion = build.GoVersion
+	for _, setting := range build.Settings {
+		switch setting.Key {
+		case "vcs.revision":
+			info.Revision = setting.Value
+		case "vcs.time":
+			info.BuildTime = setting.Value
+		case "vcs.modified":
+			info.Modified = setting.Value == "true"
+		}
+	}
+
+	return info
+})
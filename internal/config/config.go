@@ -0,0 +1,355 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	_ "github.com/joho/godotenv/autoload"
+	"gopkg.in/yaml.v3"
+)
+
+// Config is every setting of the API. Load fills it from the defaults, then
+// the YAML file named by CONFIG_FILE if any, then the environment variables
+// named in the env tags, each overriding the one before.
+//
+// Durations are written like "30s" or "5m". The settings that always took a
+// number of minutes or seconds still accept a bare number in that unit.
+type Config struct {
+	Server   ServerConfig   `yaml:"server"`
+	Database DatabaseConfig `yaml:"database"`
+	Auth     AuthConfig     `yaml:"auth"`
+	Images   ImagesConfig   `yaml:"images"`
+	Rentals  RentalsConfig  `yaml:"rentals"`
+	Log      LogConfig      `yaml:"log"`
+	Tracing  TracingConfig  `yaml:"tracing"`
+}
+
+type ServerConfig struct {
+	Port         int           `yaml:"port" env:"PORT"`
+	ReadTimeout  time.Duration `yaml:"read_timeout" env:"SERVER_READ_TIMEOUT"`
+	WriteTimeout time.Duration `yaml:"write_timeout" env:"SERVER_WRITE_TIMEOUT"`
+	IdleTimeout  time.Duration `yaml:"idle_timeout" env:"SERVER_IDLE_TIMEOUT"`
+	// ShutdownTimeout bounds how long in-flight requests get on shutdown,
+	// ShutdownDelay is how long the instance reports not ready before that
+	ShutdownTimeout time.Duration `yaml:"shutdown_timeout" env:"SHUTDOWN_TIMEOUT_SECONDS" unit:"s"`
+	ShutdownDelay   time.Duration `yaml:"shutdown_delay" env:"SHUTDOWN_DELAY_SECONDS" unit:"s"`
+	// CORSOrigins are the origins browsers may call from, comma separated in
+	// the environment, every origin when empty
+	CORSOrigins []string `yaml:"cors_origins" env:"CORS_ORIGINS"`
+}
+
+type DatabaseConfig struct {
+	Host     string `yaml:"host" env:"DB_HOST"`
+	Port     int    `yaml:"port" env:"DB_PORT"`
+	Name     string `yaml:"name" env:"DB_DATABASE"`
+	User     string `yaml:"user" env:"DB_USERNAME"`
+	Password string `yaml:"password" env:"DB_PASSWORD"`
+	SSLMode  string `yaml:"sslmode" env:"DB_SSLMODE"`
+	// MaxOpenConns of 0 leaves the pool unbounded
+	MaxOpenConns    int           `yaml:"max_open_conns" env:"DB_MAX_OPEN_CONNS"`
+	MaxIdleConns    int           `yaml:"max_idle_conns" env:"DB_MAX_IDLE_CONNS"`
+	ConnMaxLifetime time.Duration `yaml:"conn_max_lifetime" env:"DB_CONN_MAX_LIFETIME"`
+	ConnMaxIdleTime time.Duration `yaml:"conn_max_idle_time" env:"DB_CONN_MAX_IDLE_TIME"`
+	// MigrationsPath is the directory of the migrations, relative to the
+	// working directory unless absolute
+	MigrationsPath string `yaml:"migrations_path" env:"DB_MIGRATIONS_PATH"`
+}
+
+// DSN is the URL to connect to the database with.
+func (c DatabaseConfig) DSN() string {
+	dsn := url.URL{
+		Scheme:   "postgres",
+		User:     url.UserPassword(c.User, c.Password),
+		Host:     fmt.Sprintf("%s:%d", c.Host, c.Port),
+		Path:     c.Name,
+		RawQuery: url.Values{"sslmode": {c.SSLMode}}.Encode(),
+	}
+
+	return dsn.String()
+}
+
+type AuthConfig struct {
+	JWTSecret string        `yaml:"jwt_secret" env:"JWT_SECRET"`
+	JWTTTL    time.Duration `yaml:"jwt_ttl" env:"JWT_TTL_MINUTES" unit:"m"`
+	// AdminEmail and AdminPassword create the first admin on an empty users
+	// table. There is no default password, nothing is created until one is
+	// set explicitly
+	AdminEmail    string `yaml:"admin_email" env:"ADMIN_EMAIL"`
+	AdminPassword string `yaml:"admin_password" env:"ADMIN_PASSWORD"`
+}
+
+type ImagesConfig struct {
+	// Store is "local" or "s3"
+	Store       string `yaml:"store" env:"IMAGE_STORE"`
+	LocalDir    string `yaml:"local_dir" env:"IMAGE_LOCAL_DIR"`
+	BaseURL     string `yaml:"base_url" env:"IMAGE_BASE_URL"`
+	S3Endpoint  string `yaml:"s3_endpoint" env:"S3_ENDPOINT"`
+	S3AccessKey string `yaml:"s3_access_key" env:"S3_ACCESS_KEY"`
+	S3SecretKey string `yaml:"s3_secret_key" env:"S3_SECRET_KEY"`
+	S3Bucket    string `yaml:"s3_bucket" env:"S3_BUCKET"`
+	S3UseSSL    bool   `yaml:"s3_use_ssl" env:"S3_USE_SSL"`
+}
+
+type RentalsConfig struct {
+	// Turnaround is kept free between consecutive rentals of a car
+	Turnaround time.Duration `yaml:"turnaround" env:"TURNAROUND_MINUTES" unit:"m"`
+}
+
+type LogConfig struct {
+	// Level is debug, info, warn or error
+	Level string `yaml:"level" env:"LOG_LEVEL"`
+}
+
+type TracingConfig struct {
+	// Exporter is "none", "otlp" or "stdout"
+	Exporter string `yaml:"exporter" env:"TRACING_EXPORTER"`
+	// Endpoint is the URL of the OTLP/HTTP collector, such as
+	// http://localhost:4318
+	Endpoint    string `yaml:"endpoint" env:"OTEL_EXPORTER_OTLP_ENDPOINT"`
+	ServiceName string `yaml:"service_name" env:"OTEL_SERVICE_NAME"`
+}
+
+// Default is the configuration before the file and environment apply.
+func Default() Config {
+	return Config{
+		Server: ServerConfig{
+			Port:            8080,
+			ReadTimeout:     10 * time.Second,
+			WriteTimeout:    30 * time.Second,
+			IdleTimeout:     time.Minute,
+			ShutdownTimeout: 30 * time.Second,
+		},
+		Database: DatabaseConfig{
+			Host:            "localhost",
+			Port:            5432,
+			SSLMode:         "disable",
+			MaxOpenConns:    25,
+			MaxIdleConns:    5,
+			ConnMaxLifetime: 30 * time.Minute,
+			ConnMaxIdleTime: 5 * time.Minute,
+			MigrationsPath:  "migrations",
+		},
+		Auth: AuthConfig{
+			JWTTTL: time.Hour,
+		},
+		Images: ImagesConfig{
+			Store:    "local",
+			LocalDir: "uploads",
+		},
+		Log: LogConfig{
+			Level: "info",
+		},
+		Tracing: TracingConfig{
+			Exporter:    "none",
+			ServiceName: "car-rents-backend",
+		},
+	}
+}
+
+// Error lists every setting that is wrong, so they can all be fixed at once.
+type Error struct {
+	Problems []string
+}
+
+func (e *Error) Error() string {
+	return "invalid configuration:\n  " + strings.Join(e.Problems, "\n  ")
+}
+
+// Load reads the configuration, failing with an *Error when any setting
+// is invalid.
+func Load() (*Config, error) {
+	cfg := Default()
+
+	if path := os.Getenv("CONFIG_FILE"); path != "" {
+		err := readFile(path, &cfg)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var problems []string
+	readEnv(reflect.ValueOf(&cfg).Elem(), &problems)
+	problems = append(problems, cfg.validate()...)
+	if len(problems) > 0 {
+		return nil, &Error{Problems: problems}
+	}
+
+	return &cfg, nil
+}
+
+func readFile(path string, cfg *Config) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	// a misspelled key fails instead of being ignored
+	decoder := yaml.NewDecoder(file)
+	decoder.KnownFields(true)
+
+	err = decoder.Decode(cfg)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("reading %s: %w", path, err)
+	}
+
+	return nil
+}
+
+// readEnv sets the fields of v that have their env variable set, noting the
+// values that do not parse in problems.
+func readEnv(v reflect.Value, problems *[]string) {
+	for i := 0; i < v.NumField(); i++ {
+		field := v.Type().Field(i)
+		value := v.Field(i)
+		if field.Type.Kind() == reflect.Struct {
+			readEnv(value, problems)
+			continue
+		}
+
+		name := field.Tag.Get("env")
+		raw := strings.TrimSpace(os.Getenv(name))
+		if name == "" || raw == "" {
+			continue
+		}
+
+		err := setValue(value, raw, field.Tag.Get("unit"))
+		if err != nil {
+			*problems = append(*problems, fmt.Sprintf("%s: %s", name, err))
+		}
+	}
+}
+
+var units = map[string]time.Duration{
+	"s": time.Second,
+	"m": time.Minute,
+}
+
+func setValue(value reflect.Value, raw, unit string) error {
+	switch value.Interface().(type) {
+	case time.Duration:
+		if n, err := strconv.Atoi(raw); err == nil && unit != "" {
+			value.SetInt(int64(time.Duration(n) * units[unit]))
+			return nil
+		}
+
+		d, err := time.ParseDuration(raw)
+		if err != nil {
+			return errors.New("not a duration such as 30s")
+		}
+		value.SetInt(int64(d))
+	case int:
+		n, err := strconv.Atoi(raw)
+		if err != nil {
+			return errors.New("not a whole number")
+		}
+		value.SetInt(int64(n))
+	case bool:
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return errors.New("not true or false")
+		}
+		value.SetBool(b)
+	case string:
+		value.SetString(raw)
+	case []string:
+		var list []string
+		for _, item := range strings.Split(raw, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				list = append(list, item)
+			}
+		}
+		value.Set(reflect.ValueOf(list))
+	}
+
+	return nil
+}
+
+// minJWTSecret is the size of the HS256 key the tokens are signed with.
+const minJWTSecret = 32
+
+// exampleJWTSecret is what the env file ships with, anyone can read it and
+// sign admin tokens.
+const exampleJWTSecret = "change-me-to-a-long-random-string"
+
+// minAdminPassword matches the length asked of the passwords of the accounts
+// created through the API.
+const minAdminPassword = 8
+
+var (
+	sslModes         = []string{"disable", "allow", "prefer", "require", "verify-ca", "verify-full"}
+	logLevels        = []string{"debug", "info", "warn", "error"}
+	tracingExporters = []string{"none", "otlp", "stdout"}
+)
+
+func (c *Config) validate() []string {
+	var problems []string
+	check := func(ok bool, name, problem string) {
+		if !ok {
+			problems = append(problems, name+": "+problem)
+		}
+	}
+
+	check(c.Server.Port > 0 && c.Server.Port <= 65535, "PORT", "must be between 1 and 65535")
+	check(c.Server.ReadTimeout > 0, "SERVER_READ_TIMEOUT", "must be positive")
+	check(c.Server.WriteTimeout > 0, "SERVER_WRITE_TIMEOUT", "must be positive")
+	check(c.Server.IdleTimeout > 0, "SERVER_IDLE_TIMEOUT", "must be positive")
+	check(c.Server.ShutdownTimeout > 0, "SHUTDOWN_TIMEOUT_SECONDS", "must be positive")
+	check(c.Server.ShutdownDelay >= 0, "SHUTDOWN_DELAY_SECONDS", "must not be negative")
+	for _, origin := range c.Server.CORSOrigins {
+		parsed, err := url.Parse(origin)
+		check(origin == "*" || (err == nil && (parsed.Scheme == "http" || parsed.Scheme == "https") && parsed.Host != "" && parsed.Path == ""),
+			"CORS_ORIGINS", fmt.Sprintf("%q is not * or an origin such as https://example.com", origin))
+	}
+
+	check(c.Database.Host != "", "DB_HOST", "must be set")
+	check(c.Database.Port > 0 && c.Database.Port <= 65535, "DB_PORT", "must be between 1 and 65535")
+	check(c.Database.Name != "", "DB_DATABASE", "must be set")
+	check(c.Database.User != "", "DB_USERNAME", "must be set")
+	check(slices.Contains(sslModes, c.Database.SSLMode), "DB_SSLMODE", "must be one of "+strings.Join(sslModes, ", "))
+	check(c.Database.MaxOpenConns >= 0, "DB_MAX_OPEN_CONNS", "must not be negative")
+	check(c.Database.MaxIdleConns >= 0, "DB_MAX_IDLE_CONNS", "must not be negative")
+	check(c.Database.MaxOpenConns == 0 || c.Database.MaxIdleConns <= c.Database.MaxOpenConns, "DB_MAX_IDLE_CONNS", "must not be above DB_MAX_OPEN_CONNS")
+	check(c.Database.ConnMaxLifetime >= 0, "DB_CONN_MAX_LIFETIME", "must not be negative")
+	check(c.Database.ConnMaxIdleTime >= 0, "DB_CONN_MAX_IDLE_TIME", "must not be negative")
+	check(c.Database.MigrationsPath != "", "DB_MIGRATIONS_PATH", "must be set")
+
+	check(c.Auth.JWTSecret != "", "JWT_SECRET", "must be set")
+	check(c.Auth.JWTSecret == "" || len(c.Auth.JWTSecret) >= minJWTSecret, "JWT_SECRET", fmt.Sprintf("must be at least %d bytes", minJWTSecret))
+	check(c.Auth.JWTSecret != exampleJWTSecret, "JWT_SECRET", "must not be the example value of the env file")
+	check(c.Auth.JWTTTL > 0, "JWT_TTL_MINUTES", "must be positive")
+	check(c.Auth.AdminPassword == "" || c.Auth.AdminEmail != "", "ADMIN_EMAIL", "must be set together with ADMIN_PASSWORD")
+	check(c.Auth.AdminPassword == "" || len(c.Auth.AdminPassword) >= minAdminPassword, "ADMIN_PASSWORD", fmt.Sprintf("must be at least %d characters", minAdminPassword))
+
+	switch c.Images.Store {
+	case "local":
+		check(c.Images.LocalDir != "", "IMAGE_LOCAL_DIR", "must be set")
+	case "s3":
+		check(c.Images.S3Endpoint != "", "S3_ENDPOINT", "must be set")
+		check(c.Images.S3Bucket != "", "S3_BUCKET", "must be set")
+	default:
+		check(false, "IMAGE_STORE", "must be local or s3")
+	}
+
+	check(c.Rentals.Turnaround >= 0, "TURNAROUND_MINUTES", "must not be negative")
+
+	check(slices.Contains(logLevels, c.Log.Level), "LOG_LEVEL", "must be one of "+strings.Join(logLevels, ", "))
+
+	check(slices.Contains(tracingExporters, c.Tracing.Exporter), "TRACING_EXPORTER", "must be one of "+strings.Join(tracingExporters, ", "))
+	if c.Tracing.Exporter == "otlp" {
+		endpoint, err := url.Parse(c.Tracing.Endpoint)
+		check(err == nil && (endpoint.Scheme == "http" || endpoint.Scheme == "https") && endpoint.Host != "",
+			"OTEL_EXPORTER_OTLP_ENDPOINT", "must be a URL such as http://localhost:4318")
+	}
+	check(c.Tracing.ServiceName != "", "OTEL_SERVICE_NAME", "must be set")
+
+	return problems
+}
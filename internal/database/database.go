@@ -1,49 +1,64 @@
 package database
 
 import (
+	"api/internal/config"
+	"api/internal/logging"
+	"api/internal/models"
 	"context"
 	"database/sql"
-	"fmt"
+	"errors"
 	"log"
-	"os"
+	"log/slog"
+	"strings"
 	"time"
+	"unicode"
 
 	_ "github.com/jackc/pgx"
 	_ "github.com/jackc/pgx/v5/stdlib"
-	_ "github.com/joho/godotenv/autoload"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.24.0"
+	"go.opentelemetry.io/otel/trace"
 )
 
+// tracer starts a child span of the request for every statement, going
+// through whichever provider is installed when it runs.
+var tracer = otel.Tracer("api/internal/database")
+
+// Service runs the statements of the API. Exec, Query, QueryRow and
+// Beginctx are traced, so are the statements WithTx runs in a transaction.
 type Service interface {
-	Health() map[string]string
+	// Health pings the database within ctx and reports on the pool, it
+	// never fails the process.
+	Health(ctx context.Context) models.DatabaseHealth
 	Exec(ctx context.Context, query string, args ...any) (sql.Result, error)
 	Query(ctx context.Context, query string, args ...any) (*sql.Rows, error)
 	QueryRow(ctx context.Context, query string, args ...any) *sql.Row
 	Beginctx(ctx context.Context, opts *sql.TxOptions) (*sql.Tx, error)
+	// Stats reports on the connection pool.
+	Stats() sql.DBStats
+	// Close closes the pool, waiting for the queries already started.
+	Close() error
 }
 
 type service struct {
 	db *sql.DB
 }
 
-var (
-	database = os.Getenv("DB_DATABASE")
-	password = os.Getenv("DB_PASSWORD")
-	username = os.Getenv("DB_USERNAME")
-	port     = os.Getenv("DB_PORT")
-	host     = os.Getenv("DB_HOST")
-)
-
-func New() Service {
-	connStr := fmt.Sprintf("postgres://%s:%s@%s:%s/%s?sslmode=disable", username, password, host, port, database)
-	db, err := sql.Open("pgx", connStr)
+func New(cfg config.DatabaseConfig) Service {
+	db, err := sql.Open("pgx", cfg.DSN())
 	if err != nil {
 		log.Fatal(err)
 	}
+	db.SetMaxOpenConns(cfg.MaxOpenConns)
+	db.SetMaxIdleConns(cfg.MaxIdleConns)
+	db.SetConnMaxLifetime(cfg.ConnMaxLifetime)
+	db.SetConnMaxIdleTime(cfg.ConnMaxIdleTime)
 	s := &service{db: db}
 
-	err = RunMigrate(username, password, host, port, database)
+	err = RunMigrate(cfg)
 	if err != nil {
-		log.Println(err)
+		slog.Error("migrations-failed", "error", err)
 	}
 
 	return s
@@ -54,32 +69,161 @@ func NewMock(db *sql.DB) Service {
 	return s
 }
 
-func (s *service) Health() map[string]string {
-	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Second)
-	defer cancel()
+func (s *service) Health(ctx context.Context) models.DatabaseHealth {
+	stats := s.db.Stats()
+	health := models.DatabaseHealth{
+		Status:             "up",
+		MaxOpenConnections: stats.MaxOpenConnections,
+		OpenConnections:    stats.OpenConnections,
+		InUse:              stats.InUse,
+		Idle:               stats.Idle,
+		WaitCount:          stats.WaitCount,
+		WaitDurationMs:     milliseconds(stats.WaitDuration),
+	}
 
+	start := time.Now()
 	err := s.db.PingContext(ctx)
+	health.LatencyMs = milliseconds(time.Since(start))
 	if err != nil {
-		log.Fatalf(fmt.Sprintf("db down: %v", err))
+		logging.From(ctx).Warn("database-unreachable", "error", err)
+		health.Status = "down"
+		health.Error = "database-unreachable"
+		return health
 	}
 
-	return map[string]string{
-		"message": "It's healthy",
+	// golang-migrate keeps the one row of the version it got to
+	err = s.db.QueryRowContext(ctx, "SELECT version, dirty FROM schema_migrations LIMIT 1").Scan(&health.MigrationVersion, &health.MigrationDirty)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		logging.From(ctx).Warn("migration-version-unreadable", "error", err)
+		health.Status = "down"
+		health.Error = "migration-version-unreadable"
+		return health
 	}
+
+	if health.MigrationDirty {
+		health.Status = "down"
+		health.Error = "migration-dirty"
+	}
+
+	return health
+}
+
+func milliseconds(d time.Duration) float64 {
+	return float64(d.Microseconds()) / 1000
 }
 
 func (s *service) Exec(ctx context.Context, query string, args ...any) (sql.Result, error) {
-	return s.db.ExecContext(ctx, query, args...)
+	ctx, span := startSpan(ctx, query)
+	res, err := s.db.ExecContext(ctx, query, args...)
+	endSpan(span, err)
+	return res, err
 }
 
+// Query spans the statement up to its first row, reading the rest is left
+// out.
 func (s *service) Query(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
-	return s.db.QueryContext(ctx, query, args...)
+	ctx, span := startSpan(ctx, query)
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	endSpan(span, err)
+	return rows, err
 }
 
 func (s *service) QueryRow(ctx context.Context, query string, args ...any) *sql.Row {
-	return s.db.QueryRowContext(ctx, query, args...)
+	ctx, span := startSpan(ctx, query)
+	row := s.db.QueryRowContext(ctx, query, args...)
+	endSpan(span, row.Err())
+	return row
 }
 
 func (s *service) Beginctx(ctx context.Context, opts *sql.TxOptions) (*sql.Tx, error) {
-	return s.db.BeginTx(ctx, opts)
+	ctx, span := startSpan(ctx, "BEGIN")
+	tx, err := s.db.BeginTx(ctx, opts)
+	endSpan(span, err)
+	return tx, err
+}
+
+// startSpan starts the span of query under the one of ctx. Statements run
+// outside of a request, such as the metrics scrape, get none rather than a
+// trace of their own.
+func startSpan(ctx context.Context, query string) (context.Context, trace.Span) {
+	if !trace.SpanContextFromContext(ctx).IsValid() {
+		return ctx, trace.SpanFromContext(ctx)
+	}
+
+	statement := Shape(query)
+	operation, _, _ := strings.Cut(statement, " ")
+	operation = strings.ToUpper(operation)
+
+	return tracer.Start(ctx, operation,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.DBSystemPostgreSQL,
+			semconv.DBOperation(operation),
+			semconv.DBStatement(statement),
+		),
+	)
+}
+
+// endSpan marks span failed on err, a query finding no row is not an error.
+func endSpan(span trace.Span, err error) {
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// Shape is the shape of the statement query: its whitespace collapsed and
+// the literals formatted into it replaced by ?, so that no value reaches the
+// traces and the same statement always reads the same. Placeholders such as
+// $1 stay.
+func Shape(query string) string {
+	var b strings.Builder
+	runes := []rune(query)
+	space := false
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		switch {
+		case unicode.IsSpace(r):
+			space = b.Len() > 0
+			continue
+		case r == '\'':
+			// '' is a quote within the literal
+			for i++; i < len(runes); i++ {
+				if runes[i] == '\'' {
+					if i+1 < len(runes) && runes[i+1] == '\'' {
+						i++
+						continue
+					}
+					break
+				}
+			}
+			r = '?'
+		case unicode.IsDigit(r) && (i == 0 || !isIdentifier(runes[i-1])):
+			for i+1 < len(runes) && (unicode.IsDigit(runes[i+1]) || runes[i+1] == '.') {
+				i++
+			}
+			r = '?'
+		}
+
+		if space {
+			b.WriteByte(' ')
+			space = false
+		}
+		b.WriteRune(r)
+	}
+
+	return b.String()
+}
+
+func isIdentifier(r rune) bool {
+	return r == '_' || r == '$' || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+func (s *service) Stats() sql.DBStats {
+	return s.db.Stats()
+}
+
+func (s *service) Close() error {
+	return s.db.Close()
 }
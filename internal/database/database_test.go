@@ -0,0 +1,41 @@
+package database_test
+
+import (
+	"api/internal/database"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Shape(t *testing.T) {
+	tests := []struct {
+		query string
+		want  string
+	}{
+		{"SELECT COUNT(*) FROM orders WHERE car_id = $1", "SELECT COUNT(*) FROM orders WHERE car_id = $1"},
+		{"\n\t\tSELECT o.id, c.name\n\t\tFROM orders o\n\t\tJOIN cars c ON c.id = o.car_id\n\t\tLIMIT 20 OFFSET 40\n\t", "SELECT o.id, c.name FROM orders o JOIN cars c ON c.id = o.car_id LIMIT ? OFFSET ?"},
+		{"UPDATE cars SET name = 'Fiat ''Panda''', day_rate = 42.50 WHERE plate = 'AB 123'", "UPDATE cars SET name = ?, day_rate = ? WHERE plate = ?"},
+		{"SELECT * FROM car_blocks b2 WHERE b2.car_id = $12", "SELECT * FROM car_blocks b2 WHERE b2.car_id = $12"},
+		{"BEGIN", "BEGIN"},
+	}
+
+	for _, test := range tests {
+		assert.Equal(t, test.want, database.Shape(test.query))
+	}
+}
+
+func Test_ErrorCodes(t *testing.T) {
+	overlap := fmt.Errorf("booking: %w", &pgconn.PgError{Code: "23P01"})
+	lost := &pgconn.PgError{Code: "40001"}
+
+	// only the exclusion constraint tells the car is taken, a lost
+	// serializable transaction is run again
+	assert.True(t, database.IsExclusionViolation(overlap))
+	assert.False(t, database.IsSerializationFailure(overlap))
+	assert.True(t, database.IsSerializationFailure(lost))
+	assert.False(t, database.IsExclusionViolation(lost))
+	assert.False(t, database.IsSerializationFailure(errors.New("40001")))
+}
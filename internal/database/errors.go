@@ -0,0 +1,45 @@
+package database
+
+import (
+	"errors"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+const (
+	codeForeignKeyViolation  = "23503"
+	codeUniqueViolation      = "23505"
+	codeExclusionViolation   = "23P01"
+	codeSerializationFailure = "40001"
+)
+
+func pgCode(err error) string {
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) {
+		return ""
+	}
+	return pgErr.Code
+}
+
+// IsExclusionViolation reports whether err comes from an EXCLUDE
+// constraint, such as the one keeping the bookings of a car apart.
+func IsExclusionViolation(err error) bool {
+	return pgCode(err) == codeExclusionViolation
+}
+
+// IsSerializationFailure reports whether err comes from Postgres aborting a
+// serializable transaction that a concurrent one may have interfered with.
+func IsSerializationFailure(err error) bool {
+	return pgCode(err) == codeSerializationFailure
+}
+
+// IsUniqueViolation reports whether err comes from a UNIQUE constraint.
+func IsUniqueViolation(err error) bool {
+	return pgCode(err) == codeUniqueViolation
+}
+
+// IsForeignKeyViolation reports whether err comes from a row still being
+// referenced, or referencing one that does not exist.
+func IsForeignKeyViolation(err error) bool {
+	return pgCode(err) == codeForeignKeyViolation
+}
@@ -1,9 +1,8 @@
 package database
 
 import (
-	"fmt"
-	"log"
-	"os"
+	"api/internal/config"
+	"errors"
 	"path/filepath"
 
 	migrate "github.com/golang-migrate/migrate/v4"
@@ -12,31 +11,27 @@ import (
 	_ "github.com/mattes/migrate/source/file"
 )
 
-func RunMigrate(user, pass, host, port, database string) error {
-	getwd, err := os.Getwd()
+func RunMigrate(cfg config.DatabaseConfig) error {
+	path, err := filepath.Abs(cfg.MigrationsPath)
 	if err != nil {
-		log.Println(err)
 		return err
 	}
 
-	dsn := fmt.Sprintf("postgres://%s:%s@%s:%s/%s?sslmode=disable", user, pass, host, port, database)
-	configPath := "file://" + filepath.Join(getwd, "migrations")
-
 	m, err := migrate.New(
-		configPath,
-		dsn,
+		"file://"+path,
+		cfg.DSN(),
 	)
 	if err != nil {
-		log.Println(err)
 		return err
 	}
+	// releases the source and the database connection on failures too
+	defer m.Close()
 
+	// a database already up to date is fine
 	err = m.Up()
-	if err != nil {
-		log.Println(err)
+	if err != nil && !errors.Is(err, migrate.ErrNoChange) {
 		return err
 	}
-	m.Close()
 
 	return nil
 }
@@ -1,10 +1,12 @@
 package database_test
 
 import (
+	"api/internal/config"
 	"api/internal/database"
 	"database/sql"
 	"fmt"
 	"log"
+	"strconv"
 	"testing"
 
 	"github.com/ory/dockertest/v3"
@@ -47,7 +49,16 @@ func Test_RunMigrate(t *testing.T) {
 		log.Fatalf("Could not connect to database: %s", err)
 	}
 
-	err = database.RunMigrate(user, pass, host, port, name)
+	cfg := config.Default().Database
+	cfg.Host = host
+	cfg.Port, err = strconv.Atoi(port)
+	assert.Nil(t, err)
+	cfg.Name = name
+	cfg.User = user
+	cfg.Password = pass
+	cfg.MigrationsPath = "../../migrations"
+
+	err = database.RunMigrate(cfg)
 	assert.Nil(t, err)
 
 	err = pool.Purge(resource)
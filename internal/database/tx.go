@@ -0,0 +1,77 @@
+package database
+
+import (
+	"api/internal/logging"
+	"context"
+	"database/sql"
+)
+
+// Tx runs the statements of a transaction started by WithTx, traced the way
+// Service traces its own.
+type Tx struct {
+	tx *sql.Tx
+}
+
+func (t *Tx) Exec(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	ctx, span := startSpan(ctx, query)
+	res, err := t.tx.ExecContext(ctx, query, args...)
+	endSpan(span, err)
+	return res, err
+}
+
+// Query spans the statement up to its first row, as Service.Query does.
+func (t *Tx) Query(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
+	ctx, span := startSpan(ctx, query)
+	rows, err := t.tx.QueryContext(ctx, query, args...)
+	endSpan(span, err)
+	return rows, err
+}
+
+func (t *Tx) QueryRow(ctx context.Context, query string, args ...any) *sql.Row {
+	ctx, span := startSpan(ctx, query)
+	row := t.tx.QueryRowContext(ctx, query, args...)
+	endSpan(span, row.Err())
+	return row
+}
+
+// serializableRetries bounds how many times WithSerializableTx runs a
+// transaction Postgres aborted with a serialization failure.
+const serializableRetries = 3
+
+// WithSerializableTx runs fn inside a serializable transaction started on s,
+// like WithTx. Postgres aborts such a transaction when a concurrent one might
+// have changed what it read, also on false positives, so fn is run again in
+// a new transaction up to serializableRetries times. fn must be safe to run
+// more than once.
+func WithSerializableTx(ctx context.Context, s Service, fn func(tx *Tx) error) error {
+	opts := &sql.TxOptions{Isolation: sql.LevelSerializable}
+	err := WithTx(ctx, s, opts, fn)
+	for retry := 0; retry < serializableRetries && IsSerializationFailure(err); retry++ {
+		logging.From(ctx).Debug("serialization-failure-retried", "error", err)
+		err = WithTx(ctx, s, opts, fn)
+	}
+
+	return err
+}
+
+// WithTx runs fn inside a transaction started on s. The transaction is
+// committed when fn returns nil and rolled back otherwise.
+func WithTx(ctx context.Context, s Service, opts *sql.TxOptions, fn func(tx *Tx) error) error {
+	tx, err := s.Beginctx(ctx, opts)
+	if err != nil {
+		return err
+	}
+
+	err = fn(&Tx{tx: tx})
+	if err != nil {
+		if rbErr := tx.Rollback(); rbErr != nil {
+			logging.From(ctx).Error("rollback-failed", "error", rbErr)
+		}
+		return err
+	}
+
+	_, span := startSpan(ctx, "COMMIT")
+	err = tx.Commit()
+	endSpan(span, err)
+	return err
+}
@@ -0,0 +1,106 @@
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net"
+	"net/http"
+	"os/signal"
+	"sync/atomic"
+	"syscall"
+	"time"
+)
+
+// Manager runs the HTTP server until SIGINT or SIGTERM, then drains it and
+// closes the resources registered with OnClose, last registered first.
+type Manager struct {
+	// timeout bounds how long in-flight requests get to finish
+	timeout time.Duration
+	// delay keeps serving while reporting not ready, so load balancers stop
+	// sending traffic before the listener goes away
+	delay    time.Duration
+	draining atomic.Bool
+	closers  []closer
+}
+
+type closer struct {
+	name  string
+	close func() error
+}
+
+func New(timeout, delay time.Duration) *Manager {
+	return &Manager{timeout: timeout, delay: delay}
+}
+
+// OnClose registers close to be called once the server is drained.
+func (m *Manager) OnClose(name string, close func() error) {
+	m.closers = append(m.closers, closer{name: name, close: close})
+}
+
+// Draining tells whether shutdown has started, readiness checks report
+// unhealthy from then on. A nil Manager never drains.
+func (m *Manager) Draining() bool {
+	return m != nil && m.draining.Load()
+}
+
+// Run listens on server.Addr and serves until a SIGINT or SIGTERM comes in.
+// A second signal while draining kills the process the default way.
+func (m *Manager) Run(server *http.Server) error {
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	listener, err := net.Listen("tcp", server.Addr)
+	if err != nil {
+		return errors.Join(err, m.close())
+	}
+
+	go func() {
+		<-ctx.Done()
+		stop()
+	}()
+
+	return m.Serve(ctx, server, listener)
+}
+
+// Serve serves on listener until ctx is done, then drains server and closes
+// the registered resources. It returns early if server stops on its own.
+func (m *Manager) Serve(ctx context.Context, server *http.Server, listener net.Listener) error {
+	served := make(chan error, 1)
+	go func() {
+		served <- server.Serve(listener)
+	}()
+
+	select {
+	case err := <-served:
+		return errors.Join(err, m.close())
+	case <-ctx.Done():
+	}
+
+	slog.Info("shutting-down")
+	m.draining.Store(true)
+	time.Sleep(m.delay)
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), m.timeout)
+	defer cancel()
+
+	err := server.Shutdown(shutdownCtx)
+	if err != nil {
+		slog.Error("shutdown-failed", "error", err)
+	}
+
+	return errors.Join(err, m.close())
+}
+
+func (m *Manager) close() error {
+	var errs []error
+	for i := len(m.closers) - 1; i >= 0; i-- {
+		err := m.closers[i].close()
+		if err != nil {
+			slog.Error("close-failed", "resource", m.closers[i].name, "error", err)
+			errs = append(errs, err)
+		}
+	}
+
+	return errors.Join(errs...)
+}
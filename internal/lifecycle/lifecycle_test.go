@@ -0,0 +1,75 @@
+package lifecycle_test
+
+import (
+	"api/internal/lifecycle"
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_ServeDrains(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	server := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-release
+		io.WriteString(w, "done")
+	})}
+
+	m := lifecycle.New(5*time.Second, 0)
+	var closed []string
+	m.OnClose("database", func() error {
+		closed = append(closed, "database")
+		return nil
+	})
+	m.OnClose("cache", func() error {
+		closed = append(closed, "cache")
+		return errors.New("cache-down")
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	served := make(chan error, 1)
+	go func() {
+		served <- m.Serve(ctx, server, listener)
+	}()
+
+	body := make(chan string, 1)
+	go func() {
+		resp, err := http.Get("http://" + listener.Addr().String())
+		if err != nil {
+			body <- err.Error()
+			return
+		}
+		defer resp.Body.Close()
+
+		b, _ := io.ReadAll(resp.Body)
+		body <- string(b)
+	}()
+
+	<-started
+	assert.False(t, m.Draining())
+	cancel()
+
+	// the request in flight is still answered once shutdown started
+	assert.Eventually(t, m.Draining, time.Second, 10*time.Millisecond)
+	close(release)
+	assert.Equal(t, "done", <-body)
+
+	err = <-served
+	assert.ErrorContains(t, err, "cache-down")
+	assert.Equal(t, []string{"cache", "database"}, closed)
+}
+
+func Test_NilManagerNeverDrains(t *testing.T) {
+	var m *lifecycle.Manager
+	assert.False(t, m.Draining())
+}
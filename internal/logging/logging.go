@@ -0,0 +1,174 @@
+package logging
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"io"
+	"log/slog"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RequestIDHeader carries the id of a request, taken from the client when
+// it sends a usable one.
+const RequestIDHeader = "X-Request-ID"
+
+const redacted = "[REDACTED]"
+
+// sensitive are the attribute and parameter names whose values never reach
+// the logs, matched case insensitively.
+var sensitive = []string{
+	"password",
+	"password_hash",
+	"token",
+	"access_token",
+	"refresh_token",
+	"authorization",
+	"cookie",
+	"secret",
+	"jwt_secret",
+	"api_key",
+	"s3_secret_key",
+}
+
+// loggerKey finds the request logger through gin.Context.Get, ctxKey
+// through any other context.
+const loggerKey = "logging.logger"
+
+type ctxKey struct{}
+
+// New builds a JSON logger writing to w from level on, blanking out the
+// sensitive attributes.
+func New(w io.Writer, level slog.Level) *slog.Logger {
+	return slog.New(slog.NewJSONHandler(w, &slog.HandlerOptions{
+		Level: level,
+		ReplaceAttr: func(groups []string, attr slog.Attr) slog.Attr {
+			if isSensitive(attr.Key) {
+				return slog.String(attr.Key, redacted)
+			}
+			return attr
+		},
+	}))
+}
+
+// ParseLevel reads debug, info, warn or error.
+func ParseLevel(name string) (slog.Level, error) {
+	var level slog.Level
+	err := level.UnmarshalText([]byte(name))
+	return level, err
+}
+
+func isSensitive(name string) bool {
+	return slices.Contains(sensitive, strings.ToLower(name))
+}
+
+// Redact copies values with the sensitive ones blanked out.
+func Redact(values url.Values) url.Values {
+	copied := url.Values{}
+	for name, list := range values {
+		if isSensitive(name) {
+			copied[name] = []string{redacted}
+			continue
+		}
+		copied[name] = slices.Clone(list)
+	}
+
+	return copied
+}
+
+// From returns the logger of the request ctx belongs to, or the default
+// logger outside of requests.
+func From(ctx context.Context) *slog.Logger {
+	if c, ok := ctx.(*gin.Context); ok {
+		if logger, ok := c.Get(loggerKey); ok {
+			return logger.(*slog.Logger)
+		}
+		ctx = c.Request.Context()
+	}
+
+	if logger, ok := ctx.Value(ctxKey{}).(*slog.Logger); ok {
+		return logger
+	}
+
+	return slog.Default()
+}
+
+// Middleware gives every request an id, answered in the X-Request-ID
+// header, and a logger carrying it. Once the request is served it logs the
+// route, status and latency, at error level for server errors and warn for
+// client ones. The cause of a server error is left to apperr.Middleware,
+// which logs it already.
+func Middleware(base *slog.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+
+		requestID := c.GetHeader(RequestIDHeader)
+		if !validRequestID(requestID) {
+			requestID = newRequestID()
+		}
+		c.Header(RequestIDHeader, requestID)
+
+		logger := base.With("request_id", requestID)
+		c.Set(loggerKey, logger)
+		c.Request = c.Request.WithContext(context.WithValue(c.Request.Context(), ctxKey{}, logger))
+
+		c.Next()
+
+		status := c.Writer.Status()
+		attrs := []any{
+			"method", c.Request.Method,
+			"route", c.FullPath(),
+			"path", c.Request.URL.Path,
+			"status", status,
+			"latency_ms", float64(time.Since(start).Microseconds()) / 1000,
+			"client_ip", c.ClientIP(),
+		}
+		if query := c.Request.URL.Query(); len(query) > 0 {
+			attrs = append(attrs, "query", Redact(query).Encode())
+		}
+		if len(c.Errors) > 0 && status < 500 {
+			attrs = append(attrs, "error", c.Errors.Last().Error())
+		}
+
+		level := slog.LevelInfo
+		switch {
+		case status >= 500:
+			level = slog.LevelError
+		case status >= 400:
+			level = slog.LevelWarn
+		}
+
+		logger.Log(c.Request.Context(), level, "request", attrs...)
+	}
+}
+
+// validRequestID keeps ids short and printable, so clients cannot forge
+// log lines through them.
+func validRequestID(id string) bool {
+	if id == "" || len(id) > 128 {
+		return false
+	}
+
+	for _, r := range id {
+		if r < '!' || r > '~' {
+			return false
+		}
+	}
+
+	return true
+}
+
+func newRequestID() string {
+	id := make([]byte, 16)
+	_, err := rand.Read(id)
+	if err != nil {
+		return strings.ReplaceAll(time.Now().UTC().Format("20060102150405.000000000"), ".", "")
+	}
+
+	return hex.EncodeToString(id)
+}
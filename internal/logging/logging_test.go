@@ -0,0 +1,94 @@
+package logging_test
+
+import (
+	"api/internal/logging"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_NewRedacts(t *testing.T) {
+	var out bytes.Buffer
+	logger := logging.New(&out, slog.LevelInfo)
+
+	logger.Info("login", "email", "a@example.com", "Password", "hunter22")
+	logger.Debug("hidden")
+
+	var line map[string]any
+	assert.Nil(t, json.Unmarshal(out.Bytes(), &line))
+	assert.Equal(t, "login", line["msg"])
+	assert.Equal(t, "a@example.com", line["email"])
+	assert.Equal(t, "[REDACTED]", line["Password"])
+	assert.NotContains(t, out.String(), "hidden")
+}
+
+func Test_Redact(t *testing.T) {
+	values := url.Values{"search": {"jazz"}, "token": {"abc"}}
+	assert.Equal(t, "search=jazz&token=%5BREDACTED%5D", logging.Redact(values).Encode())
+	assert.Equal(t, "abc", values.Get("token"))
+}
+
+func Test_Middleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	var out bytes.Buffer
+	r := gin.New()
+	r.Use(logging.Middleware(logging.New(&out, slog.LevelInfo)))
+	r.GET("/cars/:id", func(c *gin.Context) {
+		logging.From(c).Info("looking-up")
+		logging.From(c.Request.Context()).Info("from-request-context")
+		c.Status(http.StatusNotFound)
+	})
+	r.GET("/fail", func(c *gin.Context) {
+		c.Error(errors.New("connection refused"))
+		c.Status(http.StatusInternalServerError)
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/cars/7?access_token=abc&fields=id", nil)
+	req.Header.Set(logging.RequestIDHeader, "abc-123")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	assert.Equal(t, "abc-123", w.Header().Get(logging.RequestIDHeader))
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	assert.Len(t, lines, 3)
+	for _, raw := range lines {
+		var line map[string]any
+		assert.Nil(t, json.Unmarshal([]byte(raw), &line))
+		assert.Equal(t, "abc-123", line["request_id"])
+	}
+
+	var access map[string]any
+	assert.Nil(t, json.Unmarshal([]byte(lines[2]), &access))
+	assert.Equal(t, "request", access["msg"])
+	assert.Equal(t, "WARN", access["level"])
+	assert.Equal(t, "/cars/:id", access["route"])
+	assert.Equal(t, float64(http.StatusNotFound), access["status"])
+	assert.Equal(t, "access_token=%5BREDACTED%5D&fields=id", access["query"])
+	assert.Contains(t, access, "latency_ms")
+
+	// an id that could forge log lines is replaced
+	req = httptest.NewRequest(http.MethodGet, "/cars/7", nil)
+	req.Header.Set(logging.RequestIDHeader, "bad id\n")
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	assert.Len(t, w.Header().Get(logging.RequestIDHeader), 32)
+
+	// the cause of a server error is logged by apperr.Middleware, not again
+	// on the request line
+	out.Reset()
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/fail", nil))
+	access = map[string]any{}
+	assert.Nil(t, json.Unmarshal(out.Bytes(), &access))
+	assert.Equal(t, "ERROR", access["level"])
+	assert.NotContains(t, access, "error")
+}
@@ -0,0 +1,149 @@
+package metrics
+
+import (
+	"api/internal/repository"
+	"context"
+	"database/sql"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// statsTimeout bounds the queries behind the business gauges, a slow
+// database leaves them out of the scrape rather than holding it up.
+const statsTimeout = 2 * time.Second
+
+// Metrics keeps the registry /metrics serves.
+type Metrics struct {
+	registry *prometheus.Registry
+	requests *prometheus.CounterVec
+	duration *prometheus.HistogramVec
+}
+
+// New registers the HTTP metrics, the connection pool metrics read from
+// stats and the business gauges read from orders on every scrape.
+func New(stats func() sql.DBStats, orders repository.OrderRepository) *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "http_requests_total",
+			Help: "HTTP requests served, by route and status.",
+		}, []string{"method", "route", "status"}),
+		duration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "http_request_duration_seconds",
+			Help:    "Time taken to serve HTTP requests, by route and status.",
+			Buckets: prometheus.DefBuckets,
+		}, []string{"method", "route", "status"}),
+	}
+
+	m.registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		m.requests,
+		m.duration,
+		&poolCollector{stats: stats},
+		&ordersCollector{orders: orders},
+	)
+
+	return m
+}
+
+// Middleware counts and times every request. Requests matching no route
+// share one label, so random paths cannot blow up the series.
+func (m *Metrics) Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+
+		labels := prometheus.Labels{
+			"method": c.Request.Method,
+			"route":  route,
+			"status": strconv.Itoa(c.Writer.Status()),
+		}
+		m.requests.With(labels).Inc()
+		m.duration.With(labels).Observe(time.Since(start).Seconds())
+	}
+}
+
+// Handler serves the registry in the Prometheus text format.
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{})
+}
+
+var (
+	poolMaxOpen = prometheus.NewDesc("db_max_open_connections", "Maximum number of open connections to the database.", nil, nil)
+	poolOpen    = prometheus.NewDesc("db_open_connections", "Established connections, in use and idle.", nil, nil)
+	poolInUse   = prometheus.NewDesc("db_in_use_connections", "Connections currently in use.", nil, nil)
+	poolIdle    = prometheus.NewDesc("db_idle_connections", "Idle connections.", nil, nil)
+	poolWaits   = prometheus.NewDesc("db_wait_count_total", "Connections waited for.", nil, nil)
+	poolWaited  = prometheus.NewDesc("db_wait_duration_seconds_total", "Time blocked waiting for a connection.", nil, nil)
+	poolClosed  = prometheus.NewDesc("db_closed_connections_total", "Connections closed, by reason.", []string{"reason"}, nil)
+)
+
+// poolCollector reads sql.DBStats at scrape time.
+type poolCollector struct {
+	stats func() sql.DBStats
+}
+
+func (p *poolCollector) Describe(ch chan<- *prometheus.Desc) {
+	for _, desc := range []*prometheus.Desc{poolMaxOpen, poolOpen, poolInUse, poolIdle, poolWaits, poolWaited, poolClosed} {
+		ch <- desc
+	}
+}
+
+func (p *poolCollector) Collect(ch chan<- prometheus.Metric) {
+	stats := p.stats()
+	ch <- prometheus.MustNewConstMetric(poolMaxOpen, prometheus.GaugeValue, float64(stats.MaxOpenConnections))
+	ch <- prometheus.MustNewConstMetric(poolOpen, prometheus.GaugeValue, float64(stats.OpenConnections))
+	ch <- prometheus.MustNewConstMetric(poolInUse, prometheus.GaugeValue, float64(stats.InUse))
+	ch <- prometheus.MustNewConstMetric(poolIdle, prometheus.GaugeValue, float64(stats.Idle))
+	ch <- prometheus.MustNewConstMetric(poolWaits, prometheus.CounterValue, float64(stats.WaitCount))
+	ch <- prometheus.MustNewConstMetric(poolWaited, prometheus.CounterValue, stats.WaitDuration.Seconds())
+	ch <- prometheus.MustNewConstMetric(poolClosed, prometheus.CounterValue, float64(stats.MaxIdleClosed), "max_idle")
+	ch <- prometheus.MustNewConstMetric(poolClosed, prometheus.CounterValue, float64(stats.MaxIdleTimeClosed), "max_idle_time")
+	ch <- prometheus.MustNewConstMetric(poolClosed, prometheus.CounterValue, float64(stats.MaxLifetimeClosed), "max_lifetime")
+}
+
+var (
+	activeRentals = prometheus.NewDesc("rental_active_rentals", "Orders picked up and not returned yet.", nil, nil)
+	occupiedCars  = prometheus.NewDesc("rental_occupied_cars", "Cars out on a rental or reserved for now.", nil, nil)
+	ordersCreated = prometheus.NewDesc("rental_orders_created_last_hour", "Orders booked within the last hour, through any instance.", nil, nil)
+)
+
+// ordersCollector reads the business gauges from the orders at scrape time,
+// so every instance reports the same fleet.
+type ordersCollector struct {
+	orders repository.OrderRepository
+}
+
+func (o *ordersCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- activeRentals
+	ch <- occupiedCars
+	ch <- ordersCreated
+}
+
+func (o *ordersCollector) Collect(ch chan<- prometheus.Metric) {
+	ctx, cancel := context.WithTimeout(context.Background(), statsTimeout)
+	defer cancel()
+
+	stats, err := o.orders.Stats(ctx)
+	if err != nil {
+		slog.Warn("order-stats-failed", "error", err)
+		return
+	}
+
+	ch <- prometheus.MustNewConstMetric(activeRentals, prometheus.GaugeValue, float64(stats.ActiveRentals))
+	ch <- prometheus.MustNewConstMetric(occupiedCars, prometheus.GaugeValue, float64(stats.OccupiedCars))
+	ch <- prometheus.MustNewConstMetric(ordersCreated, prometheus.GaugeValue, float64(stats.CreatedLastHour))
+}
@@ -0,0 +1,56 @@
+package metrics_test
+
+import (
+	"api/internal/metrics"
+	"api/internal/models"
+	"api/internal/repository"
+	"database/sql"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Metrics(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	mem := repository.NewMemory()
+	yesterday := time.Now().AddDate(0, 0, -1).Format("2006-01-02")
+	tomorrow := time.Now().AddDate(0, 0, 1).Format("2006-01-02")
+	mem.AddOrder(models.OrdersItem{CarId: 1, PickupDate: yesterday, DropoffDate: tomorrow, Status: "picked_up"})
+	mem.AddOrder(models.OrdersItem{CarId: 2, PickupDate: yesterday, DropoffDate: tomorrow, Status: "reserved"})
+	mem.AddOrder(models.OrdersItem{CarId: 3, PickupDate: tomorrow, DropoffDate: tomorrow, Status: "reserved"})
+
+	m := metrics.New(func() sql.DBStats {
+		return sql.DBStats{OpenConnections: 4, InUse: 1, Idle: 3, WaitCount: 2}
+	}, mem.Orders())
+
+	r := gin.New()
+	r.Use(m.Middleware())
+	r.GET("/metrics", gin.WrapH(m.Handler()))
+	r.GET("/cars/:id", func(c *gin.Context) {
+		c.Status(http.StatusNotFound)
+	})
+
+	for _, target := range []string{"/cars/1", "/cars/2", "/nowhere"} {
+		r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, target, nil))
+	}
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	body := w.Body.String()
+	assert.Contains(t, body, `http_requests_total{method="GET",route="/cars/:id",status="404"} 2`)
+	assert.Contains(t, body, `http_requests_total{method="GET",route="unmatched",status="404"} 1`)
+	assert.Contains(t, body, `http_request_duration_seconds_count{method="GET",route="/cars/:id",status="404"} 2`)
+	assert.Contains(t, body, "db_open_connections 4")
+	assert.Contains(t, body, "db_idle_connections 3")
+	assert.Contains(t, body, "db_wait_count_total 2")
+	assert.Contains(t, body, "rental_active_rentals 1")
+	assert.Contains(t, body, "rental_occupied_cars 2")
+	assert.Contains(t, body, "rental_orders_created_last_hour 3")
+}
@@ -0,0 +1,41 @@
+package models
+
+// CarBlocksItem is a period a car is off the road, Type being maintenance,
+// inspection or repair.
+type CarBlocksItem struct {
+	Id        int    `json:"id"`
+	CarId     int    `json:"car_id"`
+	StartDate string `json:"start_date"`
+	EndDate   string `json:"end_date"`
+	Type      string `json:"type"`
+	Reason    string `json:"reason"`
+}
+
+// CarBlocksPeriod is when a block takes its car off the road, without the
+// reason staff gave for it.
+type CarBlocksPeriod struct {
+	Id        int    `json:"id"`
+	StartDate string `json:"start_date"`
+	EndDate   string `json:"end_date"`
+}
+
+type CarBlocksResponseList struct {
+	Items   []*CarBlocksItem `json:"items"`
+	Message string           `json:"message"`
+}
+
+type CarBlocksRequestCreate struct {
+	CarId     string `json:"car_id"`
+	StartDate string `json:"start_date"`
+	EndDate   string `json:"end_date"`
+	Type      string `json:"type"`
+	Reason    string `json:"reason"`
+}
+
+// CarBlocksResponseCreate lists the orders still holding the car during the
+// new block, they have to be moved to another car or cancelled.
+type CarBlocksResponseCreate struct {
+	Id          int           `json:"id"`
+	Overlapping []*OrdersItem `json:"overlapping"`
+	Message     string        `json:"message"`
+}
@@ -5,32 +5,79 @@ type CarsItem struct {
 	CarName   string  `json:"car_name"`
 	DayRate   float64 `json:"day_rate"`
 	MonthRate float64 `json:"month_rate"`
-	Image     string  `json:"image"`
+	// HourRate is 0 for cars only rented by the day
+	HourRate float64 `json:"hour_rate"`
+	Image    string  `json:"image"`
+	Make     string  `json:"make"`
+	Model    string  `json:"model"`
+	// Year and Seats are 0 when not recorded
+	Year         int      `json:"year"`
+	Category     string   `json:"category"`
+	Seats        int      `json:"seats"`
+	Transmission string   `json:"transmission"`
+	FuelType     string   `json:"fuel_type"`
+	PlateNumber  string   `json:"plate_number"`
+	Features     []string `json:"features"`
+	// HomeLocationId and CurrentLocationId are 0 until staff record them
+	HomeLocationId    int `json:"home_location_id"`
+	CurrentLocationId int `json:"current_location_id"`
+	// DeletedAt is only set on cars listed from the deleted ones
+	DeletedAt string `json:"deleted_at,omitempty"`
 }
 
 type CarsResponseList struct {
-	Page    int         `json:"page"`
-	Limit   int         `json:"limit"`
-	Total   int         `json:"total"`
-	Order   string      `json:"order"`
-	OrderBy string      `json:"order_by"`
-	Items   []*CarsItem `json:"items"`
-	Message string      `json:"message"`
+	Page       int         `json:"page"`
+	Limit      int         `json:"limit"`
+	Total      *int        `json:"total,omitempty"`
+	Order      string      `json:"order"`
+	OrderBy    string      `json:"order_by"`
+	NextCursor string      `json:"next_cursor,omitempty"`
+	PrevCursor string      `json:"prev_cursor,omitempty"`
+	Items      []*CarsItem `json:"items"`
+	// Facets count the cars of each value of the facet fields, every filter
+	// but the facet's own applied
+	Facets  map[string]map[string]int `json:"facets,omitempty"`
+	Message string                    `json:"message"`
 }
 
 type CarsRequestCreate struct {
-	CarName   string `json:"car_name"`
-	DayRate   string `json:"day_rate"`
-	MonthRate string `json:"month_rate"`
-	Image     string `json:"image"`
+	CarName           string   `json:"car_name"`
+	DayRate           string   `json:"day_rate"`
+	MonthRate         string   `json:"month_rate"`
+	HourRate          string   `json:"hour_rate"`
+	Image             string   `json:"image"`
+	HomeLocationId    string   `json:"home_location_id"`
+	CurrentLocationId string   `json:"current_location_id"`
+	Make              string   `json:"make"`
+	Model             string   `json:"model"`
+	Year              string   `json:"year"`
+	Category          string   `json:"category"`
+	Seats             string   `json:"seats"`
+	Transmission      string   `json:"transmission"`
+	FuelType          string   `json:"fuel_type"`
+	PlateNumber       string   `json:"plate_number"`
+	Features          []string `json:"features"`
 }
 
 type CarsRequestUpdate struct {
-	Id        string `json:"id"`
-	CarName   string `json:"car_name"`
-	DayRate   string `json:"day_rate"`
-	MonthRate string `json:"month_rate"`
-	Image     string `json:"image"`
+	Id                string `json:"id"`
+	CarName           string `json:"car_name"`
+	DayRate           string `json:"day_rate"`
+	MonthRate         string `json:"month_rate"`
+	HourRate          string `json:"hour_rate"`
+	Image             string `json:"image"`
+	HomeLocationId    string `json:"home_location_id"`
+	CurrentLocationId string `json:"current_location_id"`
+	Make              string `json:"make"`
+	Model             string `json:"model"`
+	Year              string `json:"year"`
+	Category          string `json:"category"`
+	Seats             string `json:"seats"`
+	Transmission      string `json:"transmission"`
+	FuelType          string `json:"fuel_type"`
+	PlateNumber       string `json:"plate_number"`
+	// Features replaces the whole list when sent, [] clears it
+	Features []string `json:"features"`
 }
 
 type CarsRequestDelete struct {
@@ -41,3 +88,15 @@ type CarsResponseGet struct {
 	Message string    `json:"message"`
 	Item    *CarsItem `json:"item"`
 }
+
+type CarsRequestAvailable struct {
+	RequestListsGeneral
+	PickupDate  string `json:"pickup_date" form:"pickup_date"`
+	DropoffDate string `json:"dropoff_date" form:"dropoff_date"`
+}
+
+type CarsResponseImage struct {
+	Id      int    `json:"id"`
+	Image   string `json:"image"`
+	Message string `json:"message"`
+}
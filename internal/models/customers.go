@@ -0,0 +1,42 @@
+package models
+
+type CustomersItem struct {
+	Id            int    `json:"id"`
+	Name          string `json:"name"`
+	Email         string `json:"email"`
+	Phone         string `json:"phone"`
+	LicenceNumber string `json:"licence_number"`
+	LicenceExpiry string `json:"licence_expiry"`
+}
+
+type CustomersResponseList struct {
+	Page    int              `json:"page"`
+	Limit   int              `json:"limit"`
+	Total   int              `json:"total"`
+	Order   string           `json:"order"`
+	OrderBy string           `json:"order_by"`
+	Items   []*CustomersItem `json:"items"`
+	Message string           `json:"message"`
+}
+
+type CustomersRequestCreate struct {
+	Name          string `json:"name"`
+	Email         string `json:"email"`
+	Phone         string `json:"phone"`
+	LicenceNumber string `json:"licence_number"`
+	LicenceExpiry string `json:"licence_expiry"`
+}
+
+type CustomersRequestUpdate struct {
+	Id            string `json:"id"`
+	Name          string `json:"name"`
+	Email         string `json:"email"`
+	Phone         string `json:"phone"`
+	LicenceNumber string `json:"licence_number"`
+	LicenceExpiry string `json:"licence_expiry"`
+}
+
+type CustomersResponseGet struct {
+	Message string         `json:"message"`
+	Item    *CustomersItem `json:"item"`
+}
@@ -1,16 +1,40 @@
 package models
 
+// RequestListsGeneral carries the paging, search and sort of list
+// endpoints. Sort, such as "-day_rate,car_name", takes precedence over
+// OrderBy and Order. Filters come as field[op]=value query parameters.
+//
+// Paging "cursor", or sending a Cursor, switches from page numbers to keyset
+// paging through the next_cursor and prev_cursor of the response. Totals are
+// then left out unless IncludeTotal asks for them.
 type RequestListsGeneral struct {
-	Page     int    `json:"page"`
-	Limit    int    `json:"limit"`
-	Total    int    `json:"total"`
-	Order    string `json:"order"`
-	OrderBy  string `json:"order_by"`
-	Search   string `json:"search"`
-	SearchBy string `json:"search_by"`
+	Page         int    `json:"page" form:"page"`
+	Limit        int    `json:"limit" form:"limit"`
+	Total        int    `json:"total" form:"total"`
+	Order        string `json:"order" form:"order"`
+	OrderBy      string `json:"order_by" form:"order_by"`
+	Sort         string `json:"sort" form:"sort"`
+	Search       string `json:"search" form:"search"`
+	SearchBy     string `json:"search_by" form:"search_by"`
+	Paging       string `json:"paging" form:"paging"`
+	Cursor       string `json:"cursor" form:"cursor"`
+	IncludeTotal *bool  `json:"include_total" form:"include_total"`
 }
 
 type ResponseGeneral struct {
 	Id      int    `json:"id"`
 	Message string `json:"message"`
 }
+
+// ResponseError is the body of every failed request. Code is one of the
+// apperr codes, Message the specific reason such as "missing-car-name".
+type ResponseError struct {
+	Code    string                 `json:"code"`
+	Message string                 `json:"message"`
+	Details []*ResponseErrorDetail `json:"details,omitempty"`
+}
+
+type ResponseErrorDetail struct {
+	Field  string `json:"field"`
+	Reason string `json:"reason"`
+}
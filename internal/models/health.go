@@ -0,0 +1,38 @@
+package models
+
+// HealthResponse is the body of /healthz and /readyz. Status is "ok" on
+// liveness, "ready", "not-ready" or "draining" on readiness, the database
+// is only checked for readiness.
+type HealthResponse struct {
+	Status   string          `json:"status"`
+	Database *DatabaseHealth `json:"database,omitempty"`
+	Build    BuildInfo       `json:"build"`
+}
+
+// DatabaseHealth reports the ping and the connection pool, Error is only
+// set when Status is "down".
+type DatabaseHealth struct {
+	Status             string  `json:"status"`
+	Error              string  `json:"error,omitempty"`
+	LatencyMs          float64 `json:"latency_ms"`
+	MaxOpenConnections int     `json:"max_open_connections"`
+	OpenConnections    int     `json:"open_connections"`
+	InUse              int     `json:"in_use"`
+	Idle               int     `json:"idle"`
+	WaitCount          int64   `json:"wait_count"`
+	WaitDurationMs     float64 `json:"wait_duration_ms"`
+	// MigrationVersion is the last migration applied, MigrationDirty tells
+	// that it failed halfway
+	MigrationVersion int  `json:"migration_version"`
+	MigrationDirty   bool `json:"migration_dirty"`
+}
+
+// BuildInfo tells which build is running, Revision and BuildTime come from
+// the VCS stamp of the Go toolchain and are empty outside a checkout.
+type BuildInfo struct {
+	Version   string `json:"version"`
+	Revision  string `json:"revision,omitempty"`
+	BuildTime string `json:"build_time,omitempty"`
+	Modified  bool   `json:"modified,omitempty"`
+	GoVersion string `json:"go_version"`
+}
@@ -0,0 +1,47 @@
+package models
+
+// LocationsItem is a fleet branch. OpeningHours maps "mon" to "sun" to
+// hours such as "08:00-18:00" in Timezone, an empty map is always open.
+type LocationsItem struct {
+	Id           int               `json:"id"`
+	Name         string            `json:"name"`
+	Address      string            `json:"address"`
+	Latitude     *float64          `json:"latitude"`
+	Longitude    *float64          `json:"longitude"`
+	Timezone     string            `json:"timezone"`
+	OpeningHours map[string]string `json:"opening_hours"`
+}
+
+type LocationsResponseList struct {
+	Page    int              `json:"page"`
+	Limit   int              `json:"limit"`
+	Total   int              `json:"total"`
+	Order   string           `json:"order"`
+	OrderBy string           `json:"order_by"`
+	Items   []*LocationsItem `json:"items"`
+	Message string           `json:"message"`
+}
+
+type LocationsRequestCreate struct {
+	Name         string            `json:"name"`
+	Address      string            `json:"address"`
+	Latitude     string            `json:"latitude"`
+	Longitude    string            `json:"longitude"`
+	Timezone     string            `json:"timezone"`
+	OpeningHours map[string]string `json:"opening_hours"`
+}
+
+type LocationsRequestUpdate struct {
+	Id           string            `json:"id"`
+	Name         string            `json:"name"`
+	Address      string            `json:"address"`
+	Latitude     string            `json:"latitude"`
+	Longitude    string            `json:"longitude"`
+	Timezone     string            `json:"timezone"`
+	OpeningHours map[string]string `json:"opening_hours"`
+}
+
+type LocationsResponseGet struct {
+	Message string         `json:"message"`
+	Item    *LocationsItem `json:"item"`
+}
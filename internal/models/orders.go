@@ -1,44 +1,74 @@
 package models
 
+import "time"
+
 type OrdersItem struct {
-	Id              int    `json:"id"`
-	CarId           int    `json:"car_id"`
-	CarName         string `json:"car_name"`
-	OrderDate       string `json:"order_date"`
-	PickupDate      string `json:"pickup_date"`
-	DropoffDate     string `json:"dropoff_date"`
-	PickupLocation  string `json:"pickup_location"`
-	DropoffLocation string `json:"dropoff_location"`
+	Id           int    `json:"id"`
+	CarId        int    `json:"car_id"`
+	CarName      string `json:"car_name"`
+	CustomerId   int    `json:"customer_id"`
+	CustomerName string `json:"customer_name"`
+	OrderDate    string `json:"order_date"`
+	// OrderedAt is the stored order_date at full precision, OrderDate only
+	// shows its day. Keyset cursors resume from it.
+	OrderedAt       time.Time `json:"-"`
+	PickupDate      string    `json:"pickup_date"`
+	DropoffDate     string    `json:"dropoff_date"`
+	PickupLocation  string    `json:"pickup_location"`
+	DropoffLocation string    `json:"dropoff_location"`
+	// PickupLocationId and DropoffLocationId are 0 on orders whose places
+	// matched no branch
+	PickupLocationId  int     `json:"pickup_location_id"`
+	DropoffLocationId int     `json:"dropoff_location_id"`
+	TotalPrice        float64 `json:"total_price"`
+	Status            string  `json:"status"`
+	// DeletedAt is only set on orders listed from the deleted ones
+	DeletedAt string `json:"deleted_at,omitempty"`
+}
+
+type OrdersRequestList struct {
+	RequestListsGeneral
+	CustomerId int `json:"customer_id" form:"customer_id"`
 }
 
 type OrdersResponseList struct {
-	Page    int           `json:"page"`
-	Limit   int           `json:"limit"`
-	Total   int           `json:"total"`
-	Order   string        `json:"order"`
-	OrderBy string        `json:"order_by"`
-	Items   []*OrdersItem `json:"items"`
-	Message string        `json:"message"`
+	Page       int           `json:"page"`
+	Limit      int           `json:"limit"`
+	Total      *int          `json:"total,omitempty"`
+	Order      string        `json:"order"`
+	OrderBy    string        `json:"order_by"`
+	NextCursor string        `json:"next_cursor,omitempty"`
+	PrevCursor string        `json:"prev_cursor,omitempty"`
+	Items      []*OrdersItem `json:"items"`
+	Message    string        `json:"message"`
 }
 
 type OrdersRequestCreate struct {
 	Id              string `json:"id"`
 	CarId           string `json:"car_id"`
+	CustomerId      string `json:"customer_id"`
 	OrderDate       string `json:"order_date"`
 	PickupDate      string `json:"pickup_date"`
 	DropoffDate     string `json:"dropoff_date"`
 	PickupLocation  string `json:"pickup_location"`
 	DropoffLocation string `json:"dropoff_location"`
+	// PickupLocationId and DropoffLocationId name the branches, the
+	// location names are still taken from clients that only send those
+	PickupLocationId  string `json:"pickup_location_id"`
+	DropoffLocationId string `json:"dropoff_location_id"`
 }
 
 type OrdersRequestUpdate struct {
-	Id              string `json:"id"`
-	CarId           string `json:"car_id"`
-	OrderDate       string `json:"order_date"`
-	PickupDate      string `json:"pickup_date"`
-	DropoffDate     string `json:"dropoff_date"`
-	PickupLocation  string `json:"pickup_location"`
-	DropoffLocation string `json:"dropoff_location"`
+	Id                string `json:"id"`
+	CarId             string `json:"car_id"`
+	CustomerId        string `json:"customer_id"`
+	OrderDate         string `json:"order_date"`
+	PickupDate        string `json:"pickup_date"`
+	DropoffDate       string `json:"dropoff_date"`
+	PickupLocation    string `json:"pickup_location"`
+	DropoffLocation   string `json:"dropoff_location"`
+	PickupLocationId  string `json:"pickup_location_id"`
+	DropoffLocationId string `json:"dropoff_location_id"`
 }
 
 type OrdersRequestDelete struct {
@@ -51,6 +81,23 @@ type OrdersResponseGet struct {
 }
 
 type RequestOrdersCheckOcupiedCars struct {
-	CarId      string `json:"car_id"`
-	PickupDate string `json:"pickup_date"`
+	CarId          string `json:"car_id"`
+	PickupDate     string `json:"pickup_date"`
+	DropoffDate    string `json:"dropoff_date"`
+	ExcludeOrderId int    `json:"-"`
+}
+
+// OrdersPeriod is when an order holds its car, all the public occupancy
+// check tells about it.
+type OrdersPeriod struct {
+	Id          int    `json:"id"`
+	PickupDate  string `json:"pickup_date"`
+	DropoffDate string `json:"dropoff_date"`
+}
+
+type OrdersResponseCheckOccupied struct {
+	Message   string          `json:"message"`
+	Conflicts []*OrdersPeriod `json:"conflicts"`
+	// Blocks are the maintenance and other blocks taking the car off the road
+	Blocks []*CarBlocksPeriod `json:"blocks"`
 }
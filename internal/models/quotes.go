@@ -0,0 +1,26 @@
+package models
+
+type QuotesRequestCreate struct {
+	CarId       string `json:"car_id"`
+	PickupDate  string `json:"pickup_date"`
+	DropoffDate string `json:"dropoff_date"`
+}
+
+type QuotesItem struct {
+	CarId       int     `json:"car_id"`
+	PickupDate  string  `json:"pickup_date"`
+	DropoffDate string  `json:"dropoff_date"`
+	Days        int     `json:"days"`
+	Months      int     `json:"months"`
+	ExtraDays   int     `json:"extra_days"`
+	Hours       int     `json:"hours"`
+	DayRate     float64 `json:"day_rate"`
+	MonthRate   float64 `json:"month_rate"`
+	HourRate    float64 `json:"hour_rate"`
+	TotalPrice  float64 `json:"total_price"`
+}
+
+type QuotesResponseGet struct {
+	Message string      `json:"message"`
+	Item    *QuotesItem `json:"item"`
+}
@@ -0,0 +1,30 @@
+package models
+
+// UsersItem is an account that logs in to the API. CustomerId is set for
+// customer accounts only.
+type UsersItem struct {
+	Id           int    `json:"id"`
+	Email        string `json:"email"`
+	PasswordHash string `json:"-"`
+	Role         string `json:"role"`
+	CustomerId   int    `json:"customer_id,omitempty"`
+}
+
+type UsersRequestLogin struct {
+	Email    string `json:"email"`
+	Password string `json:"password"`
+}
+
+type UsersResponseLogin struct {
+	Message   string `json:"message"`
+	Token     string `json:"token"`
+	ExpiresAt string `json:"expires_at"`
+	Role      string `json:"role"`
+}
+
+type UsersRequestCreate struct {
+	Email      string `json:"email"`
+	Password   string `json:"password"`
+	Role       string `json:"role"`
+	CustomerId string `json:"customer_id"`
+}
@@ -0,0 +1,8 @@
+// Package openapi holds the OpenAPI 3 document of the API. It is written by
+// hand, the tests of the routes fail when a route is missing from it.
+package openapi
+
+import _ "embed"
+
+//go:embed openapi.json
+var Document []byte
@@ -0,0 +1,139 @@
+// Package openinghours describes when a branch is open, as a weekly schedule
+// in the branch's own time zone.
+package openinghours
+
+import (
+	"api/internal/apperr"
+	"fmt"
+	"strings"
+	"time"
+
+	// branches name IANA zones, do not depend on the host having them
+	_ "time/tzdata"
+)
+
+const clockLayout = "15:04"
+
+var weekdays = map[string]time.Weekday{
+	"sun": time.Sunday,
+	"mon": time.Monday,
+	"tue": time.Tuesday,
+	"wed": time.Wednesday,
+	"thu": time.Thursday,
+	"fri": time.Friday,
+	"sat": time.Saturday,
+}
+
+// Hours maps a weekday ("mon" to "sun") to its opening hours such as
+// "08:00-18:00". A missing day is closed, "00:00-00:00" is open all day and
+// a closing time before the opening one runs past midnight. Empty Hours are
+// open around the clock.
+type Hours map[string]string
+
+type span struct {
+	opens, closes time.Duration
+}
+
+// Validate checks every day and its hours are well formed.
+func (h Hours) Validate() error {
+	for day, hours := range h {
+		if _, ok := weekdays[day]; !ok {
+			return apperr.Validation("invalid-opening-hours-day", "opening_hours")
+		}
+
+		if _, err := parseSpan(hours); err != nil {
+			return apperr.Validation("invalid-opening-hours", "opening_hours").Wrap(err)
+		}
+	}
+
+	return nil
+}
+
+func parseSpan(hours string) (span, error) {
+	opens, closes, ok := strings.Cut(hours, "-")
+	if !ok {
+		return span{}, fmt.Errorf("hours %q are not opens-closes", hours)
+	}
+
+	o, err := parseClock(opens)
+	if err != nil {
+		return span{}, err
+	}
+
+	c, err := parseClock(closes)
+	if err != nil {
+		return span{}, err
+	}
+
+	return span{opens: o, closes: c}, nil
+}
+
+func parseClock(value string) (time.Duration, error) {
+	t, err := time.Parse(clockLayout, strings.TrimSpace(value))
+	if err != nil {
+		return 0, err
+	}
+
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+}
+
+func (h Hours) day(weekday time.Weekday) (span, bool) {
+	for name, hours := range h {
+		if weekdays[name] != weekday {
+			continue
+		}
+
+		s, err := parseSpan(hours)
+		return s, err == nil
+	}
+
+	return span{}, false
+}
+
+// OpenOn tells whether the branch opens at all on the calendar day of date.
+func (h Hours) OpenOn(date time.Time) bool {
+	if len(h) == 0 {
+		return true
+	}
+
+	_, ok := h.day(date.Weekday())
+	return ok
+}
+
+// OpenAt tells whether the branch is open at t, read in the zone loc.
+func (h Hours) OpenAt(t time.Time, loc *time.Location) bool {
+	if len(h) == 0 {
+		return true
+	}
+
+	local := t.In(loc)
+	clock := time.Duration(local.Hour())*time.Hour + time.Duration(local.Minute())*time.Minute
+
+	if today, ok := h.day(local.Weekday()); ok {
+		switch {
+		case today.opens == today.closes:
+			return true
+		case today.opens < today.closes && clock >= today.opens && clock < today.closes:
+			return true
+		case today.opens > today.closes && clock >= today.opens:
+			return true
+		}
+	}
+
+	// the tail of yesterday's hours when they ran past midnight
+	if yesterday, ok := h.day(local.AddDate(0, 0, -1).Weekday()); ok {
+		return yesterday.opens > yesterday.closes && clock < yesterday.closes
+	}
+
+	return false
+}
+
+// LoadZone resolves an IANA time zone name such as "Asia/Jakarta".
+func LoadZone(name string) (*time.Location, error) {
+	loc, err := time.LoadLocation(name)
+	if err != nil || name == "" || strings.EqualFold(name, "local") {
+		return nil, apperr.Validation("invalid-timezone", "timezone")
+	}
+
+	return loc, nil
+}
@@ -0,0 +1,46 @@
+package openinghours_test
+
+import (
+	"api/internal/openinghours"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Validate(t *testing.T) {
+	assert.Nil(t, openinghours.Hours{"mon": "08:00-18:00", "sat": "22:00-02:00"}.Validate())
+	assert.NotNil(t, openinghours.Hours{"monday": "08:00-18:00"}.Validate())
+	assert.NotNil(t, openinghours.Hours{"mon": "8 to 6"}.Validate())
+	assert.NotNil(t, openinghours.Hours{"mon": "08:00-25:00"}.Validate())
+}
+
+func Test_OpenAt(t *testing.T) {
+	jakarta, err := openinghours.LoadZone("Asia/Jakarta")
+	assert.Nil(t, err)
+
+	hours := openinghours.Hours{
+		"mon": "08:00-18:00",
+		"fri": "20:00-02:00",
+		"sun": "00:00-00:00",
+	}
+
+	// 2024-03-04 is a Monday, Jakarta is UTC+7
+	assert.True(t, hours.OpenAt(time.Date(2024, 3, 4, 1, 0, 0, 0, time.UTC), jakarta))
+	assert.False(t, hours.OpenAt(time.Date(2024, 3, 4, 11, 0, 0, 0, time.UTC), jakarta))
+	assert.False(t, hours.OpenAt(time.Date(2024, 3, 5, 9, 0, 0, 0, jakarta), jakarta))
+
+	// friday night hours carry on into saturday
+	assert.True(t, hours.OpenAt(time.Date(2024, 3, 8, 23, 0, 0, 0, jakarta), jakarta))
+	assert.True(t, hours.OpenAt(time.Date(2024, 3, 9, 1, 30, 0, 0, jakarta), jakarta))
+	assert.False(t, hours.OpenAt(time.Date(2024, 3, 9, 2, 0, 0, 0, jakarta), jakarta))
+
+	assert.True(t, hours.OpenAt(time.Date(2024, 3, 10, 3, 0, 0, 0, jakarta), jakarta))
+
+	assert.True(t, hours.OpenOn(time.Date(2024, 3, 4, 0, 0, 0, 0, time.UTC)))
+	assert.False(t, hours.OpenOn(time.Date(2024, 3, 5, 0, 0, 0, 0, time.UTC)))
+	assert.True(t, openinghours.Hours{}.OpenAt(time.Now(), jakarta))
+
+	_, err = openinghours.LoadZone("Mars/Olympus_Mons")
+	assert.NotNil(t, err)
+}
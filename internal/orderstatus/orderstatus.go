@@ -0,0 +1,55 @@
+package orderstatus
+
+import (
+	"fmt"
+	"strings"
+)
+
+type Status string
+
+const (
+	Reserved  Status = "reserved"
+	PickedUp  Status = "picked_up"
+	Returned  Status = "returned"
+	Cancelled Status = "cancelled"
+	NoShow    Status = "no_show"
+)
+
+// transitions lists the legal moves out of each status. An order follows
+// reserved -> picked_up -> returned, and may leave early through cancelled
+// or no_show. Returned, cancelled and no_show are final.
+var transitions = map[Status][]Status{
+	Reserved: {PickedUp, Cancelled, NoShow},
+	PickedUp: {Returned},
+}
+
+// occupying are the statuses in which an order still holds its car.
+var occupying = []Status{Reserved, PickedUp}
+
+func CanTransition(from, to Status) bool {
+	for _, next := range transitions[from] {
+		if next == to {
+			return true
+		}
+	}
+	return false
+}
+
+func (s Status) Occupies() bool {
+	for _, st := range occupying {
+		if st == s {
+			return true
+		}
+	}
+	return false
+}
+
+// OccupyingCondition renders a SQL condition on col matching the orders that
+// still hold their car, for use in availability queries.
+func OccupyingCondition(col string) string {
+	values := make([]string, len(occupying))
+	for i, st := range occupying {
+		values[i] = "'" + string(st) + "'"
+	}
+	return fmt.Sprintf("%s IN (%s)", col, strings.Join(values, ","))
+}
@@ -0,0 +1,32 @@
+package orderstatus_test
+
+import (
+	"api/internal/orderstatus"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_CanTransition(t *testing.T) {
+	assert.True(t, orderstatus.CanTransition(orderstatus.Reserved, orderstatus.PickedUp))
+	assert.True(t, orderstatus.CanTransition(orderstatus.Reserved, orderstatus.Cancelled))
+	assert.True(t, orderstatus.CanTransition(orderstatus.Reserved, orderstatus.NoShow))
+	assert.True(t, orderstatus.CanTransition(orderstatus.PickedUp, orderstatus.Returned))
+
+	assert.False(t, orderstatus.CanTransition(orderstatus.Reserved, orderstatus.Returned))
+	assert.False(t, orderstatus.CanTransition(orderstatus.PickedUp, orderstatus.Cancelled))
+	assert.False(t, orderstatus.CanTransition(orderstatus.Returned, orderstatus.PickedUp))
+	assert.False(t, orderstatus.CanTransition(orderstatus.Cancelled, orderstatus.Reserved))
+	assert.False(t, orderstatus.CanTransition(orderstatus.NoShow, orderstatus.PickedUp))
+	assert.False(t, orderstatus.CanTransition(orderstatus.Reserved, orderstatus.Reserved))
+}
+
+func Test_Occupies(t *testing.T) {
+	assert.True(t, orderstatus.Reserved.Occupies())
+	assert.True(t, orderstatus.PickedUp.Occupies())
+	assert.False(t, orderstatus.Returned.Occupies())
+	assert.False(t, orderstatus.Cancelled.Occupies())
+	assert.False(t, orderstatus.NoShow.Occupies())
+
+	assert.Equal(t, "orders.status IN ('reserved','picked_up')", orderstatus.OccupyingCondition("orders.status"))
+}
@@ -0,0 +1,84 @@
+package pricing
+
+import (
+	"api/internal/booking"
+	"math"
+)
+
+// DaysPerMonth is the length of a billing month.
+const DaysPerMonth = 30
+
+type Rates struct {
+	DayRate   float64
+	MonthRate float64
+	// HourRate is optional, without it every started day is billed whole.
+	HourRate float64
+}
+
+type Quote struct {
+	Days      int
+	Months    int
+	ExtraDays int
+	// Hours are the started hours past the last whole day, billed at
+	// HourRate instead of one more day.
+	Hours     int
+	DayRate   float64
+	MonthRate float64
+	HourRate  float64
+	Total     float64
+}
+
+const minutesPerDay = 24 * 60
+
+// Calculate prices a rental of r. Every started day is billed, and the days
+// are split between month_rate and day_rate in whichever way costs least, so a
+// long tail of extra days is rounded up to a whole month when that is cheaper.
+// With an hour_rate, the hours past the last whole day are billed by the
+// started hour as long as that stays under a day's rate.
+func Calculate(rates Rates, r booking.Range) Quote {
+	minutes := int(math.Ceil(r.End.Sub(r.Start).Minutes()))
+	days, hours := splitDays(rates, minutes)
+
+	best := Quote{
+		Days:      days,
+		ExtraDays: days,
+		Hours:     hours,
+		DayRate:   rates.DayRate,
+		MonthRate: rates.MonthRate,
+		HourRate:  rates.HourRate,
+		Total:     float64(days)*rates.DayRate + float64(hours)*rates.HourRate,
+	}
+
+	if rates.MonthRate > 0 {
+		maxMonths := (minutes + DaysPerMonth*minutesPerDay - 1) / (DaysPerMonth * minutesPerDay)
+		for months := 1; months <= maxMonths; months++ {
+			extraDays, extraHours := splitDays(rates, max(minutes-months*DaysPerMonth*minutesPerDay, 0))
+
+			total := float64(months)*rates.MonthRate + float64(extraDays)*rates.DayRate + float64(extraHours)*rates.HourRate
+			if total < best.Total {
+				best.Months = months
+				best.ExtraDays = extraDays
+				best.Hours = extraHours
+				best.Total = total
+			}
+		}
+	}
+
+	best.Total = math.Round(best.Total*100) / 100
+
+	return best
+}
+
+// splitDays bills minutes as whole days and the started hours left over,
+// rounding those up to one more day when there is no hour_rate or a day
+// costs less.
+func splitDays(rates Rates, minutes int) (int, int) {
+	days := minutes / minutesPerDay
+	hours := (minutes%minutesPerDay + 59) / 60
+
+	if hours > 0 && (rates.HourRate <= 0 || float64(hours)*rates.HourRate >= rates.DayRate) {
+		return days + 1, 0
+	}
+
+	return days, hours
+}
@@ -0,0 +1,83 @@
+package pricing_test
+
+import (
+	"api/internal/booking"
+	"api/internal/pricing"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Calculate(t *testing.T) {
+	rates := pricing.Rates{DayRate: 100, MonthRate: 2000}
+
+	cases := []struct {
+		name                    string
+		pickup, dropoff         string
+		days, months, extraDays int
+		total                   float64
+	}{
+		{"single day", "2024-01-01", "2024-01-02", 1, 0, 1, 100},
+		{"under a month", "2024-01-01", "2024-01-11", 10, 0, 10, 1000},
+		{"month cheaper than days", "2024-01-01", "2024-01-26", 25, 1, 0, 2000},
+		{"exactly a month", "2024-01-01", "2024-01-31", 30, 1, 0, 2000},
+		{"month plus days", "2024-01-01", "2024-02-05", 35, 1, 5, 2500},
+		{"tail rounded up to a month", "2024-01-01", "2024-03-01", 60, 2, 0, 4000},
+		{"tail cheaper as a month", "2024-01-01", "2024-02-25", 55, 2, 0, 4000},
+	}
+
+	for _, tc := range cases {
+		r, err := booking.ParseRange(tc.pickup, tc.dropoff)
+		assert.Nil(t, err)
+
+		q := pricing.Calculate(rates, r)
+		assert.Equal(t, tc.days, q.Days, tc.name)
+		assert.Equal(t, tc.months, q.Months, tc.name)
+		assert.Equal(t, tc.extraDays, q.ExtraDays, tc.name)
+		assert.Equal(t, tc.total, q.Total, tc.name)
+	}
+}
+
+func Test_CalculateWithoutMonthRate(t *testing.T) {
+	r, _ := booking.ParseRange("2024-01-01", "2024-03-01")
+
+	q := pricing.Calculate(pricing.Rates{DayRate: 10}, r)
+	assert.Equal(t, 0, q.Months)
+	assert.Equal(t, 60, q.ExtraDays)
+	assert.Equal(t, 600.0, q.Total)
+}
+
+func Test_CalculateHourly(t *testing.T) {
+	rates := pricing.Rates{DayRate: 100, MonthRate: 2000, HourRate: 15}
+
+	cases := []struct {
+		name                   string
+		pickup, dropoff        string
+		days, extraDays, hours int
+		total                  float64
+	}{
+		{"a few hours", "2024-01-01T09:00:00Z", "2024-01-01T12:00:00Z", 0, 0, 3, 45},
+		{"started hour billed whole", "2024-01-01T09:00:00Z", "2024-01-01T12:01:00Z", 0, 0, 4, 60},
+		{"hours dearer than a day", "2024-01-01T09:00:00Z", "2024-01-01T17:00:00Z", 1, 1, 0, 100},
+		{"day and hours", "2024-01-01T09:00:00Z", "2024-01-02T11:00:00Z", 1, 1, 2, 130},
+		{"month and hours", "2024-01-01T09:00:00Z", "2024-01-31T10:30:00Z", 30, 0, 2, 2030},
+	}
+
+	for _, tc := range cases {
+		r, err := booking.ParseRange(tc.pickup, tc.dropoff)
+		assert.Nil(t, err)
+
+		q := pricing.Calculate(rates, r)
+		assert.Equal(t, tc.days, q.Days, tc.name)
+		assert.Equal(t, tc.extraDays, q.ExtraDays, tc.name)
+		assert.Equal(t, tc.hours, q.Hours, tc.name)
+		assert.Equal(t, tc.total, q.Total, tc.name)
+	}
+
+	// without an hour_rate a started day is a day
+	r, _ := booking.ParseRange("2024-01-01T09:00:00Z", "2024-01-01T10:00:00Z")
+	q := pricing.Calculate(pricing.Rates{DayRate: 100}, r)
+	assert.Equal(t, 1, q.Days)
+	assert.Equal(t, 0, q.Hours)
+	assert.Equal(t, 100.0, q.Total)
+}
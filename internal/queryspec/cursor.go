@@ -0,0 +1,179 @@
+package queryspec
+
+import (
+	"api/internal/apperr"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Cursor marks a row of a sorted list by the values of its sort keys, the
+// tiebreak included. A backward cursor pages towards the start of the list.
+type Cursor struct {
+	Values   []any
+	Backward bool
+}
+
+// cursorToken is what the opaque cursor string carries. Sort pins the token
+// to the ordering it was issued for.
+type cursorToken struct {
+	Sort     string            `json:"s"`
+	Values   []json.RawMessage `json:"v"`
+	Backward bool              `json:"b,omitempty"`
+}
+
+// FormatSort writes sorts back as a sort expression.
+func FormatSort(sorts []Sort) string {
+	parts := make([]string, len(sorts))
+	for i, item := range sorts {
+		parts[i] = item.Field
+		if item.Desc {
+			parts[i] = "-" + item.Field
+		}
+	}
+
+	return strings.Join(parts, ",")
+}
+
+// Reverse flips the direction of every key, a backward page is read in the
+// reversed order and turned around afterwards.
+func Reverse(sorts []Sort) []Sort {
+	reversed := make([]Sort, len(sorts))
+	for i, item := range sorts {
+		reversed[i] = Sort{Field: item.Field, Desc: !item.Desc}
+	}
+
+	return reversed
+}
+
+// CheckKeyset makes sure every key of sorts can be paged through with a
+// cursor. NULLs never compare, so nullable fields cannot.
+func (s *Spec) CheckKeyset(sorts []Sort) error {
+	for _, item := range sorts {
+		if s.Fields[item.Field].Nullable {
+			return &apperr.Error{
+				Code:    apperr.CodeValidation,
+				Message: "invalid-cursor-sort",
+				Fields:  []apperr.FieldError{{Field: "sort", Reason: fmt.Sprintf("nullable-field-%s", item.Field)}},
+			}
+		}
+	}
+
+	return nil
+}
+
+// EncodeCursor builds the opaque cursor for the row whose sort key values,
+// in the order of WithTiebreak(sorts), are values.
+func (s *Spec) EncodeCursor(sorts []Sort, values []any, backward bool) string {
+	token := cursorToken{
+		Sort:     FormatSort(s.WithTiebreak(sorts)),
+		Backward: backward,
+	}
+
+	for _, value := range values {
+		raw, _ := json.Marshal(value)
+		token.Values = append(token.Values, raw)
+	}
+
+	body, _ := json.Marshal(token)
+	return base64.RawURLEncoding.EncodeToString(body)
+}
+
+// DecodeCursor reads a cursor issued by EncodeCursor. Cursors issued for
+// another sort are rejected, they would resume at a meaningless position.
+func (s *Spec) DecodeCursor(sorts []Sort, cursor string) (*Cursor, error) {
+	invalid := apperr.Validation("invalid-cursor", "cursor")
+
+	body, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, invalid.Wrap(err)
+	}
+
+	var token cursorToken
+	err = json.Unmarshal(body, &token)
+	if err != nil {
+		return nil, invalid.Wrap(err)
+	}
+
+	keys := s.WithTiebreak(sorts)
+	if token.Sort != FormatSort(keys) || len(token.Values) != len(keys) {
+		return nil, invalid
+	}
+
+	decoded := &Cursor{Backward: token.Backward}
+	for i, item := range keys {
+		value, err := decodeValue(s.Fields[item.Field].Type, token.Values[i])
+		if err != nil {
+			return nil, invalid.Wrap(err)
+		}
+
+		decoded.Values = append(decoded.Values, value)
+	}
+
+	return decoded, nil
+}
+
+func decodeValue(t Type, raw json.RawMessage) (any, error) {
+	switch t {
+	case Integer:
+		var value int
+		err := json.Unmarshal(raw, &value)
+		return value, err
+	case Number:
+		var value float64
+		err := json.Unmarshal(raw, &value)
+		return value, err
+	case Date:
+		var value time.Time
+		err := json.Unmarshal(raw, &value)
+		return value, err
+	default:
+		var value string
+		err := json.Unmarshal(raw, &value)
+		return value, err
+	}
+}
+
+// Seek renders the condition selecting the rows that come after c in the
+// order of sorts, before it for a backward cursor. Placeholders are numbered
+// from next, one per key.
+func (s *Spec) Seek(sorts []Sort, c *Cursor, next int) (string, []any) {
+	keys := s.WithTiebreak(sorts)
+
+	var alternatives []string
+	for i, item := range keys {
+		var parts []string
+		for j := 0; j < i; j++ {
+			parts = append(parts, fmt.Sprintf("%s = $%d", s.Fields[keys[j].Field].Column, next+j))
+		}
+
+		op := ">"
+		if item.Desc != c.Backward {
+			op = "<"
+		}
+		parts = append(parts, fmt.Sprintf("%s %s $%d", s.Fields[item.Field].Column, op, next+i))
+
+		alternatives = append(alternatives, "("+strings.Join(parts, " AND ")+")")
+	}
+
+	return "(" + strings.Join(alternatives, " OR ") + ")", c.Values
+}
+
+// Admits tells whether the row with the given sort key values comes after c
+// in the order of keys, before it for a backward cursor. It mirrors Seek for
+// in-memory data, keys being the sort with its tiebreak already appended.
+func (c *Cursor) Admits(keys []Sort, values []any) bool {
+	for i, item := range keys {
+		result := Compare(values[i], c.Values[i])
+		if item.Desc != c.Backward {
+			result = -result
+		}
+		if result != 0 {
+			return result > 0
+		}
+	}
+
+	return false
+}
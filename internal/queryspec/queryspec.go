@@ -0,0 +1,417 @@
+// Package queryspec turns the sort and filter parameters of list endpoints
+// into SQL through an allow-list, so only known fields ever reach a query.
+//
+// Sorting takes a comma separated list of fields, a leading "-" sorts that
+// field descending: sort=-day_rate,car_name. Filters are written as
+// field[op]=value, for example day_rate[gte]=100 or
+// pickup_date[between]=2024-01-01,2024-01-31. Facet fields can also be
+// filtered as plain field=value, category=suv,van being category[in]=suv,van.
+package queryspec
+
+import (
+	"api/internal/apperr"
+	"api/internal/booking"
+	"cmp"
+	"fmt"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+type Type int
+
+const (
+	String Type = iota
+	Integer
+	Number
+	Date
+	// List is an array of strings, it can be filtered but not sorted on.
+	List
+)
+
+type Op string
+
+const (
+	Eq      Op = "eq"
+	Ne      Op = "ne"
+	Gt      Op = "gt"
+	Gte     Op = "gte"
+	Lt      Op = "lt"
+	Lte     Op = "lte"
+	Like    Op = "like"
+	In      Op = "in"
+	Between Op = "between"
+	// Has keeps the rows whose list holds every one of the values.
+	Has Op = "has"
+)
+
+var opsByType = map[Type][]Op{
+	String:  {Eq, Ne, Like, In},
+	Integer: {Eq, Ne, Gt, Gte, Lt, Lte, In, Between},
+	Number:  {Eq, Ne, Gt, Gte, Lt, Lte, In, Between},
+	Date:    {Eq, Ne, Gt, Gte, Lt, Lte, Between},
+	List:    {Has},
+}
+
+// Field is a public field name's real column and the type its filter values
+// are parsed as.
+type Field struct {
+	Column   string
+	Type     Type
+	Nullable bool
+}
+
+type Spec struct {
+	Fields map[string]Field
+	// Tiebreak is the unique field appended to every sort so rows with equal
+	// keys keep a stable order between pages.
+	Tiebreak string
+	// Search maps the search_by values to the text column each one matches.
+	Search map[string]string
+	// DefaultSearch is the search_by used when the request names none.
+	DefaultSearch string
+	// Facets are the fields that also take plain field=value filters and
+	// that lists count their values of.
+	Facets []string
+}
+
+// SearchAny is the search_by value that searches every text column at once
+// through the resource's full-text index.
+const SearchAny = "any"
+
+type Sort struct {
+	Field string
+	Desc  bool
+}
+
+// Filter is one field[op]=value condition. Values hold int, float64, string
+// or time.Time depending on the field type, two of them for Between.
+type Filter struct {
+	Field  string
+	Op     Op
+	Values []any
+}
+
+type Query struct {
+	Sort    []Sort
+	Filters []Filter
+}
+
+// LegacySort turns the older order_by and order parameters into a sort
+// expression.
+func LegacySort(orderBy, order string) string {
+	if orderBy == "" {
+		return ""
+	}
+
+	if strings.ToUpper(order) == "DESC" {
+		return "-" + orderBy
+	}
+
+	return orderBy
+}
+
+// Parse reads the sort expression expr and every field[op] parameter of values.
+// Parameters without brackets are left for the caller.
+func (s *Spec) Parse(expr string, values url.Values) (Query, error) {
+	var q Query
+	var err error
+	q.Sort, err = s.ParseSort(expr)
+	if err != nil {
+		return Query{}, err
+	}
+
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	// a stable order keeps the generated SQL the same for the same request
+	slices.Sort(keys)
+
+	for _, key := range keys {
+		raw := values[key]
+		open := strings.IndexByte(key, '[')
+		if open < 0 || !strings.HasSuffix(key, "]") {
+			if !slices.Contains(s.Facets, key) {
+				continue
+			}
+
+			op := In
+			if s.Fields[key].Type == List {
+				op = Has
+			}
+
+			for _, value := range raw {
+				filter, err := s.parseFilter(key, op, value)
+				if err != nil {
+					return Query{}, err
+				}
+
+				q.Filters = append(q.Filters, filter)
+			}
+			continue
+		}
+
+		for _, value := range raw {
+			filter, err := s.parseFilter(key[:open], Op(key[open+1:len(key)-1]), value)
+			if err != nil {
+				return Query{}, err
+			}
+
+			q.Filters = append(q.Filters, filter)
+		}
+	}
+
+	return q, nil
+}
+
+// ParseSearchBy checks searchBy against the searchable columns, an empty one
+// falls back to DefaultSearch.
+func (s *Spec) ParseSearchBy(searchBy string) (string, error) {
+	if searchBy == "" {
+		return s.DefaultSearch, nil
+	}
+
+	if _, ok := s.Search[searchBy]; ok || searchBy == SearchAny {
+		return searchBy, nil
+	}
+
+	return "", apperr.Validation("invalid-search-by", "search_by")
+}
+
+// TSQuery turns free text into a prefix matching tsquery, "jak sel" becomes
+// "jak:* & sel:*". Anything but letters and digits is dropped, so the result
+// is always valid input for to_tsquery.
+func TSQuery(search string) string {
+	words := strings.FieldsFunc(search, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+
+	for i, word := range words {
+		words[i] = strings.ToLower(word) + ":*"
+	}
+
+	return strings.Join(words, " & ")
+}
+
+// ParseSort reads a sort expression such as "-day_rate,car_name".
+func (s *Spec) ParseSort(expr string) ([]Sort, error) {
+	var sorts []Sort
+	for _, part := range strings.Split(expr, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+
+		item := Sort{Field: part}
+		if strings.HasPrefix(part, "-") {
+			item = Sort{Field: part[1:], Desc: true}
+		}
+
+		if field, ok := s.Fields[item.Field]; !ok || field.Type == List {
+			return nil, &apperr.Error{
+				Code:    apperr.CodeValidation,
+				Message: "invalid-sort-field",
+				Fields:  []apperr.FieldError{{Field: "sort", Reason: fmt.Sprintf("unknown-field-%s", item.Field)}},
+			}
+		}
+
+		sorts = append(sorts, item)
+	}
+
+	return sorts, nil
+}
+
+func (s *Spec) parseFilter(name string, op Op, raw string) (Filter, error) {
+	field, ok := s.Fields[name]
+	if !ok {
+		return Filter{}, apperr.Validation("invalid-filter-field", name)
+	}
+
+	allowed := false
+	for _, candidate := range opsByType[field.Type] {
+		allowed = allowed || candidate == op
+	}
+	if !allowed {
+		return Filter{}, apperr.Validation("invalid-filter-operator", name)
+	}
+
+	parts := []string{raw}
+	if op == In || op == Between || op == Has {
+		parts = strings.Split(raw, ",")
+	}
+	if op == Between && len(parts) != 2 {
+		return Filter{}, apperr.Validation("invalid-filter-value", name)
+	}
+
+	filter := Filter{Field: name, Op: op}
+	for _, part := range parts {
+		value, err := parseValue(field.Type, strings.TrimSpace(part))
+		if err != nil {
+			return Filter{}, apperr.Validation("invalid-filter-value", name)
+		}
+
+		filter.Values = append(filter.Values, value)
+	}
+
+	return filter, nil
+}
+
+func parseValue(t Type, raw string) (any, error) {
+	switch t {
+	case Integer:
+		return strconv.Atoi(raw)
+	case Number:
+		return strconv.ParseFloat(raw, 64)
+	case Date:
+		return booking.ParseTime(raw)
+	default:
+		return raw, nil
+	}
+}
+
+// Where renders filters as SQL conditions with placeholders numbered from
+// next, returning them along with their arguments.
+func (s *Spec) Where(filters []Filter, next int) ([]string, []any) {
+	var conditions []string
+	var args []any
+	placeholder := func(value any) string {
+		args = append(args, value)
+		next++
+		return fmt.Sprintf("$%d", next-1)
+	}
+
+	for _, f := range filters {
+		column := s.Fields[f.Field].Column
+		switch f.Op {
+		case Like:
+			conditions = append(conditions, fmt.Sprintf("LOWER(%s) LIKE LOWER(%s)", column, placeholder(fmt.Sprintf("%%%v%%", f.Values[0]))))
+		case In:
+			list := make([]string, len(f.Values))
+			for i, value := range f.Values {
+				list[i] = placeholder(value)
+			}
+			conditions = append(conditions, fmt.Sprintf("%s IN (%s)", column, strings.Join(list, ",")))
+		case Between:
+			conditions = append(conditions, fmt.Sprintf("%s BETWEEN %s AND %s", column, placeholder(f.Values[0]), placeholder(f.Values[1])))
+		case Has:
+			list := make([]string, len(f.Values))
+			for i, value := range f.Values {
+				list[i] = placeholder(value)
+			}
+			conditions = append(conditions, fmt.Sprintf("%s @> ARRAY[%s]::text[]", column, strings.Join(list, ",")))
+		default:
+			conditions = append(conditions, fmt.Sprintf("%s %s %s", column, sqlOps[f.Op], placeholder(f.Values[0])))
+		}
+	}
+
+	return conditions, args
+}
+
+var sqlOps = map[Op]string{
+	Eq:  "=",
+	Ne:  "<>",
+	Gt:  ">",
+	Gte: ">=",
+	Lt:  "<",
+	Lte: "<=",
+}
+
+// OrderBy renders sorts, followed by the tiebreak, as the body of an ORDER BY
+// clause.
+func (s *Spec) OrderBy(sorts []Sort) string {
+	var parts []string
+	for _, item := range s.WithTiebreak(sorts) {
+		direction := "ASC"
+		if item.Desc {
+			direction = "DESC"
+		}
+		parts = append(parts, fmt.Sprintf("%s %s", s.Fields[item.Field].Column, direction))
+	}
+
+	return strings.Join(parts, ", ")
+}
+
+// WithTiebreak appends the tiebreak field to sorts unless it is already there.
+func (s *Spec) WithTiebreak(sorts []Sort) []Sort {
+	if s.Tiebreak == "" {
+		return sorts
+	}
+
+	for _, item := range sorts {
+		if item.Field == s.Tiebreak {
+			return sorts
+		}
+	}
+
+	return append(sorts[:len(sorts):len(sorts)], Sort{Field: s.Tiebreak})
+}
+
+// Match evaluates the filter against value, which must be of the same Go
+// type as the filter values. It mirrors Where for in-memory data.
+func (f Filter) Match(value any) bool {
+	switch f.Op {
+	case Eq:
+		return Compare(value, f.Values[0]) == 0
+	case Ne:
+		return Compare(value, f.Values[0]) != 0
+	case Gt:
+		return Compare(value, f.Values[0]) > 0
+	case Gte:
+		return Compare(value, f.Values[0]) >= 0
+	case Lt:
+		return Compare(value, f.Values[0]) < 0
+	case Lte:
+		return Compare(value, f.Values[0]) <= 0
+	case Like:
+		s, _ := value.(string)
+		return strings.Contains(strings.ToLower(s), strings.ToLower(fmt.Sprint(f.Values[0])))
+	case In:
+		for _, candidate := range f.Values {
+			if Compare(value, candidate) == 0 {
+				return true
+			}
+		}
+		return false
+	case Between:
+		return Compare(value, f.Values[0]) >= 0 && Compare(value, f.Values[1]) <= 0
+	case Has:
+		list, _ := value.([]string)
+		for _, wanted := range f.Values {
+			if !slices.Contains(list, fmt.Sprint(wanted)) {
+				return false
+			}
+		}
+		return true
+	}
+
+	return false
+}
+
+// Compare orders two values of the types filters hold. Values of different
+// types compare as equal.
+func Compare(a, b any) int {
+	switch a := a.(type) {
+	case int:
+		if b, ok := b.(int); ok {
+			return cmp.Compare(a, b)
+		}
+	case float64:
+		if b, ok := b.(float64); ok {
+			return cmp.Compare(a, b)
+		}
+	case string:
+		if b, ok := b.(string); ok {
+			return cmp.Compare(a, b)
+		}
+	case time.Time:
+		if b, ok := b.(time.Time); ok {
+			return a.Compare(b)
+		}
+	}
+
+	return 0
+}
@@ -0,0 +1,158 @@
+package queryspec_test
+
+import (
+	"api/internal/apperr"
+	"api/internal/queryspec"
+	"errors"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var spec = &queryspec.Spec{
+	Fields: map[string]queryspec.Field{
+		"id":          {Column: "car_id", Type: queryspec.Integer},
+		"car_name":    {Column: "car_name", Type: queryspec.String},
+		"day_rate":    {Column: "day_rate", Type: queryspec.Number},
+		"pickup_date": {Column: "orders.pickup_date", Type: queryspec.Date},
+	},
+	Tiebreak: "id",
+}
+
+func Test_Parse(t *testing.T) {
+	q, err := spec.Parse("-day_rate, car_name", url.Values{
+		"day_rate[gte]":        {"100"},
+		"pickup_date[between]": {"2024-01-01,2024-01-31"},
+		"car_name[in]":         {"Avanza,Jazz"},
+		"page":                 {"2"},
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, []queryspec.Sort{{Field: "day_rate", Desc: true}, {Field: "car_name"}}, q.Sort)
+	assert.Equal(t, "day_rate DESC, car_name ASC, car_id ASC", spec.OrderBy(q.Sort))
+
+	conditions, args := spec.Where(q.Filters, 3)
+	assert.Equal(t, []string{
+		"car_name IN ($3,$4)",
+		"day_rate >= $5",
+		"orders.pickup_date BETWEEN $6 AND $7",
+	}, conditions)
+	assert.Equal(t, []any{
+		"Avanza", "Jazz",
+		100.0,
+		time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), time.Date(2024, 1, 31, 0, 0, 0, 0, time.UTC),
+	}, args)
+}
+
+func Test_ParseRejects(t *testing.T) {
+	cases := []struct {
+		sort    string
+		values  url.Values
+		message string
+	}{
+		{"car_name; DROP TABLE cars", nil, "invalid-sort-field"},
+		{"-password", nil, "invalid-sort-field"},
+		{"", url.Values{"password[eq]": {"x"}}, "invalid-filter-field"},
+		{"", url.Values{"car_name[gte]": {"a"}}, "invalid-filter-operator"},
+		{"", url.Values{"day_rate[gte]": {"cheap"}}, "invalid-filter-value"},
+		{"", url.Values{"pickup_date[between]": {"2024-01-01"}}, "invalid-filter-value"},
+	}
+
+	for _, tc := range cases {
+		_, err := spec.Parse(tc.sort, tc.values)
+
+		var appErr *apperr.Error
+		assert.True(t, errors.As(err, &appErr), tc.message)
+		assert.Equal(t, apperr.CodeValidation, appErr.Code)
+		assert.Equal(t, tc.message, appErr.Message)
+	}
+}
+
+func Test_Match(t *testing.T) {
+	q, err := spec.Parse("", url.Values{
+		"day_rate[between]": {"100,200"},
+		"car_name[like]":    {"AVA"},
+	})
+	assert.Nil(t, err)
+
+	assert.True(t, q.Filters[0].Match("Toyota Avanza"))
+	assert.False(t, q.Filters[0].Match("Honda Jazz"))
+	assert.True(t, q.Filters[1].Match(200.0))
+	assert.False(t, q.Filters[1].Match(200.5))
+}
+
+func Test_SearchBy(t *testing.T) {
+	searchable := &queryspec.Spec{
+		Search:        map[string]string{"car_name": "cars.car_name", "customer": "customers.name"},
+		DefaultSearch: "car_name",
+	}
+
+	for searchBy, expected := range map[string]string{"": "car_name", "customer": "customer", "any": "any"} {
+		got, err := searchable.ParseSearchBy(searchBy)
+		assert.Nil(t, err)
+		assert.Equal(t, expected, got)
+	}
+
+	_, err := searchable.ParseSearchBy("password_hash")
+	assert.NotNil(t, err)
+
+	assert.Equal(t, "jak:* & sel:*", queryspec.TSQuery(" Jak'; sel!"))
+	assert.Equal(t, "", queryspec.TSQuery("&|!"))
+}
+
+func Test_Seek(t *testing.T) {
+	sorts := []queryspec.Sort{{Field: "day_rate", Desc: true}, {Field: "car_name"}}
+	cursor, err := spec.DecodeCursor(sorts, spec.EncodeCursor(sorts, []any{150.0, "Jazz", 7}, false))
+	assert.Nil(t, err)
+	assert.Equal(t, []any{150.0, "Jazz", 7}, cursor.Values)
+
+	condition, args := spec.Seek(sorts, cursor, 2)
+	assert.Equal(t, "((day_rate < $2) OR (day_rate = $2 AND car_name > $3) OR (day_rate = $2 AND car_name = $3 AND car_id > $4))", condition)
+	assert.Equal(t, []any{150.0, "Jazz", 7}, args)
+
+	cursor.Backward = true
+	condition, _ = spec.Seek(sorts, cursor, 1)
+	assert.Equal(t, "((day_rate > $1) OR (day_rate = $1 AND car_name < $2) OR (day_rate = $1 AND car_name = $2 AND car_id < $3))", condition)
+
+	_, err = spec.DecodeCursor(sorts, "not-a-cursor")
+	assert.NotNil(t, err)
+}
+
+func Test_ParseFacets(t *testing.T) {
+	facets := &queryspec.Spec{
+		Fields: map[string]queryspec.Field{
+			"id":       {Column: "car_id", Type: queryspec.Integer},
+			"category": {Column: "category", Type: queryspec.String},
+			"seats":    {Column: "seats", Type: queryspec.Integer},
+			"features": {Column: "features", Type: queryspec.List},
+		},
+		Tiebreak: "id",
+		Facets:   []string{"category", "seats", "features"},
+	}
+
+	q, err := facets.Parse("", url.Values{
+		"category":   {"suv,van"},
+		"features":   {"gps, child_seat"},
+		"seats[gte]": {"7"},
+		"search":     {"toyota"},
+	})
+	assert.Nil(t, err)
+
+	conditions, args := facets.Where(q.Filters, 1)
+	assert.Equal(t, []string{
+		"category IN ($1,$2)",
+		"features @> ARRAY[$3,$4]::text[]",
+		"seats >= $5",
+	}, conditions)
+	assert.Equal(t, []any{"suv", "van", "gps", "child_seat", 7}, args)
+
+	assert.True(t, q.Filters[1].Match([]string{"child_seat", "gps", "bluetooth"}))
+	assert.False(t, q.Filters[1].Match([]string{"gps"}))
+
+	_, err = facets.Parse("features", nil)
+	assert.EqualError(t, err, "invalid-sort-field")
+
+	_, err = facets.Parse("", url.Values{"seats": {"many"}})
+	assert.EqualError(t, err, "invalid-filter-value")
+}
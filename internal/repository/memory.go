@@ -0,0 +1,1193 @@
+package repository
+
+import (
+	"api/internal/booking"
+	"api/internal/models"
+	"api/internal/orderstatus"
+	"api/internal/queryspec"
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Memory keeps cars, orders, car blocks, customers and locations in maps. It behaves like Postgres for
+// everything the repositories expose and lets handlers be tested without a
+// database.
+type Memory struct {
+	mu        sync.RWMutex
+	cars      map[int]models.CarsItem
+	orders    map[int]models.OrdersItem
+	blocks    map[int]models.CarBlocksItem
+	customers map[int]models.CustomersItem
+	locations map[int]models.LocationsItem
+	users     map[int]models.UsersItem
+	// created keeps when each order was booked and ready until when it
+	// holds its car, orders do not carry either
+	created        map[int]time.Time
+	ready          map[int]time.Time
+	lastCarId      int
+	lastOrderId    int
+	lastBlockId    int
+	lastCustomerId int
+	lastLocationId int
+	lastUserId     int
+}
+
+func NewMemory() *Memory {
+	return &Memory{
+		cars:      map[int]models.CarsItem{},
+		orders:    map[int]models.OrdersItem{},
+		blocks:    map[int]models.CarBlocksItem{},
+		customers: map[int]models.CustomersItem{},
+		locations: map[int]models.LocationsItem{},
+		users:     map[int]models.UsersItem{},
+		created:   map[int]time.Time{},
+		ready:     map[int]time.Time{},
+	}
+}
+
+func (m *Memory) Cars() CarRepository {
+	return &memoryCars{m}
+}
+
+func (m *Memory) Orders() OrderRepository {
+	return &memoryOrders{m}
+}
+
+func (m *Memory) CarBlocks() CarBlockRepository {
+	return &memoryCarBlocks{m}
+}
+
+func (m *Memory) Customers() CustomerRepository {
+	return &memoryCustomers{m}
+}
+
+func (m *Memory) Locations() LocationRepository {
+	return &memoryLocations{m}
+}
+
+func (m *Memory) Users() UserRepository {
+	return &memoryUsers{m}
+}
+
+// AddOrder stores order as is under a new id and returns that id, for
+// fixtures a booking would check and price. The order counts as created now.
+func (m *Memory) AddOrder(order models.OrdersItem) int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.lastOrderId++
+	order.Id = m.lastOrderId
+	m.orders[order.Id] = order
+	m.created[order.Id] = time.Now()
+
+	return order.Id
+}
+
+// AddBlock stores block as is under a new id and returns that id, also for
+// cars that are not there.
+func (m *Memory) AddBlock(block models.CarBlocksItem) int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.lastBlockId++
+	block.Id = m.lastBlockId
+	m.blocks[block.Id] = block
+
+	return block.Id
+}
+
+// matches tells whether item passes every filter.
+func matches[T any](item *T, filters []queryspec.Filter, fields map[string]func(*T) any) bool {
+	for _, filter := range filters {
+		if !filter.Match(fields[filter.Field](item)) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// page sorts items the way spec orders them in SQL and cuts out the
+// requested page. items are all the rows matching the query.
+func page[T any](items []*T, q ListQuery, spec *queryspec.Spec, fields map[string]func(*T) any) *Page[T] {
+	var total *int
+	if q.CountTotal {
+		total = new(int)
+		*total = len(items)
+	}
+
+	keys := spec.WithTiebreak(q.Sort)
+	values := func(item *T) []any {
+		values := make([]any, len(keys))
+		for i, key := range keys {
+			values[i] = fields[key.Field](item)
+		}
+		return values
+	}
+
+	sorts := keys
+	if q.Keyset && q.Cursor != nil {
+		items = slices.DeleteFunc(items, func(item *T) bool {
+			return !q.Cursor.Admits(keys, values(item))
+		})
+
+		// a backward page is read walking away from the cursor
+		if q.Cursor.Backward {
+			sorts = queryspec.Reverse(keys)
+		}
+	}
+
+	slices.SortStableFunc(items, func(a, b *T) int {
+		for _, item := range sorts {
+			get := fields[item.Field]
+			result := queryspec.Compare(get(a), get(b))
+			if item.Desc {
+				result = -result
+			}
+			if result != 0 {
+				return result
+			}
+		}
+		return 0
+	})
+
+	if q.Keyset {
+		items = items[:min(q.Limit+1, len(items))]
+		result := keysetPage(items, q, spec, fields)
+		result.Total = total
+		return result
+	}
+
+	start := min(q.Offset, len(items))
+	end := len(items)
+	if q.Limit > 0 {
+		end = min(start+q.Limit, len(items))
+	}
+
+	return &Page[T]{Items: items[start:end], Total: total}
+}
+
+// carsSearch reads the CarsSpec searchable columns off a car.
+var carsSearch = map[string]func(car *models.CarsItem) string{
+	"car_name":     func(car *models.CarsItem) string { return car.CarName },
+	"make":         func(car *models.CarsItem) string { return car.Make },
+	"model":        func(car *models.CarsItem) string { return car.Model },
+	"plate_number": func(car *models.CarsItem) string { return car.PlateNumber },
+}
+
+// ordersSearch reads the OrdersSpec searchable columns off an order.
+var ordersSearch = map[string]func(order *models.OrdersItem) string{
+	"car_name":         func(order *models.OrdersItem) string { return order.CarName },
+	"pickup_location":  func(order *models.OrdersItem) string { return order.PickupLocation },
+	"dropoff_location": func(order *models.OrdersItem) string { return order.DropoffLocation },
+	"customer":         func(order *models.OrdersItem) string { return order.CustomerName },
+}
+
+// customersSearch reads the CustomersSpec searchable columns off a customer.
+var customersSearch = map[string]func(customer *models.CustomersItem) string{
+	"name":  func(customer *models.CustomersItem) string { return customer.Name },
+	"email": func(customer *models.CustomersItem) string { return customer.Email },
+}
+
+// locationsSearch reads the LocationsSpec searchable columns off a location.
+var locationsSearch = map[string]func(location *models.LocationsItem) string{
+	"name":    func(location *models.LocationsItem) string { return location.Name },
+	"address": func(location *models.LocationsItem) string { return location.Address },
+}
+
+// searched tells whether item matches q.Search. Searching any column looks
+// for every word of the search as a word prefix, like the tsquery does.
+func searched[T any](item *T, q ListQuery, spec *queryspec.Spec, columns map[string]func(*T) string) bool {
+	if q.Search == "" {
+		return true
+	}
+
+	if q.SearchBy != queryspec.SearchAny {
+		return strings.Contains(strings.ToLower(columns[q.searchBy(spec)](item)), strings.ToLower(q.Search))
+	}
+
+	var document []string
+	for _, column := range columns {
+		document = append(document, strings.Fields(strings.ToLower(column(item)))...)
+	}
+
+	for _, word := range strings.Split(queryspec.TSQuery(q.Search), " & ") {
+		prefix := strings.ToLower(strings.TrimSuffix(word, ":*"))
+		found := false
+		for _, candidate := range document {
+			found = found || (prefix != "" && strings.HasPrefix(candidate, prefix))
+		}
+		if !found {
+			return false
+		}
+	}
+
+	return true
+}
+
+type memoryCars struct {
+	*Memory
+}
+
+func (r *memoryCars) List(ctx context.Context, q CarQuery) (*Page[models.CarsItem], error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return page(r.selected(q, q.Filters), q.ListQuery, CarsSpec, carsFields), nil
+}
+
+func (r *memoryCars) Facets(ctx context.Context, q CarQuery) (map[string]map[string]int, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	facets := map[string]map[string]int{}
+	for _, facet := range CarsSpec.Facets {
+		facets[facet] = map[string]int{}
+		for _, car := range r.selected(q, withoutField(q.Filters, facet)) {
+			values := []string{fmt.Sprint(carsFields[facet](car))}
+			if list, ok := carsFields[facet](car).([]string); ok {
+				values = list
+			}
+
+			for _, value := range values {
+				// unset values, NULL or empty in Postgres, are not counted
+				if value != "" && value != "0" {
+					facets[facet][value]++
+				}
+			}
+		}
+	}
+
+	return facets, nil
+}
+
+// selected lists the cars matching q, with filters instead of q's own.
+func (r *memoryCars) selected(q CarQuery, filters []queryspec.Filter) []*models.CarsItem {
+	cars := []*models.CarsItem{}
+	for _, car := range r.cars {
+		if (car.DeletedAt != "") != q.Deleted || !searched(&car, q.ListQuery, CarsSpec, carsSearch) {
+			continue
+		}
+
+		if q.AvailableIn != nil && r.occupied(car.Id, *q.AvailableIn) {
+			continue
+		}
+
+		car := car
+		if !matches(&car, filters, carsFields) {
+			continue
+		}
+
+		cars = append(cars, &car)
+	}
+
+	return cars
+}
+
+// occupied tells whether an order still holding carId, or a block of it,
+// overlaps dateRange.
+func (r *memoryCars) occupied(carId int, dateRange booking.Range) bool {
+	for _, block := range r.blocks {
+		if block.CarId != carId {
+			continue
+		}
+
+		blockRange, err := booking.ParseRange(block.StartDate, block.EndDate)
+		if err == nil && blockRange.Overlaps(dateRange) {
+			return true
+		}
+	}
+
+	for _, order := range r.orders {
+		if order.CarId != carId || order.DeletedAt != "" || !orderstatus.Status(order.Status).Occupies() {
+			continue
+		}
+
+		orderRange, err := booking.ParseRange(order.PickupDate, order.DropoffDate)
+		if err == nil && orderRange.Overlaps(dateRange) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (r *memoryCars) Get(ctx context.Context, id int) (*models.CarsItem, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	car, ok := r.cars[id]
+	if !ok || car.DeletedAt != "" {
+		return nil, ErrNotFound
+	}
+
+	return &car, nil
+}
+
+func (r *memoryCars) Create(ctx context.Context, car *models.CarsItem) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.lastCarId++
+	stored := *car
+	stored.Id = r.lastCarId
+	stored.Features = slices.Clone(car.Features)
+	r.cars[stored.Id] = stored
+
+	return stored.Id, nil
+}
+
+func (r *memoryCars) Update(ctx context.Context, id int, update CarUpdate) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	car, ok := r.cars[id]
+	if !ok || car.DeletedAt != "" {
+		return ErrNotFound
+	}
+
+	if update.CarName != nil {
+		car.CarName = *update.CarName
+	}
+
+	if update.DayRate != nil {
+		car.DayRate = *update.DayRate
+	}
+
+	if update.MonthRate != nil {
+		car.MonthRate = *update.MonthRate
+	}
+
+	if update.HourRate != nil {
+		car.HourRate = *update.HourRate
+	}
+
+	if update.Image != nil {
+		car.Image = *update.Image
+	}
+
+	if update.HomeLocationId != nil {
+		car.HomeLocationId = *update.HomeLocationId
+	}
+
+	for _, field := range []struct {
+		target *string
+		value  *string
+	}{
+		{&car.Make, update.Make},
+		{&car.Model, update.Model},
+		{&car.Category, update.Category},
+		{&car.Transmission, update.Transmission},
+		{&car.FuelType, update.FuelType},
+		{&car.PlateNumber, update.PlateNumber},
+	} {
+		if field.value != nil {
+			*field.target = *field.value
+		}
+	}
+
+	if update.Year != nil {
+		car.Year = *update.Year
+	}
+
+	if update.Seats != nil {
+		car.Seats = *update.Seats
+	}
+
+	if update.Features != nil {
+		car.Features = slices.Clone(*update.Features)
+	}
+
+	if update.CurrentLocationId != nil {
+		car.CurrentLocationId = *update.CurrentLocationId
+	}
+
+	r.cars[id] = car
+	return nil
+}
+
+func (r *memoryCars) Delete(ctx context.Context, id int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	car, ok := r.cars[id]
+	if !ok || car.DeletedAt != "" {
+		return ErrNotFound
+	}
+
+	now := time.Now()
+	for _, order := range r.orders {
+		if order.CarId != id || order.DeletedAt != "" {
+			continue
+		}
+
+		switch orderstatus.Status(order.Status) {
+		case orderstatus.PickedUp:
+			return ErrInUse
+		case orderstatus.Reserved:
+			if parseDate(order.DropoffDate).After(now) {
+				return ErrInUse
+			}
+		}
+	}
+
+	car.DeletedAt = booking.FormatTime(now)
+	r.cars[id] = car
+	return nil
+}
+
+func (r *memoryCars) Restore(ctx context.Context, id int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	car, ok := r.cars[id]
+	if !ok || car.DeletedAt == "" {
+		return ErrNotFound
+	}
+
+	car.DeletedAt = ""
+	r.cars[id] = car
+	return nil
+}
+
+type memoryOrders struct {
+	*Memory
+}
+
+// joined fills in the car and customer names the way the SQL joins do.
+// Deleted cars are kept for their orders, so only an order stored with a made
+// up car is left out, as by the inner join.
+func (r *memoryOrders) joined(order models.OrdersItem) (*models.OrdersItem, bool) {
+	car, ok := r.cars[order.CarId]
+	if !ok {
+		return nil, false
+	}
+
+	order.CarName = car.CarName
+	if customer, ok := r.customers[order.CustomerId]; ok {
+		order.CustomerName = customer.Name
+	}
+	return &order, true
+}
+
+func (r *memoryOrders) List(ctx context.Context, q OrderQuery) (*Page[models.OrdersItem], error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	orders := []*models.OrdersItem{}
+	for _, stored := range r.orders {
+		if (stored.DeletedAt != "") != q.Deleted {
+			continue
+		}
+
+		if q.CustomerId != 0 && stored.CustomerId != q.CustomerId {
+			continue
+		}
+
+		order, ok := r.joined(stored)
+		if !ok || !searched(order, q.ListQuery, OrdersSpec, ordersSearch) || !matches(order, q.Filters, ordersFields) {
+			continue
+		}
+
+		orders = append(orders, order)
+	}
+
+	return page(orders, q.ListQuery, OrdersSpec, ordersFields), nil
+}
+
+func (r *memoryOrders) Get(ctx context.Context, id int) (*models.OrdersItem, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	stored, ok := r.orders[id]
+	if !ok || stored.DeletedAt != "" {
+		return nil, ErrNotFound
+	}
+
+	order, ok := r.joined(stored)
+	if !ok {
+		return nil, ErrNotFound
+	}
+
+	return order, nil
+}
+
+func (r *memoryOrders) Delete(ctx context.Context, id int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	order, ok := r.orders[id]
+	if !ok || order.DeletedAt != "" {
+		return ErrNotFound
+	}
+
+	order.DeletedAt = booking.FormatTime(time.Now())
+	r.orders[id] = order
+	return nil
+}
+
+func (r *memoryOrders) Stats(ctx context.Context) (OrderStats, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	now := time.Now()
+	var stats OrderStats
+	occupied := map[int]bool{}
+	for _, order := range r.orders {
+		if r.created[order.Id].After(now.Add(-time.Hour)) {
+			stats.CreatedLastHour++
+		}
+
+		if order.DeletedAt != "" {
+			continue
+		}
+
+		switch orderstatus.Status(order.Status) {
+		case orderstatus.PickedUp:
+			stats.ActiveRentals++
+			occupied[order.CarId] = true
+		case orderstatus.Reserved:
+			if !parseDate(order.PickupDate).After(now) && now.Before(parseDate(order.DropoffDate)) {
+				occupied[order.CarId] = true
+			}
+		}
+	}
+	stats.OccupiedCars = len(occupied)
+
+	return stats, nil
+}
+
+func (r *memoryOrders) Restore(ctx context.Context, id int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	order, ok := r.orders[id]
+	if !ok || order.DeletedAt == "" {
+		return ErrNotFound
+	}
+
+	if r.cars[order.CarId].DeletedAt != "" {
+		return ErrCarDeleted
+	}
+
+	order.DeletedAt = ""
+	if orderstatus.Status(order.Status).Occupies() && r.overlaps(order, r.readyDate(order)) {
+		return ErrOverlap
+	}
+
+	r.orders[id] = order
+	return nil
+}
+
+type memoryCustomers struct {
+	*Memory
+}
+
+// searchedCustomer tells whether customer matches q.Search, which without a
+// search_by is looked for in both the name and the email.
+func searchedCustomer(customer *models.CustomersItem, q ListQuery) bool {
+	if q.Search != "" && q.SearchBy == "" {
+		search := strings.ToLower(q.Search)
+		return strings.Contains(strings.ToLower(customer.Name), search) || strings.Contains(strings.ToLower(customer.Email), search)
+	}
+
+	return searched(customer, q, CustomersSpec, customersSearch)
+}
+
+func (r *memoryCustomers) List(ctx context.Context, q ListQuery) (*Page[models.CustomersItem], error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	customers := []*models.CustomersItem{}
+	for _, customer := range r.customers {
+		customer := customer
+		if !searchedCustomer(&customer, q) || !matches(&customer, q.Filters, customersFields) {
+			continue
+		}
+
+		customers = append(customers, &customer)
+	}
+
+	return page(customers, q, CustomersSpec, customersFields), nil
+}
+
+func (r *memoryCustomers) Get(ctx context.Context, id int) (*models.CustomersItem, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	customer, ok := r.customers[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+
+	return &customer, nil
+}
+
+func (r *memoryCustomers) Create(ctx context.Context, customer *models.CustomersItem) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.lastCustomerId++
+	stored := *customer
+	stored.Id = r.lastCustomerId
+	r.customers[stored.Id] = stored
+
+	return stored.Id, nil
+}
+
+func (r *memoryCustomers) Update(ctx context.Context, id int, update CustomerUpdate) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	customer, ok := r.customers[id]
+	if !ok {
+		return ErrNotFound
+	}
+
+	for _, field := range []struct {
+		target *string
+		value  *string
+	}{
+		{&customer.Name, update.Name},
+		{&customer.Email, update.Email},
+		{&customer.Phone, update.Phone},
+		{&customer.LicenceNumber, update.LicenceNumber},
+		{&customer.LicenceExpiry, update.LicenceExpiry},
+	} {
+		if field.value != nil {
+			*field.target = *field.value
+		}
+	}
+
+	r.customers[id] = customer
+	return nil
+}
+
+func (r *memoryCustomers) Delete(ctx context.Context, id int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.customers[id]; !ok {
+		return ErrNotFound
+	}
+
+	// the foreign key of orders counts deleted ones as well
+	for _, order := range r.orders {
+		if order.CustomerId == id {
+			return ErrInUse
+		}
+	}
+
+	delete(r.customers, id)
+	return nil
+}
+
+type memoryLocations struct {
+	*Memory
+}
+
+// searchedLocation tells whether location matches q.Search. Searching any
+// column looks for the search in the name or the address, there is no
+// full-text index for branches.
+func searchedLocation(location *models.LocationsItem, q ListQuery) bool {
+	if q.Search != "" && q.SearchBy == queryspec.SearchAny {
+		search := strings.ToLower(q.Search)
+		return strings.Contains(strings.ToLower(location.Name), search) || strings.Contains(strings.ToLower(location.Address), search)
+	}
+
+	return searched(location, q, LocationsSpec, locationsSearch)
+}
+
+// cloneLocation copies location so callers cannot reach the stored hours.
+func cloneLocation(location models.LocationsItem) *models.LocationsItem {
+	location.OpeningHours = maps.Clone(location.OpeningHours)
+	if location.OpeningHours == nil {
+		location.OpeningHours = map[string]string{}
+	}
+
+	return &location
+}
+
+func (r *memoryLocations) List(ctx context.Context, q ListQuery) (*Page[models.LocationsItem], error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	locations := []*models.LocationsItem{}
+	for _, stored := range r.locations {
+		location := cloneLocation(stored)
+		if !searchedLocation(location, q) || !matches(location, q.Filters, locationsFields) {
+			continue
+		}
+
+		locations = append(locations, location)
+	}
+
+	return page(locations, q, LocationsSpec, locationsFields), nil
+}
+
+func (r *memoryLocations) Get(ctx context.Context, id int) (*models.LocationsItem, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	location, ok := r.locations[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+
+	return cloneLocation(location), nil
+}
+
+func (r *memoryLocations) GetByName(ctx context.Context, name string) (*models.LocationsItem, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, location := range r.locations {
+		if strings.EqualFold(location.Name, strings.TrimSpace(name)) {
+			return cloneLocation(location), nil
+		}
+	}
+
+	return nil, ErrNotFound
+}
+
+func (r *memoryLocations) Create(ctx context.Context, location *models.LocationsItem) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.lastLocationId++
+	stored := *cloneLocation(*location)
+	stored.Id = r.lastLocationId
+	r.locations[stored.Id] = stored
+
+	return stored.Id, nil
+}
+
+func (r *memoryLocations) Update(ctx context.Context, id int, update LocationUpdate) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	location, ok := r.locations[id]
+	if !ok {
+		return ErrNotFound
+	}
+
+	for _, field := range []struct {
+		target *string
+		value  *string
+	}{
+		{&location.Name, update.Name},
+		{&location.Address, update.Address},
+		{&location.Timezone, update.Timezone},
+	} {
+		if field.value != nil {
+			*field.target = *field.value
+		}
+	}
+
+	if update.Latitude != nil {
+		location.Latitude = update.Latitude
+	}
+
+	if update.Longitude != nil {
+		location.Longitude = update.Longitude
+	}
+
+	if update.OpeningHours != nil {
+		location.OpeningHours = maps.Clone(update.OpeningHours)
+	}
+
+	r.locations[id] = location
+	return nil
+}
+
+func (r *memoryLocations) Delete(ctx context.Context, id int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.locations[id]; !ok {
+		return ErrNotFound
+	}
+
+	// the foreign keys of cars and orders count deleted ones as well
+	for _, car := range r.cars {
+		if car.HomeLocationId == id || car.CurrentLocationId == id {
+			return ErrInUse
+		}
+	}
+
+	for _, order := range r.orders {
+		if order.PickupLocationId == id || order.DropoffLocationId == id {
+			return ErrInUse
+		}
+	}
+
+	delete(r.locations, id)
+	return nil
+}
+
+func (r *memoryOrders) Occupancy(ctx context.Context, carId, excludeOrderId int, dateRange booking.Range) (*Occupancy, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return (&memoryBooking{r.Memory}).Occupancy(ctx, carId, excludeOrderId, dateRange)
+}
+
+// Book holds the lock for the whole of fn and puts the orders back when it
+// fails, like the transaction rolling back.
+func (r *memoryOrders) Book(ctx context.Context, fn func(b Booking) error) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	orders, lastOrderId := maps.Clone(r.orders), r.lastOrderId
+	err := fn(&memoryBooking{r.Memory})
+	if err != nil {
+		r.orders, r.lastOrderId = orders, lastOrderId
+	}
+
+	return err
+}
+
+func (r *memoryOrders) Transition(ctx context.Context, id int, to orderstatus.Status, allow func(order *models.OrdersItem) error) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	order, err := (&memoryBooking{r.Memory}).Order(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	err = allow(order)
+	if err != nil {
+		return err
+	}
+
+	stored := r.orders[id]
+	stored.Status = string(to)
+	r.orders[id] = stored
+
+	if car, ok := r.cars[stored.CarId]; ok && to == orderstatus.Returned && stored.DropoffLocationId != 0 {
+		car.CurrentLocationId = stored.DropoffLocationId
+		r.cars[car.Id] = car
+	}
+
+	return nil
+}
+
+// memoryBooking works on Memory under the lock its caller holds.
+type memoryBooking struct {
+	*Memory
+}
+
+func (b *memoryBooking) Order(ctx context.Context, id int) (*models.OrdersItem, error) {
+	stored, ok := b.orders[id]
+	if !ok || stored.DeletedAt != "" {
+		return nil, ErrNotFound
+	}
+
+	order, ok := (&memoryOrders{b.Memory}).joined(stored)
+	if !ok {
+		return nil, ErrNotFound
+	}
+
+	return order, nil
+}
+
+func (b *memoryBooking) Car(ctx context.Context, id int) (*models.CarsItem, error) {
+	car, ok := b.cars[id]
+	if !ok || car.DeletedAt != "" {
+		return nil, ErrNotFound
+	}
+
+	return &car, nil
+}
+
+func (b *memoryBooking) Customer(ctx context.Context, id int) (*models.CustomersItem, error) {
+	customer, ok := b.customers[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+
+	return &customer, nil
+}
+
+func (b *memoryBooking) Location(ctx context.Context, id int) (*models.LocationsItem, error) {
+	location, ok := b.locations[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+
+	return cloneLocation(location), nil
+}
+
+func (b *memoryBooking) Occupancy(ctx context.Context, carId, excludeOrderId int, dateRange booking.Range) (*Occupancy, error) {
+	occupancy := &Occupancy{Orders: []*models.OrdersItem{}, Blocks: []*models.CarBlocksItem{}}
+	for _, stored := range b.orders {
+		if stored.CarId != carId || stored.Id == excludeOrderId || stored.DeletedAt != "" || !orderstatus.Status(stored.Status).Occupies() {
+			continue
+		}
+
+		orderRange, err := booking.ParseRange(stored.PickupDate, stored.DropoffDate)
+		if err != nil || !orderRange.Overlaps(dateRange) {
+			continue
+		}
+
+		if order, ok := (&memoryOrders{b.Memory}).joined(stored); ok {
+			occupancy.Orders = append(occupancy.Orders, order)
+		}
+	}
+
+	for _, block := range b.blocks {
+		if block.CarId != carId {
+			continue
+		}
+
+		blockRange, err := booking.ParseRange(block.StartDate, block.EndDate)
+		if err == nil && blockRange.Overlaps(dateRange) {
+			block := block
+			occupancy.Blocks = append(occupancy.Blocks, &block)
+		}
+	}
+
+	slices.SortFunc(occupancy.Orders, func(a, b *models.OrdersItem) int {
+		return parseDate(a.PickupDate).Compare(parseDate(b.PickupDate))
+	})
+	slices.SortFunc(occupancy.Blocks, func(a, b *models.CarBlocksItem) int {
+		return parseDate(a.StartDate).Compare(parseDate(b.StartDate))
+	})
+
+	return occupancy, nil
+}
+
+func (b *memoryBooking) CarLocation(ctx context.Context, carId, excludeOrderId int, at time.Time) (int, error) {
+	var last *models.OrdersItem
+	for _, order := range b.orders {
+		if order.CarId != carId || order.Id == excludeOrderId || order.DeletedAt != "" || !orderstatus.Status(order.Status).Occupies() {
+			continue
+		}
+
+		dropoff := parseDate(order.DropoffDate)
+		if dropoff.After(at) || (last != nil && !dropoff.After(parseDate(last.DropoffDate))) {
+			continue
+		}
+
+		order := order
+		last = &order
+	}
+
+	if last != nil {
+		return last.DropoffLocationId, nil
+	}
+
+	car, err := b.Car(ctx, carId)
+	if err != nil {
+		return 0, err
+	}
+
+	return car.CurrentLocationId, nil
+}
+
+func (b *memoryBooking) Insert(ctx context.Context, order *models.OrdersItem, dateRange booking.Range, readyDate time.Time) (int, error) {
+	b.lastOrderId++
+	stored := *order
+	stored.Id = b.lastOrderId
+	stored.PickupDate = booking.FormatTime(dateRange.Start)
+	stored.DropoffDate = booking.FormatTime(dateRange.End)
+	stored.Status = string(orderstatus.Reserved)
+	if b.overlaps(stored, readyDate) {
+		return 0, ErrOverlap
+	}
+
+	b.orders[stored.Id] = stored
+	b.created[stored.Id] = time.Now()
+	b.ready[stored.Id] = readyDate
+
+	return stored.Id, nil
+}
+
+func (b *memoryBooking) Update(ctx context.Context, id int, update OrderUpdate) error {
+	order, ok := b.orders[id]
+	if !ok || order.DeletedAt != "" {
+		return ErrNotFound
+	}
+
+	if update.CarId != nil {
+		order.CarId = *update.CarId
+	}
+
+	if update.CustomerId != nil {
+		order.CustomerId = *update.CustomerId
+	}
+
+	if update.OrderDate != nil {
+		order.OrderDate = *update.OrderDate
+	}
+
+	if update.PickupDate != nil {
+		order.PickupDate = booking.FormatTime(*update.PickupDate)
+	}
+
+	if update.DropoffDate != nil {
+		order.DropoffDate = booking.FormatTime(*update.DropoffDate)
+	}
+
+	if update.Pickup != nil {
+		order.PickupLocation = update.Pickup.Name
+		order.PickupLocationId = update.Pickup.Id
+	}
+
+	if update.Dropoff != nil {
+		order.DropoffLocation = update.Dropoff.Name
+		order.DropoffLocationId = update.Dropoff.Id
+	}
+
+	if update.TotalPrice != nil {
+		order.TotalPrice = *update.TotalPrice
+	}
+
+	readyDate := b.readyDate(order)
+	if update.ReadyDate != nil {
+		readyDate = *update.ReadyDate
+	}
+
+	if orderstatus.Status(order.Status).Occupies() && b.overlaps(order, readyDate) {
+		return ErrOverlap
+	}
+
+	b.orders[id] = order
+	b.ready[id] = readyDate
+	return nil
+}
+
+// readyDate is until when order holds its car, its dropoff for orders
+// stored without one.
+func (m *Memory) readyDate(order models.OrdersItem) time.Time {
+	if ready, ok := m.ready[order.Id]; ok {
+		return ready
+	}
+
+	return parseDate(order.DropoffDate)
+}
+
+// overlaps tells whether another live order holding the car of order does so
+// between its pickup and readyDate, what orders_no_overlap keeps out in
+// Postgres.
+func (m *Memory) overlaps(order models.OrdersItem, readyDate time.Time) bool {
+	pickup := parseDate(order.PickupDate)
+	for _, other := range m.orders {
+		if other.Id == order.Id || other.CarId != order.CarId || other.DeletedAt != "" || !orderstatus.Status(other.Status).Occupies() {
+			continue
+		}
+
+		if parseDate(other.PickupDate).Before(readyDate) && pickup.Before(m.readyDate(other)) {
+			return true
+		}
+	}
+
+	return false
+}
+
+type memoryCarBlocks struct {
+	*Memory
+}
+
+func (r *memoryCarBlocks) List(ctx context.Context, carId int) ([]*models.CarBlocksItem, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	blocks := []*models.CarBlocksItem{}
+	for _, block := range r.blocks {
+		if block.CarId == carId {
+			block := block
+			blocks = append(blocks, &block)
+		}
+	}
+
+	slices.SortFunc(blocks, func(a, b *models.CarBlocksItem) int {
+		if result := parseDate(a.StartDate).Compare(parseDate(b.StartDate)); result != 0 {
+			return result
+		}
+		return a.Id - b.Id
+	})
+
+	return blocks, nil
+}
+
+func (r *memoryCarBlocks) Create(ctx context.Context, block *models.CarBlocksItem) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if car, ok := r.cars[block.CarId]; !ok || car.DeletedAt != "" {
+		return 0, ErrNotFound
+	}
+
+	r.lastBlockId++
+	stored := *block
+	stored.Id = r.lastBlockId
+	r.blocks[stored.Id] = stored
+
+	return stored.Id, nil
+}
+
+func (r *memoryCarBlocks) Delete(ctx context.Context, carId, id int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	block, ok := r.blocks[id]
+	if !ok || block.CarId != carId {
+		return ErrNotFound
+	}
+
+	delete(r.blocks, id)
+	return nil
+}
+
+type memoryUsers struct {
+	*Memory
+}
+
+func (r *memoryUsers) GetByEmail(ctx context.Context, email string) (*models.UsersItem, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, user := range r.users {
+		if user.Email == email {
+			return &user, nil
+		}
+	}
+
+	return nil, ErrNotFound
+}
+
+func (r *memoryUsers) Create(ctx context.Context, user *models.UsersItem) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, other := range r.users {
+		if other.Email == user.Email {
+			return 0, ErrDuplicate
+		}
+	}
+
+	if _, ok := r.customers[user.CustomerId]; user.CustomerId != 0 && !ok {
+		return 0, ErrNotFound
+	}
+
+	r.lastUserId++
+	stored := *user
+	stored.Id = r.lastUserId
+	r.users[stored.Id] = stored
+
+	return stored.Id, nil
+}
+
+func (r *memoryUsers) Count(ctx context.Context) (int, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return len(r.users), nil
+}
@@ -0,0 +1,164 @@
+package repository_test
+
+import (
+	"api/internal/models"
+	"api/internal/queryspec"
+	"api/internal/repository"
+	"context"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_MemoryCarsList(t *testing.T) {
+	ctx := context.Background()
+	cars := repository.NewMemory().Cars()
+	for _, name := range []string{"Delta", "alpha", "Charlie", "bravo"} {
+		_, err := cars.Create(ctx, &models.CarsItem{CarName: name})
+		assert.Nil(t, err)
+	}
+
+	page, err := cars.List(ctx, repository.CarQuery{ListQuery: repository.ListQuery{
+		Sort:       []queryspec.Sort{{Field: "id", Desc: true}},
+		Limit:      2,
+		Offset:     1,
+		CountTotal: true,
+	}})
+	assert.Nil(t, err)
+	assert.Equal(t, 4, *page.Total)
+	assert.Len(t, page.Items, 2)
+	assert.Equal(t, "Charlie", page.Items[0].CarName)
+	assert.Equal(t, "alpha", page.Items[1].CarName)
+
+	page, err = cars.List(ctx, repository.CarQuery{ListQuery: repository.ListQuery{Search: "A", Offset: 10}})
+	assert.Nil(t, err)
+	assert.Nil(t, page.Total)
+	assert.Empty(t, page.Items)
+
+	q, err := repository.CarsSpec.Parse("-car_name", url.Values{"id[in]": {"1,2,4"}})
+	assert.Nil(t, err)
+	page, err = cars.List(ctx, repository.CarQuery{ListQuery: repository.ListQuery{Sort: q.Sort, Filters: q.Filters, CountTotal: true}})
+	assert.Nil(t, err)
+	assert.Equal(t, 3, *page.Total)
+	assert.Equal(t, []string{"bravo", "alpha", "Delta"}, names(page.Items))
+
+	assert.ErrorIs(t, cars.Delete(ctx, 42), repository.ErrNotFound)
+	_, err = cars.Get(ctx, 42)
+	assert.ErrorIs(t, err, repository.ErrNotFound)
+}
+
+func Test_MemoryOrdersJoinCars(t *testing.T) {
+	ctx := context.Background()
+	mem := repository.NewMemory()
+	carId, err := mem.Cars().Create(ctx, &models.CarsItem{CarName: "Avanza"})
+	assert.Nil(t, err)
+
+	kept := mem.AddOrder(models.OrdersItem{CarId: carId})
+	orphan := mem.AddOrder(models.OrdersItem{CarId: carId + 1})
+
+	order, err := mem.Orders().Get(ctx, kept)
+	assert.Nil(t, err)
+	assert.Equal(t, "Avanza", order.CarName)
+
+	// like the inner join on cars, orders of a missing car are not returned
+	_, err = mem.Orders().Get(ctx, orphan)
+	assert.ErrorIs(t, err, repository.ErrNotFound)
+
+	page, err := mem.Orders().List(ctx, repository.OrderQuery{})
+	assert.Nil(t, err)
+	assert.Len(t, page.Items, 1)
+}
+
+func Test_MemoryCarsKeyset(t *testing.T) {
+	ctx := context.Background()
+	cars := repository.NewMemory().Cars()
+	for i, rate := range []float64{300, 100, 300, 200, 300} {
+		_, err := cars.Create(ctx, &models.CarsItem{CarName: string(rune('a' + i)), DayRate: rate})
+		assert.Nil(t, err)
+	}
+
+	sort := []queryspec.Sort{{Field: "day_rate", Desc: true}}
+	list := func(cursor string) *repository.Page[models.CarsItem] {
+		q := repository.ListQuery{Sort: sort, Limit: 2, Keyset: true}
+		if cursor != "" {
+			var err error
+			q.Cursor, err = repository.CarsSpec.DecodeCursor(sort, cursor)
+			assert.Nil(t, err)
+		}
+
+		page, err := cars.List(ctx, repository.CarQuery{ListQuery: q})
+		assert.Nil(t, err)
+		return page
+	}
+
+	// equal day rates fall back to the id, so no row is skipped or repeated
+	first := list("")
+	assert.Equal(t, []string{"a", "c"}, names(first.Items))
+	assert.Empty(t, first.PrevCursor)
+
+	second := list(first.NextCursor)
+	assert.Equal(t, []string{"e", "d"}, names(second.Items))
+	assert.NotEmpty(t, second.PrevCursor)
+
+	last := list(second.NextCursor)
+	assert.Equal(t, []string{"b"}, names(last.Items))
+	assert.Empty(t, last.NextCursor)
+
+	back := list(last.PrevCursor)
+	assert.Equal(t, []string{"e", "d"}, names(back.Items))
+	assert.Equal(t, second.NextCursor, back.NextCursor)
+
+	start := list(back.PrevCursor)
+	assert.Equal(t, []string{"a", "c"}, names(start.Items))
+	assert.Empty(t, start.PrevCursor)
+
+	// a cursor only resumes the sort it was issued for
+	_, err := repository.CarsSpec.DecodeCursor([]queryspec.Sort{{Field: "car_name"}}, first.NextCursor)
+	assert.NotNil(t, err)
+}
+
+func Test_MemoryOrdersKeysetByOrderTime(t *testing.T) {
+	ctx := context.Background()
+	mem := repository.NewMemory()
+	carId, err := mem.Cars().Create(ctx, &models.CarsItem{CarName: "a"})
+	assert.Nil(t, err)
+
+	// three orders placed on one day, the latest first in order_date DESC
+	day := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	for _, hour := range []int{9, 15, 11} {
+		mem.AddOrder(models.OrdersItem{CarId: carId, OrderDate: "2024-05-01", OrderedAt: day.Add(time.Duration(hour) * time.Hour)})
+	}
+
+	sort := []queryspec.Sort{{Field: "order_date", Desc: true}}
+	var ids []int
+	cursor := ""
+	for i := 0; i < 4; i++ {
+		q := repository.ListQuery{Sort: sort, Limit: 1, Keyset: true}
+		if cursor != "" {
+			q.Cursor, err = repository.OrdersSpec.DecodeCursor(sort, cursor)
+			assert.Nil(t, err)
+		}
+
+		page, err := mem.Orders().List(ctx, repository.OrderQuery{ListQuery: q})
+		assert.Nil(t, err)
+		for _, order := range page.Items {
+			ids = append(ids, order.Id)
+		}
+		if page.NextCursor == "" {
+			break
+		}
+		cursor = page.NextCursor
+	}
+
+	assert.Equal(t, []int{2, 3, 1}, ids)
+}
+
+func names(cars []*models.CarsItem) []string {
+	result := []string{}
+	for _, car := range cars {
+		result = append(result, car.CarName)
+	}
+	return result
+}
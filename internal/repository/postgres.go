@@ -0,0 +1,1369 @@
+package repository
+
+import (
+	"api/internal/booking"
+	"api/internal/database"
+	"api/internal/models"
+	"api/internal/orderstatus"
+	"api/internal/queryspec"
+	"api/internal/utils"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Postgres hands out the repositories backed by database.Service.
+type Postgres struct {
+	db database.Service
+}
+
+func NewPostgres(db database.Service) *Postgres {
+	return &Postgres{db: db}
+}
+
+func (p *Postgres) Cars() CarRepository {
+	return &postgresCars{db: p.db}
+}
+
+func (p *Postgres) Orders() OrderRepository {
+	return &postgresOrders{db: p.db}
+}
+
+func (p *Postgres) CarBlocks() CarBlockRepository {
+	return &postgresCarBlocks{db: p.db}
+}
+
+func (p *Postgres) Customers() CustomerRepository {
+	return &postgresCustomers{db: p.db}
+}
+
+func (p *Postgres) Locations() LocationRepository {
+	return &postgresLocations{db: p.db}
+}
+
+func (p *Postgres) Users() UserRepository {
+	return &postgresUsers{db: p.db}
+}
+
+type postgresCars struct {
+	db database.Service
+}
+
+const carsFrom = `
+	FROM cars
+`
+
+const carsSelect = `
+	SELECT
+		car_id,
+		car_name,
+		day_rate,
+		month_rate,
+		hour_rate,
+		image,
+		home_location_id,
+		current_location_id,
+		make,
+		model,
+		year,
+		category,
+		seats,
+		transmission,
+		fuel_type,
+		plate_number,
+		array_to_json(features),
+		deleted_at
+` + carsFrom
+
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+func scanCar(row scanner) (*models.CarsItem, error) {
+	var id, homeLocationId, currentLocationId, year, seats sql.NullInt64
+	var dayRate, monthRate, hourRate sql.NullFloat64
+	var carName, image, carMake, carModel, category, transmission, fuelType, plateNumber sql.NullString
+	var features []byte
+	var deletedAt sql.NullTime
+	err := row.Scan(
+		&id,
+		&carName,
+		&dayRate,
+		&monthRate,
+		&hourRate,
+		&image,
+		&homeLocationId,
+		&currentLocationId,
+		&carMake,
+		&carModel,
+		&year,
+		&category,
+		&seats,
+		&transmission,
+		&fuelType,
+		&plateNumber,
+		&features,
+		&deletedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	car := &models.CarsItem{
+		Id:                int(id.Int64),
+		CarName:           strings.TrimSpace(carName.String),
+		DayRate:           dayRate.Float64,
+		MonthRate:         monthRate.Float64,
+		HourRate:          hourRate.Float64,
+		Image:             strings.TrimSpace(image.String),
+		HomeLocationId:    int(homeLocationId.Int64),
+		CurrentLocationId: int(currentLocationId.Int64),
+		Make:              carMake.String,
+		Model:             carModel.String,
+		Year:              int(year.Int64),
+		Category:          category.String,
+		Seats:             int(seats.Int64),
+		Transmission:      transmission.String,
+		FuelType:          fuelType.String,
+		PlateNumber:       plateNumber.String,
+		Features:          []string{},
+	}
+
+	if deletedAt.Valid {
+		car.DeletedAt = booking.FormatTime(deletedAt.Time)
+	}
+
+	err = json.Unmarshal(features, &car.Features)
+	if err != nil {
+		return nil, err
+	}
+
+	return car, nil
+}
+
+func (r *postgresCars) List(ctx context.Context, q CarQuery) (*Page[models.CarsItem], error) {
+	conditions, params := carConditions(q, q.Filters)
+	return listPage(ctx, r.db, q.ListQuery, CarsSpec, carsFields, carsSelect, carsFrom, conditions, params, scanCar)
+}
+
+// Facets counts the cars of every value of each CarsSpec facet, applying
+// all of q but its filters on that same facet, so the other values of a
+// facet being filtered on stay in sight.
+func (r *postgresCars) Facets(ctx context.Context, q CarQuery) (map[string]map[string]int, error) {
+	facets := map[string]map[string]int{}
+	for _, facet := range CarsSpec.Facets {
+		conditions, params := carConditions(q, withoutField(q.Filters, facet))
+
+		value := CarsSpec.Fields[facet].Column
+		if CarsSpec.Fields[facet].Type == queryspec.List {
+			value = fmt.Sprintf("unnest(%s)", value)
+		}
+
+		where := ""
+		if len(conditions) > 0 {
+			where = "WHERE " + strings.Join(conditions, " AND ")
+		}
+
+		rows, err := r.db.Query(ctx, fmt.Sprintf("SELECT value::text, COUNT(*) FROM (SELECT %s AS value %s %s) facet WHERE value IS NOT NULL AND value::text <> '' GROUP BY value", value, carsFrom, where), params...)
+		if err != nil {
+			return nil, err
+		}
+
+		facets[facet] = map[string]int{}
+		for rows.Next() {
+			var key string
+			var count int
+			err = rows.Scan(&key, &count)
+			if err != nil {
+				rows.Close()
+				return nil, err
+			}
+
+			facets[facet][key] = count
+		}
+
+		err = rows.Err()
+		rows.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return facets, nil
+}
+
+// carConditions renders the availability window, search and filters of q as
+// SQL conditions on cars, taking the filters from filters instead of q.
+func carConditions(q CarQuery, filters []queryspec.Filter) ([]string, []any) {
+	conditions := []string{deletedCondition("cars", q.Deleted)}
+	var params []interface{}
+	count := 0
+
+	if q.AvailableIn != nil {
+		// leave out every car that has a live order or a block overlapping
+		// the window
+		conditions = append(conditions, fmt.Sprintf(
+			"NOT EXISTS (SELECT 1 FROM orders WHERE orders.car_id=cars.car_id AND orders.deleted_at IS NULL AND %s AND %s)",
+			orderstatus.OccupyingCondition("orders.status"),
+			booking.OverlapCondition("orders.pickup_date", "orders.dropoff_date", count+1, count+2),
+		), fmt.Sprintf(
+			"NOT EXISTS (SELECT 1 FROM car_blocks WHERE car_blocks.car_id=cars.car_id AND %s)",
+			booking.OverlapCondition("car_blocks.start_date", "car_blocks.end_date", count+1, count+2),
+		))
+		params = append(params, q.AvailableIn.Start, q.AvailableIn.End)
+		count += 2
+	}
+
+	if len(q.Search) > 0 {
+		count++
+		conditions = append(conditions, searchCondition(CarsSpec, q.ListQuery, count, "cars.search_vector"))
+		params = append(params, searchArg(q.ListQuery))
+	}
+
+	where, args := CarsSpec.Where(filters, count+1)
+	conditions = append(conditions, where...)
+	params = append(params, args...)
+
+	return conditions, params
+}
+
+func (r *postgresCars) Get(ctx context.Context, id int) (*models.CarsItem, error) {
+	car, err := scanCar(r.db.QueryRow(ctx, carsSelect+" WHERE car_id = $1 AND deleted_at IS NULL", id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+
+	return car, err
+}
+
+func (r *postgresCars) Create(ctx context.Context, car *models.CarsItem) (int, error) {
+	var carId int
+	err := r.db.QueryRow(ctx, "INSERT INTO cars (car_name, day_rate, month_rate, hour_rate, image, home_location_id, current_location_id, make, model, year, category, seats, transmission, fuel_type, plate_number, features) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16) RETURNING car_id",
+		car.CarName, car.DayRate, car.MonthRate, nullableRate(car.HourRate), car.Image, nullableInt(car.HomeLocationId), nullableInt(car.CurrentLocationId),
+		car.Make, car.Model, nullableInt(car.Year), car.Category, nullableInt(car.Seats), car.Transmission, car.FuelType, nullableString(car.PlateNumber), features(car.Features),
+	).Scan(&carId)
+	return carId, err
+}
+
+func (r *postgresCars) Update(ctx context.Context, id int, update CarUpdate) error {
+	query := "UPDATE cars SET"
+	var params []interface{}
+	var set []string
+
+	count := 0
+	if update.CarName != nil {
+		count++
+		set = append(set, fmt.Sprintf("car_name=$%d", count))
+		params = append(params, *update.CarName)
+	}
+
+	if update.Image != nil {
+		count++
+		set = append(set, fmt.Sprintf("image=$%d", count))
+		params = append(params, *update.Image)
+	}
+
+	if update.DayRate != nil {
+		count++
+		set = append(set, fmt.Sprintf("day_rate=$%d", count))
+		params = append(params, *update.DayRate)
+	}
+
+	if update.MonthRate != nil {
+		count++
+		set = append(set, fmt.Sprintf("month_rate=$%d", count))
+		params = append(params, *update.MonthRate)
+	}
+
+	if update.HourRate != nil {
+		count++
+		set = append(set, fmt.Sprintf("hour_rate=$%d", count))
+		params = append(params, nullableRate(*update.HourRate))
+	}
+
+	if update.HomeLocationId != nil {
+		count++
+		set = append(set, fmt.Sprintf("home_location_id=$%d", count))
+		params = append(params, nullableInt(*update.HomeLocationId))
+	}
+
+	if update.CurrentLocationId != nil {
+		count++
+		set = append(set, fmt.Sprintf("current_location_id=$%d", count))
+		params = append(params, nullableInt(*update.CurrentLocationId))
+	}
+
+	for _, field := range []struct {
+		column string
+		value  *string
+	}{
+		{"make", update.Make},
+		{"model", update.Model},
+		{"category", update.Category},
+		{"transmission", update.Transmission},
+		{"fuel_type", update.FuelType},
+	} {
+		if field.value != nil {
+			count++
+			set = append(set, fmt.Sprintf("%s=$%d", field.column, count))
+			params = append(params, *field.value)
+		}
+	}
+
+	if update.Year != nil {
+		count++
+		set = append(set, fmt.Sprintf("year=$%d", count))
+		params = append(params, nullableInt(*update.Year))
+	}
+
+	if update.Seats != nil {
+		count++
+		set = append(set, fmt.Sprintf("seats=$%d", count))
+		params = append(params, nullableInt(*update.Seats))
+	}
+
+	if update.PlateNumber != nil {
+		count++
+		set = append(set, fmt.Sprintf("plate_number=$%d", count))
+		params = append(params, nullableString(*update.PlateNumber))
+	}
+
+	if update.Features != nil {
+		count++
+		set = append(set, fmt.Sprintf("features=$%d", count))
+		params = append(params, features(*update.Features))
+	}
+
+	// nothing to change, only tell whether the car is there
+	if len(set) == 0 {
+		_, err := r.Get(ctx, id)
+		return err
+	}
+
+	count++
+	query = fmt.Sprintf("%s %s WHERE car_id=$%d AND deleted_at IS NULL", query, strings.Join(set, ","), count)
+	params = append(params, id)
+
+	res, err := r.db.Exec(ctx, query, params...)
+	if err != nil {
+		return err
+	}
+
+	return expectAffected(res)
+}
+
+func (r *postgresCars) Delete(ctx context.Context, id int) error {
+	// picked up orders hold the car until it is back, even overdue, and
+	// reservations until their dropoff
+	query := fmt.Sprintf(
+		"SELECT EXISTS (SELECT 1 FROM orders WHERE car_id=$1 AND deleted_at IS NULL AND (status='%s' OR (status='%s' AND dropoff_date > NOW())))",
+		orderstatus.PickedUp, orderstatus.Reserved,
+	)
+
+	return database.WithSerializableTx(ctx, r.db, func(tx *database.Tx) error {
+		var active bool
+		err := tx.QueryRow(ctx, query, id).Scan(&active)
+		if err != nil {
+			return err
+		}
+
+		if active {
+			return ErrInUse
+		}
+
+		res, err := tx.Exec(ctx, "UPDATE cars SET deleted_at=NOW() WHERE car_id=$1 AND deleted_at IS NULL", id)
+		if err != nil {
+			return err
+		}
+
+		return expectAffected(res)
+	})
+}
+
+func (r *postgresCars) Restore(ctx context.Context, id int) error {
+	res, err := r.db.Exec(ctx, "UPDATE cars SET deleted_at=NULL WHERE car_id=$1 AND deleted_at IS NOT NULL", id)
+	if err != nil {
+		return err
+	}
+
+	return expectAffected(res)
+}
+
+// deletedCondition keeps the live rows of table, or the deleted ones.
+func deletedCondition(table string, deleted bool) string {
+	if deleted {
+		return table + ".deleted_at IS NOT NULL"
+	}
+
+	return table + ".deleted_at IS NULL"
+}
+
+// nullableInt stores an unset id or number, 0, as NULL.
+func nullableInt(value int) any {
+	if value == 0 {
+		return nil
+	}
+
+	return value
+}
+
+// nullableString stores an unset string as NULL.
+func nullableString(value string) any {
+	if value == "" {
+		return nil
+	}
+
+	return value
+}
+
+// features never stores a NULL list.
+func features(list []string) []string {
+	if list == nil {
+		return []string{}
+	}
+
+	return list
+}
+
+// nullableRate stores an unset rate, 0, as NULL.
+func nullableRate(rate float64) any {
+	if rate == 0 {
+		return nil
+	}
+
+	return rate
+}
+
+type postgresOrders struct {
+	db database.Service
+}
+
+const ordersFrom = `
+	FROM orders
+		JOIN cars ON orders.car_id=cars.car_id
+		LEFT JOIN customers ON orders.customer_id=customers.customer_id
+`
+
+const ordersSelect = `
+	SELECT
+		order_id,
+		orders.car_id,
+		cars.car_name,
+		orders.customer_id,
+		customers.name,
+		order_date,
+		pickup_date,
+		dropoff_date,
+		pickup_location,
+		dropoff_location,
+		orders.pickup_location_id,
+		orders.dropoff_location_id,
+		total_price,
+		status,
+		orders.deleted_at
+` + ordersFrom
+
+func scanOrder(row scanner) (*models.OrdersItem, error) {
+	var id, carId, customerId, pickupLocationId, dropoffLocationId sql.NullInt64
+	var orderDate, pickupDate, dropoffDate sql.NullTime
+	var pickupLocation, dropoffLocation, carName, customerName, status sql.NullString
+	var totalPrice sql.NullFloat64
+	var deletedAt sql.NullTime
+	err := row.Scan(
+		&id,
+		&carId,
+		&carName,
+		&customerId,
+		&customerName,
+		&orderDate,
+		&pickupDate,
+		&dropoffDate,
+		&pickupLocation,
+		&dropoffLocation,
+		&pickupLocationId,
+		&dropoffLocationId,
+		&totalPrice,
+		&status,
+		&deletedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	order := &models.OrdersItem{
+		Id:                int(id.Int64),
+		CarId:             int(carId.Int64),
+		CarName:           strings.TrimSpace(carName.String),
+		CustomerId:        int(customerId.Int64),
+		CustomerName:      customerName.String,
+		OrderDate:         orderDate.Time.Format(booking.DateLayout),
+		OrderedAt:         orderDate.Time,
+		PickupDate:        booking.FormatTime(pickupDate.Time),
+		DropoffDate:       booking.FormatTime(dropoffDate.Time),
+		PickupLocation:    strings.TrimSpace(pickupLocation.String),
+		DropoffLocation:   strings.TrimSpace(dropoffLocation.String),
+		PickupLocationId:  int(pickupLocationId.Int64),
+		DropoffLocationId: int(dropoffLocationId.Int64),
+		TotalPrice:        totalPrice.Float64,
+		Status:            status.String,
+	}
+
+	if deletedAt.Valid {
+		order.DeletedAt = booking.FormatTime(deletedAt.Time)
+	}
+
+	return order, nil
+}
+
+func (r *postgresOrders) List(ctx context.Context, q OrderQuery) (*Page[models.OrdersItem], error) {
+	// orders of a deleted car are still listed, the car row stays for them
+	conditions := []string{deletedCondition("orders", q.Deleted)}
+	var params []interface{}
+	count := 0
+
+	if len(q.Search) > 0 {
+		count++
+		conditions = append(conditions, searchCondition(OrdersSpec, q.ListQuery, count, "orders.search_vector", "cars.search_vector", "customers.search_vector"))
+		params = append(params, searchArg(q.ListQuery))
+	}
+
+	if q.CustomerId != 0 {
+		count++
+		conditions = append(conditions, fmt.Sprintf("orders.customer_id=$%d", count))
+		params = append(params, q.CustomerId)
+	}
+
+	filters, args := OrdersSpec.Where(q.Filters, count+1)
+	conditions = append(conditions, filters...)
+	params = append(params, args...)
+
+	return listPage(ctx, r.db, q.ListQuery, OrdersSpec, ordersFields, ordersSelect, ordersFrom, conditions, params, scanOrder)
+}
+
+func (r *postgresOrders) Get(ctx context.Context, id int) (*models.OrdersItem, error) {
+	order, err := scanOrder(r.db.QueryRow(ctx, ordersSelect+" WHERE orders.order_id = $1 AND orders.deleted_at IS NULL", id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+
+	return order, err
+}
+
+func (r *postgresOrders) Delete(ctx context.Context, id int) error {
+	res, err := r.db.Exec(ctx, "UPDATE orders SET deleted_at=NOW() WHERE order_id=$1 AND deleted_at IS NULL", id)
+	if err != nil {
+		return err
+	}
+
+	return expectAffected(res)
+}
+
+// Restore leaves it to orders_no_overlap to refuse an order whose time was
+// booked by another one meanwhile.
+func (r *postgresOrders) Restore(ctx context.Context, id int) error {
+	return database.WithTx(ctx, r.db, nil, func(tx *database.Tx) error {
+		var carDeleted bool
+		err := tx.QueryRow(ctx, "SELECT cars.deleted_at IS NOT NULL FROM orders JOIN cars ON orders.car_id=cars.car_id WHERE orders.order_id=$1 AND orders.deleted_at IS NOT NULL FOR UPDATE OF orders", id).Scan(&carDeleted)
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrNotFound
+		}
+		if err != nil {
+			return err
+		}
+
+		if carDeleted {
+			return ErrCarDeleted
+		}
+
+		_, err = tx.Exec(ctx, "UPDATE orders SET deleted_at=NULL WHERE order_id=$1", id)
+		if database.IsExclusionViolation(err) {
+			return ErrOverlap
+		}
+		return err
+	})
+}
+
+func (r *postgresOrders) Stats(ctx context.Context) (OrderStats, error) {
+	// an overdue car that was picked up is still out
+	occupying := fmt.Sprintf("deleted_at IS NULL AND (status='%[1]s' OR (status='%[2]s' AND pickup_date <= NOW() AND NOW() < dropoff_date))", orderstatus.PickedUp, orderstatus.Reserved)
+	created := "created_at > NOW() - INTERVAL '1 hour'"
+	query := fmt.Sprintf(`
+		SELECT
+			COUNT(*) FILTER (WHERE %[1]s AND status='%[3]s'),
+			COUNT(DISTINCT car_id) FILTER (WHERE %[1]s),
+			COUNT(*) FILTER (WHERE %[2]s)
+		FROM orders
+		WHERE (%[1]s) OR %[2]s
+	`, occupying, created, orderstatus.PickedUp)
+
+	var stats OrderStats
+	err := r.db.QueryRow(ctx, query).Scan(&stats.ActiveRentals, &stats.OccupiedCars, &stats.CreatedLastHour)
+	return stats, err
+}
+
+func (r *postgresOrders) Occupancy(ctx context.Context, carId, excludeOrderId int, dateRange booking.Range) (*Occupancy, error) {
+	var occupancy *Occupancy
+	err := database.WithTx(ctx, r.db, &sql.TxOptions{ReadOnly: true}, func(tx *database.Tx) error {
+		var err error
+		occupancy, err = (&postgresBooking{tx: tx}).Occupancy(ctx, carId, excludeOrderId, dateRange)
+		return err
+	})
+	return occupancy, err
+}
+
+func (r *postgresOrders) Book(ctx context.Context, fn func(b Booking) error) error {
+	return database.WithSerializableTx(ctx, r.db, func(tx *database.Tx) error {
+		return fn(&postgresBooking{tx: tx})
+	})
+}
+
+func (r *postgresOrders) Transition(ctx context.Context, id int, to orderstatus.Status, allow func(order *models.OrdersItem) error) error {
+	return database.WithTx(ctx, r.db, nil, func(tx *database.Tx) error {
+		order, err := scanOrder(tx.QueryRow(ctx, ordersSelect+" WHERE orders.order_id=$1 AND orders.deleted_at IS NULL FOR UPDATE OF orders", id))
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrNotFound
+		}
+		if err != nil {
+			return err
+		}
+
+		err = allow(order)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.Exec(ctx, "UPDATE orders SET status=$1 WHERE order_id=$2", string(to), id)
+		if err != nil || to != orderstatus.Returned {
+			return err
+		}
+
+		_, err = tx.Exec(ctx, "UPDATE cars SET current_location_id=orders.dropoff_location_id FROM orders WHERE orders.order_id=$1 AND cars.car_id=orders.car_id AND orders.dropoff_location_id IS NOT NULL", id)
+		return err
+	})
+}
+
+type postgresBooking struct {
+	tx *database.Tx
+}
+
+const carBlocksSelect = `
+	SELECT
+		block_id,
+		car_id,
+		start_date,
+		end_date,
+		block_type,
+		reason
+	FROM car_blocks
+`
+
+func scanCarBlock(row scanner) (*models.CarBlocksItem, error) {
+	var id, carId sql.NullInt64
+	var startDate, endDate sql.NullTime
+	var blockType, reason sql.NullString
+	err := row.Scan(
+		&id,
+		&carId,
+		&startDate,
+		&endDate,
+		&blockType,
+		&reason,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.CarBlocksItem{
+		Id:        int(id.Int64),
+		CarId:     int(carId.Int64),
+		StartDate: booking.FormatTime(startDate.Time),
+		EndDate:   booking.FormatTime(endDate.Time),
+		Type:      blockType.String,
+		Reason:    reason.String,
+	}, nil
+}
+
+func (b *postgresBooking) Order(ctx context.Context, id int) (*models.OrdersItem, error) {
+	order, err := scanOrder(b.tx.QueryRow(ctx, ordersSelect+" WHERE orders.order_id = $1 AND orders.deleted_at IS NULL", id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+
+	return order, err
+}
+
+func (b *postgresBooking) Car(ctx context.Context, id int) (*models.CarsItem, error) {
+	car, err := scanCar(b.tx.QueryRow(ctx, carsSelect+" WHERE car_id = $1 AND deleted_at IS NULL", id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+
+	return car, err
+}
+
+func (b *postgresBooking) Customer(ctx context.Context, id int) (*models.CustomersItem, error) {
+	customer, err := scanCustomer(b.tx.QueryRow(ctx, customersSelect+" WHERE customer_id = $1", id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+
+	return customer, err
+}
+
+func (b *postgresBooking) Location(ctx context.Context, id int) (*models.LocationsItem, error) {
+	location, err := scanLocation(b.tx.QueryRow(ctx, locationsSelect+" WHERE location_id = $1", id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+
+	return location, err
+}
+
+// Occupancy leaves out the cancelled, returned and no-show orders, they never
+// hold the car.
+func (b *postgresBooking) Occupancy(ctx context.Context, carId, excludeOrderId int, dateRange booking.Range) (*Occupancy, error) {
+	query := fmt.Sprintf(
+		"%s WHERE orders.car_id=$1 AND order_id<>$2 AND orders.deleted_at IS NULL AND %s AND %s ORDER BY pickup_date",
+		ordersSelect, orderstatus.OccupyingCondition("orders.status"), booking.OverlapCondition("pickup_date", "dropoff_date", 3, 4),
+	)
+	rows, err := b.tx.Query(ctx, query, carId, excludeOrderId, dateRange.Start, dateRange.End)
+	if err != nil {
+		return nil, err
+	}
+
+	var occupancy Occupancy
+	occupancy.Orders, err = collect(rows, scanOrder)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err = b.tx.Query(ctx, carBlocksSelect+" WHERE car_id=$1 AND "+booking.OverlapCondition("start_date", "end_date", 2, 3)+" ORDER BY start_date", carId, dateRange.Start, dateRange.End)
+	if err != nil {
+		return nil, err
+	}
+
+	occupancy.Blocks, err = collect(rows, scanCarBlock)
+	if err != nil {
+		return nil, err
+	}
+
+	return &occupancy, nil
+}
+
+func (b *postgresBooking) CarLocation(ctx context.Context, carId, excludeOrderId int, at time.Time) (int, error) {
+	query := fmt.Sprintf(`
+		SELECT dropoff_location_id
+		FROM orders
+		WHERE car_id=$1 AND order_id<>$2 AND deleted_at IS NULL AND %s AND dropoff_date<=$3
+		ORDER BY dropoff_date DESC
+		LIMIT 1
+	`, orderstatus.OccupyingCondition("status"))
+
+	var locationId sql.NullInt64
+	err := b.tx.QueryRow(ctx, query, carId, excludeOrderId, at).Scan(&locationId)
+	if errors.Is(err, sql.ErrNoRows) {
+		err = b.tx.QueryRow(ctx, "SELECT current_location_id FROM cars WHERE car_id=$1 AND deleted_at IS NULL", carId).Scan(&locationId)
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, ErrNotFound
+		}
+	}
+
+	return int(locationId.Int64), err
+}
+
+// Insert fills in the location names for readers of the old columns,
+// ready_date lets the overlap guard keep the turnaround free.
+func (b *postgresBooking) Insert(ctx context.Context, order *models.OrdersItem, dateRange booking.Range, readyDate time.Time) (int, error) {
+	var id int
+	err := b.tx.QueryRow(ctx, "INSERT INTO orders (car_id, customer_id, order_date, pickup_date, dropoff_date, ready_date, pickup_location, dropoff_location, pickup_location_id, dropoff_location_id, total_price) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11) RETURNING order_id",
+		order.CarId, order.CustomerId, order.OrderDate, dateRange.Start, dateRange.End, readyDate, order.PickupLocation, order.DropoffLocation, order.PickupLocationId, order.DropoffLocationId, order.TotalPrice,
+	).Scan(&id)
+	if database.IsExclusionViolation(err) {
+		return 0, ErrOverlap
+	}
+
+	return id, err
+}
+
+func (b *postgresBooking) Update(ctx context.Context, id int, update OrderUpdate) error {
+	var set []string
+	var params []interface{}
+	add := func(column string, value any) {
+		params = append(params, value)
+		set = append(set, fmt.Sprintf("%s=$%d", column, len(params)))
+	}
+
+	if update.CarId != nil {
+		add("car_id", *update.CarId)
+	}
+
+	if update.CustomerId != nil {
+		add("customer_id", *update.CustomerId)
+	}
+
+	if update.OrderDate != nil {
+		add("order_date", *update.OrderDate)
+	}
+
+	if update.PickupDate != nil {
+		add("pickup_date", *update.PickupDate)
+	}
+
+	if update.DropoffDate != nil {
+		add("dropoff_date", *update.DropoffDate)
+	}
+
+	if update.ReadyDate != nil {
+		add("ready_date", *update.ReadyDate)
+	}
+
+	if update.Pickup != nil {
+		add("pickup_location", update.Pickup.Name)
+		add("pickup_location_id", update.Pickup.Id)
+	}
+
+	if update.Dropoff != nil {
+		add("dropoff_location", update.Dropoff.Name)
+		add("dropoff_location_id", update.Dropoff.Id)
+	}
+
+	if update.TotalPrice != nil {
+		add("total_price", *update.TotalPrice)
+	}
+
+	// nothing to change, only tell whether the order is there
+	if len(set) == 0 {
+		_, err := b.Order(ctx, id)
+		return err
+	}
+
+	params = append(params, id)
+	res, err := b.tx.Exec(ctx, fmt.Sprintf("UPDATE orders SET %s WHERE order_id=$%d AND deleted_at IS NULL", strings.Join(set, ","), len(params)), params...)
+	if database.IsExclusionViolation(err) {
+		return ErrOverlap
+	}
+	if err != nil {
+		return err
+	}
+
+	return expectAffected(res)
+}
+
+type postgresCarBlocks struct {
+	db database.Service
+}
+
+func (r *postgresCarBlocks) List(ctx context.Context, carId int) ([]*models.CarBlocksItem, error) {
+	rows, err := r.db.Query(ctx, carBlocksSelect+" WHERE car_id=$1 ORDER BY start_date, block_id", carId)
+	if err != nil {
+		return nil, err
+	}
+
+	return collect(rows, scanCarBlock)
+}
+
+// Create checks the car itself, the foreign key still lets a deleted one
+// through.
+func (r *postgresCarBlocks) Create(ctx context.Context, block *models.CarBlocksItem) (int, error) {
+	var id int
+	err := r.db.QueryRow(ctx, "INSERT INTO car_blocks (car_id, start_date, end_date, block_type, reason) SELECT car_id, $2, $3, $4, $5 FROM cars WHERE car_id=$1 AND deleted_at IS NULL RETURNING block_id", block.CarId, block.StartDate, block.EndDate, block.Type, block.Reason).Scan(&id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, ErrNotFound
+	}
+
+	return id, err
+}
+
+func (r *postgresCarBlocks) Delete(ctx context.Context, carId, id int) error {
+	res, err := r.db.Exec(ctx, "DELETE FROM car_blocks WHERE block_id=$1 AND car_id=$2", id, carId)
+	if err != nil {
+		return err
+	}
+
+	return expectAffected(res)
+}
+
+type postgresCustomers struct {
+	db database.Service
+}
+
+const customersFrom = `
+	FROM customers
+`
+
+const customersSelect = `
+	SELECT
+		customer_id,
+		name,
+		email,
+		phone,
+		licence_number,
+		licence_expiry
+` + customersFrom
+
+func scanCustomer(row scanner) (*models.CustomersItem, error) {
+	var id sql.NullInt64
+	var name, email, phone, licenceNumber sql.NullString
+	var licenceExpiry sql.NullTime
+	err := row.Scan(
+		&id,
+		&name,
+		&email,
+		&phone,
+		&licenceNumber,
+		&licenceExpiry,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.CustomersItem{
+		Id:            int(id.Int64),
+		Name:          name.String,
+		Email:         email.String,
+		Phone:         phone.String,
+		LicenceNumber: licenceNumber.String,
+		LicenceExpiry: licenceExpiry.Time.Format(booking.DateLayout),
+	}, nil
+}
+
+func (r *postgresCustomers) List(ctx context.Context, q ListQuery) (*Page[models.CustomersItem], error) {
+	var conditions []string
+	var params []interface{}
+	count := 0
+
+	if len(q.Search) > 0 {
+		count++
+		if q.SearchBy == "" {
+			conditions = append(conditions, fmt.Sprintf("(LOWER(name) LIKE LOWER($%d) OR LOWER(email) LIKE LOWER($%d))", count, count))
+		} else {
+			conditions = append(conditions, searchCondition(CustomersSpec, q, count, "search_vector"))
+		}
+		params = append(params, searchArg(q))
+	}
+
+	filters, args := CustomersSpec.Where(q.Filters, count+1)
+	conditions = append(conditions, filters...)
+	params = append(params, args...)
+
+	return listPage(ctx, r.db, q, CustomersSpec, customersFields, customersSelect, customersFrom, conditions, params, scanCustomer)
+}
+
+func (r *postgresCustomers) Get(ctx context.Context, id int) (*models.CustomersItem, error) {
+	customer, err := scanCustomer(r.db.QueryRow(ctx, customersSelect+" WHERE customer_id = $1", id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+
+	return customer, err
+}
+
+func (r *postgresCustomers) Create(ctx context.Context, customer *models.CustomersItem) (int, error) {
+	var id int
+	err := r.db.QueryRow(ctx, "INSERT INTO customers (name, email, phone, licence_number, licence_expiry) VALUES ($1, $2, $3, $4, $5) RETURNING customer_id", customer.Name, customer.Email, customer.Phone, customer.LicenceNumber, customer.LicenceExpiry).Scan(&id)
+	return id, err
+}
+
+func (r *postgresCustomers) Update(ctx context.Context, id int, update CustomerUpdate) error {
+	var set []string
+	var params []interface{}
+	for _, field := range []struct {
+		column string
+		value  *string
+	}{
+		{"name", update.Name},
+		{"email", update.Email},
+		{"phone", update.Phone},
+		{"licence_number", update.LicenceNumber},
+		{"licence_expiry", update.LicenceExpiry},
+	} {
+		if field.value != nil {
+			params = append(params, *field.value)
+			set = append(set, fmt.Sprintf("%s=$%d", field.column, len(params)))
+		}
+	}
+
+	// nothing to change, only tell whether the customer is there
+	if len(set) == 0 {
+		_, err := r.Get(ctx, id)
+		return err
+	}
+
+	params = append(params, id)
+	res, err := r.db.Exec(ctx, fmt.Sprintf("UPDATE customers SET %s WHERE customer_id=$%d", strings.Join(set, ","), len(params)), params...)
+	if err != nil {
+		return err
+	}
+
+	return expectAffected(res)
+}
+
+func (r *postgresCustomers) Delete(ctx context.Context, id int) error {
+	res, err := r.db.Exec(ctx, "DELETE FROM customers WHERE customer_id=$1", id)
+	if database.IsForeignKeyViolation(err) {
+		return ErrInUse
+	}
+	if err != nil {
+		return err
+	}
+
+	return expectAffected(res)
+}
+
+type postgresLocations struct {
+	db database.Service
+}
+
+const locationsFrom = `
+	FROM locations
+`
+
+const locationsSelect = `
+	SELECT
+		location_id,
+		name,
+		address,
+		latitude,
+		longitude,
+		timezone,
+		opening_hours
+` + locationsFrom
+
+func scanLocation(row scanner) (*models.LocationsItem, error) {
+	var id sql.NullInt64
+	var name, address, timezone sql.NullString
+	var latitude, longitude sql.NullFloat64
+	var openingHours []byte
+	err := row.Scan(
+		&id,
+		&name,
+		&address,
+		&latitude,
+		&longitude,
+		&timezone,
+		&openingHours,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	item := &models.LocationsItem{
+		Id:           int(id.Int64),
+		Name:         name.String,
+		Address:      address.String,
+		Timezone:     timezone.String,
+		OpeningHours: map[string]string{},
+	}
+
+	if latitude.Valid {
+		item.Latitude = &latitude.Float64
+	}
+
+	if longitude.Valid {
+		item.Longitude = &longitude.Float64
+	}
+
+	err = json.Unmarshal(openingHours, &item.OpeningHours)
+	if err != nil {
+		return nil, err
+	}
+
+	return item, nil
+}
+
+func (r *postgresLocations) List(ctx context.Context, q ListQuery) (*Page[models.LocationsItem], error) {
+	var conditions []string
+	var params []interface{}
+	count := 0
+
+	if len(q.Search) > 0 {
+		count++
+		params = append(params, "%"+utils.Sanitize(q.Search)+"%")
+		if q.SearchBy == queryspec.SearchAny {
+			// too few rows to be worth a full-text index
+			conditions = append(conditions, fmt.Sprintf("(LOWER(name) LIKE LOWER($%d) OR LOWER(address) LIKE LOWER($%d))", count, count))
+		} else {
+			conditions = append(conditions, searchCondition(LocationsSpec, q, count))
+		}
+	}
+
+	filters, args := LocationsSpec.Where(q.Filters, count+1)
+	conditions = append(conditions, filters...)
+	params = append(params, args...)
+
+	return listPage(ctx, r.db, q, LocationsSpec, locationsFields, locationsSelect, locationsFrom, conditions, params, scanLocation)
+}
+
+func (r *postgresLocations) Get(ctx context.Context, id int) (*models.LocationsItem, error) {
+	location, err := scanLocation(r.db.QueryRow(ctx, locationsSelect+" WHERE location_id = $1", id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+
+	return location, err
+}
+
+func (r *postgresLocations) GetByName(ctx context.Context, name string) (*models.LocationsItem, error) {
+	location, err := scanLocation(r.db.QueryRow(ctx, locationsSelect+" WHERE LOWER(name) = LOWER($1)", strings.TrimSpace(name)))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+
+	return location, err
+}
+
+func (r *postgresLocations) Create(ctx context.Context, location *models.LocationsItem) (int, error) {
+	openingHours, err := json.Marshal(location.OpeningHours)
+	if err != nil {
+		return 0, err
+	}
+
+	var id int
+	err = r.db.QueryRow(ctx, "INSERT INTO locations (name, address, latitude, longitude, timezone, opening_hours) VALUES ($1, $2, $3, $4, $5, $6) RETURNING location_id", location.Name, location.Address, location.Latitude, location.Longitude, location.Timezone, string(openingHours)).Scan(&id)
+	return id, err
+}
+
+func (r *postgresLocations) Update(ctx context.Context, id int, update LocationUpdate) error {
+	var set []string
+	var params []interface{}
+	for _, field := range []struct {
+		column string
+		value  *string
+	}{
+		{"name", update.Name},
+		{"address", update.Address},
+		{"timezone", update.Timezone},
+	} {
+		if field.value != nil {
+			params = append(params, *field.value)
+			set = append(set, fmt.Sprintf("%s=$%d", field.column, len(params)))
+		}
+	}
+
+	if update.Latitude != nil {
+		params = append(params, *update.Latitude)
+		set = append(set, fmt.Sprintf("latitude=$%d", len(params)))
+	}
+
+	if update.Longitude != nil {
+		params = append(params, *update.Longitude)
+		set = append(set, fmt.Sprintf("longitude=$%d", len(params)))
+	}
+
+	if update.OpeningHours != nil {
+		openingHours, err := json.Marshal(update.OpeningHours)
+		if err != nil {
+			return err
+		}
+
+		params = append(params, string(openingHours))
+		set = append(set, fmt.Sprintf("opening_hours=$%d", len(params)))
+	}
+
+	// nothing to change, only tell whether the branch is there
+	if len(set) == 0 {
+		_, err := r.Get(ctx, id)
+		return err
+	}
+
+	params = append(params, id)
+	res, err := r.db.Exec(ctx, fmt.Sprintf("UPDATE locations SET %s WHERE location_id=$%d", strings.Join(set, ","), len(params)), params...)
+	if err != nil {
+		return err
+	}
+
+	return expectAffected(res)
+}
+
+func (r *postgresLocations) Delete(ctx context.Context, id int) error {
+	res, err := r.db.Exec(ctx, "DELETE FROM locations WHERE location_id=$1", id)
+	if database.IsForeignKeyViolation(err) {
+		return ErrInUse
+	}
+	if err != nil {
+		return err
+	}
+
+	return expectAffected(res)
+}
+
+// listPage reads the page q asks for out of the rows matching conditions,
+// whose placeholders are bound to params. selectQuery ends with the from
+// clause, which the count reuses.
+func listPage[T any](ctx context.Context, db database.Service, q ListQuery, spec *queryspec.Spec, fields map[string]func(*T) any, selectQuery, from string, conditions []string, params []interface{}, scan func(scanner) (*T, error)) (*Page[T], error) {
+	cmdQuery := ""
+	if len(conditions) > 0 {
+		cmdQuery = fmt.Sprintf("%s WHERE %s", cmdQuery, strings.Join(conditions, " AND "))
+	}
+
+	var total *int
+	if q.CountTotal {
+		// count all of search result, filters may name joined columns so the
+		// count uses the same from clause
+		total = new(int)
+		err := db.QueryRow(ctx, fmt.Sprintf("SELECT COUNT(*) AS total %s %s", from, cmdQuery), params...).Scan(total)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	count := len(params)
+	sorts := spec.WithTiebreak(q.Sort)
+	if q.Keyset {
+		if q.Cursor != nil {
+			seek, args := spec.Seek(q.Sort, q.Cursor, count+1)
+			conditions = append(conditions, seek)
+			params = append(params, args...)
+			count += len(args)
+
+			// a backward page is read walking away from the cursor
+			if q.Cursor.Backward {
+				sorts = queryspec.Reverse(sorts)
+			}
+		}
+
+		cmdQuery = ""
+		if len(conditions) > 0 {
+			cmdQuery = fmt.Sprintf("%s WHERE %s", cmdQuery, strings.Join(conditions, " AND "))
+		}
+	}
+
+	cmdQuery = fmt.Sprintf("%s ORDER BY %s", cmdQuery, spec.OrderBy(sorts))
+
+	// keyset pages read one row more to know whether another page follows
+	count++
+	cmdQuery = fmt.Sprintf("%s LIMIT $%d ", cmdQuery, count)
+	if q.Keyset {
+		params = append(params, q.Limit+1)
+	} else {
+		params = append(params, q.Limit)
+
+		count++
+		cmdQuery = fmt.Sprintf("%s OFFSET $%d ", cmdQuery, count)
+		params = append(params, q.Offset)
+	}
+
+	rows, err := db.Query(ctx, fmt.Sprintf("%s %s", selectQuery, cmdQuery), params...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	items := []*T{}
+	for rows.Next() {
+		item, err := scan(rows)
+		if err != nil {
+			return nil, err
+		}
+
+		items = append(items, item)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if !q.Keyset {
+		return &Page[T]{Items: items, Total: total}, nil
+	}
+
+	page := keysetPage(items, q, spec, fields)
+	page.Total = total
+	return page, nil
+}
+
+// searchCondition matches placeholder $arg against the column q.SearchBy
+// picks, or against every full-text document when searching any column.
+func searchCondition(spec *queryspec.Spec, q ListQuery, arg int, documents ...string) string {
+	if q.SearchBy == queryspec.SearchAny {
+		var matches []string
+		for _, document := range documents {
+			matches = append(matches, fmt.Sprintf("%s @@ to_tsquery('simple', $%d)", document, arg))
+		}
+		return "(" + strings.Join(matches, " OR ") + ")"
+	}
+
+	return fmt.Sprintf("LOWER(%s) LIKE LOWER($%d)", spec.Search[q.searchBy(spec)], arg)
+}
+
+// collect scans every row of rows with scan and closes them.
+func collect[T any](rows *sql.Rows, scan func(scanner) (*T, error)) ([]*T, error) {
+	defer rows.Close()
+
+	items := []*T{}
+	for rows.Next() {
+		item, err := scan(rows)
+		if err != nil {
+			return nil, err
+		}
+
+		items = append(items, item)
+	}
+
+	return items, rows.Err()
+}
+
+func searchArg(q ListQuery) string {
+	if q.SearchBy == queryspec.SearchAny {
+		return queryspec.TSQuery(q.Search)
+	}
+
+	return "%" + utils.Sanitize(q.Search) + "%"
+}
+
+func expectAffected(res sql.Result) error {
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if affected == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
+type postgresUsers struct {
+	db database.Service
+}
+
+func (r *postgresUsers) GetByEmail(ctx context.Context, email string) (*models.UsersItem, error) {
+	var user models.UsersItem
+	var customerId sql.NullInt64
+	err := r.db.QueryRow(ctx, "SELECT user_id, email, password_hash, role, customer_id FROM users WHERE email=$1", email).Scan(&user.Id, &user.Email, &user.PasswordHash, &user.Role, &customerId)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	user.CustomerId = int(customerId.Int64)
+	return &user, nil
+}
+
+func (r *postgresUsers) Create(ctx context.Context, user *models.UsersItem) (int, error) {
+	customerId := sql.NullInt64{Int64: int64(user.CustomerId), Valid: user.CustomerId != 0}
+
+	var id int
+	err := r.db.QueryRow(ctx, "INSERT INTO users (email, password_hash, role, customer_id) VALUES ($1, $2, $3, $4) RETURNING user_id", user.Email, user.PasswordHash, user.Role, customerId).Scan(&id)
+	switch {
+	case database.IsUniqueViolation(err):
+		return 0, ErrDuplicate
+	case database.IsForeignKeyViolation(err):
+		return 0, ErrNotFound
+	}
+
+	return id, err
+}
+
+func (r *postgresUsers) Count(ctx context.Context) (int, error) {
+	var users int
+	err := r.db.QueryRow(ctx, "SELECT COUNT(*) FROM users").Scan(&users)
+	return users, err
+}
@@ -0,0 +1,480 @@
+package repository
+
+import (
+	"api/internal/booking"
+	"api/internal/models"
+	"api/internal/orderstatus"
+	"api/internal/queryspec"
+	"context"
+	"errors"
+	"slices"
+	"time"
+)
+
+// ErrNotFound is returned when the requested row does not exist.
+var ErrNotFound = errors.New("not-found")
+
+// ErrInUse is returned when deleting a car that orders still hold, now or
+// later on, a customer that orders were booked for or a branch that cars or
+// orders refer to.
+var ErrInUse = errors.New("in-use")
+
+// ErrCarDeleted is returned when restoring an order whose car is deleted.
+var ErrCarDeleted = errors.New("car-deleted")
+
+// ErrDuplicate is returned when creating a user whose email is registered
+// already.
+var ErrDuplicate = errors.New("duplicate")
+
+// ErrOverlap is returned when an order would hold its car between the
+// pickup and the ready date of another order still holding it.
+var ErrOverlap = errors.New("overlap")
+
+// ListQuery is the search, filtering, ordering and paging shared by every
+// list. Sort and Filters only name fields of the resource's spec.
+type ListQuery struct {
+	Search string
+	// SearchBy is a key of the spec's Search or queryspec.SearchAny, empty
+	// means the spec's DefaultSearch.
+	SearchBy string
+	Filters  []queryspec.Filter
+	Sort     []queryspec.Sort
+	Limit    int
+	// Offset pages by position, unless Keyset is set.
+	Offset int
+	// Keyset pages from Cursor instead, from the start when Cursor is nil.
+	Keyset bool
+	Cursor *queryspec.Cursor
+	// CountTotal asks for the number of rows across all pages.
+	CountTotal bool
+	// Deleted lists the soft-deleted rows instead of the live ones.
+	Deleted bool
+}
+
+// Page is one page of a list. Total is only set when the query asked for it,
+// the cursors only in keyset paging and when there is a page that way.
+type Page[T any] struct {
+	Items      []*T
+	Total      *int
+	NextCursor string
+	PrevCursor string
+}
+
+func (q ListQuery) searchBy(spec *queryspec.Spec) string {
+	if q.SearchBy == "" {
+		return spec.DefaultSearch
+	}
+
+	return q.SearchBy
+}
+
+// CarsSpec lists the car fields that can be sorted and filtered on.
+var CarsSpec = &queryspec.Spec{
+	Fields: map[string]queryspec.Field{
+		"id":                  {Column: "car_id", Type: queryspec.Integer},
+		"car_name":            {Column: "car_name", Type: queryspec.String},
+		"day_rate":            {Column: "day_rate", Type: queryspec.Number},
+		"month_rate":          {Column: "month_rate", Type: queryspec.Number},
+		"hour_rate":           {Column: "hour_rate", Type: queryspec.Number, Nullable: true},
+		"home_location_id":    {Column: "home_location_id", Type: queryspec.Integer, Nullable: true},
+		"current_location_id": {Column: "current_location_id", Type: queryspec.Integer, Nullable: true},
+		"make":                {Column: "make", Type: queryspec.String},
+		"model":               {Column: "model", Type: queryspec.String},
+		"year":                {Column: "year", Type: queryspec.Integer, Nullable: true},
+		"category":            {Column: "category", Type: queryspec.String},
+		"seats":               {Column: "seats", Type: queryspec.Integer, Nullable: true},
+		"transmission":        {Column: "transmission", Type: queryspec.String},
+		"fuel_type":           {Column: "fuel_type", Type: queryspec.String},
+		"plate_number":        {Column: "plate_number", Type: queryspec.String, Nullable: true},
+		"features":            {Column: "features", Type: queryspec.List},
+	},
+	Tiebreak: "id",
+	Search: map[string]string{
+		"car_name":     "car_name",
+		"make":         "make",
+		"model":        "model",
+		"plate_number": "plate_number",
+	},
+	DefaultSearch: "car_name",
+	Facets:        []string{"make", "year", "category", "seats", "transmission", "fuel_type", "features"},
+}
+
+// OrdersSpec lists the order fields that can be sorted and filtered on.
+var OrdersSpec = &queryspec.Spec{
+	Fields: map[string]queryspec.Field{
+		"id":                  {Column: "orders.order_id", Type: queryspec.Integer},
+		"car_id":              {Column: "orders.car_id", Type: queryspec.Integer},
+		"car_name":            {Column: "cars.car_name", Type: queryspec.String},
+		"customer_id":         {Column: "orders.customer_id", Type: queryspec.Integer, Nullable: true},
+		"customer_name":       {Column: "customers.name", Type: queryspec.String, Nullable: true},
+		"order_date":          {Column: "orders.order_date", Type: queryspec.Date},
+		"pickup_date":         {Column: "orders.pickup_date", Type: queryspec.Date},
+		"dropoff_date":        {Column: "orders.dropoff_date", Type: queryspec.Date},
+		"pickup_location":     {Column: "orders.pickup_location", Type: queryspec.String},
+		"dropoff_location":    {Column: "orders.dropoff_location", Type: queryspec.String},
+		"pickup_location_id":  {Column: "orders.pickup_location_id", Type: queryspec.Integer, Nullable: true},
+		"dropoff_location_id": {Column: "orders.dropoff_location_id", Type: queryspec.Integer, Nullable: true},
+		"total_price":         {Column: "orders.total_price", Type: queryspec.Number, Nullable: true},
+		"status":              {Column: "orders.status", Type: queryspec.String},
+	},
+	Tiebreak: "id",
+	Search: map[string]string{
+		"car_name":         "cars.car_name",
+		"pickup_location":  "orders.pickup_location",
+		"dropoff_location": "orders.dropoff_location",
+		"customer":         "customers.name",
+	},
+	DefaultSearch: "pickup_location",
+}
+
+// CustomersSpec lists the customer fields that can be sorted and filtered on.
+var CustomersSpec = &queryspec.Spec{
+	Fields: map[string]queryspec.Field{
+		"id":             {Column: "customer_id", Type: queryspec.Integer},
+		"name":           {Column: "name", Type: queryspec.String},
+		"email":          {Column: "email", Type: queryspec.String},
+		"phone":          {Column: "phone", Type: queryspec.String},
+		"licence_number": {Column: "licence_number", Type: queryspec.String},
+		"licence_expiry": {Column: "licence_expiry", Type: queryspec.Date},
+	},
+	Tiebreak: "id",
+	// without search_by both name and email are searched
+	Search: map[string]string{
+		"name":  "name",
+		"email": "email",
+	},
+}
+
+// LocationsSpec lists the location fields that can be sorted and filtered on.
+var LocationsSpec = &queryspec.Spec{
+	Fields: map[string]queryspec.Field{
+		"id":       {Column: "location_id", Type: queryspec.Integer},
+		"name":     {Column: "name", Type: queryspec.String},
+		"timezone": {Column: "timezone", Type: queryspec.String},
+	},
+	Tiebreak: "id",
+	Search: map[string]string{
+		"name":    "name",
+		"address": "address",
+	},
+	DefaultSearch: "name",
+}
+
+type CarQuery struct {
+	ListQuery
+	// AvailableIn, when set, leaves out cars that have an order still holding
+	// them, or are blocked, during that range.
+	AvailableIn *booking.Range
+}
+
+// CarUpdate holds the fields to change, nil ones keep their stored value.
+type CarUpdate struct {
+	CarName   *string
+	DayRate   *float64
+	MonthRate *float64
+	// HourRate set to 0 stops hourly rentals of the car
+	HourRate          *float64
+	Image             *string
+	HomeLocationId    *int
+	CurrentLocationId *int
+	Make              *string
+	Model             *string
+	Year              *int
+	Category          *string
+	Seats             *int
+	Transmission      *string
+	FuelType          *string
+	PlateNumber       *string
+	// Features replaces the whole list
+	Features *[]string
+}
+
+type CarRepository interface {
+	List(ctx context.Context, q CarQuery) (*Page[models.CarsItem], error)
+	Get(ctx context.Context, id int) (*models.CarsItem, error)
+	// Facets counts the cars of each value of the CarsSpec facets.
+	Facets(ctx context.Context, q CarQuery) (map[string]map[string]int, error)
+	Create(ctx context.Context, car *models.CarsItem) (int, error)
+	Update(ctx context.Context, id int, update CarUpdate) error
+	// Delete soft-deletes the car, ErrInUse while orders still hold it.
+	Delete(ctx context.Context, id int) error
+	// Restore brings a deleted car back.
+	Restore(ctx context.Context, id int) error
+}
+
+type OrderQuery struct {
+	ListQuery
+	// CustomerId, when not zero, keeps only the orders of that customer.
+	CustomerId int
+}
+
+// OrderRepository covers reading, booking and changing orders. What makes a
+// booking acceptable is up to the caller, which checks it through Booking.
+type OrderRepository interface {
+	List(ctx context.Context, q OrderQuery) (*Page[models.OrdersItem], error)
+	Get(ctx context.Context, id int) (*models.OrdersItem, error)
+	// Delete soft-deletes the order, which then no longer holds its car.
+	Delete(ctx context.Context, id int) error
+	// Restore brings a deleted order back, ErrCarDeleted while its car is
+	// deleted.
+	Restore(ctx context.Context, id int) error
+	// Stats counts what the orders hold right now.
+	Stats(ctx context.Context) (OrderStats, error)
+	// Occupancy reads what holds carId during dateRange, leaving out the
+	// order excludeOrderId, all at one point in time.
+	Occupancy(ctx context.Context, carId, excludeOrderId int, dateRange booking.Range) (*Occupancy, error)
+	// Book runs fn in a serializable transaction, committed when fn returns
+	// nil and rolled back otherwise. Two bookings of a car never both see it
+	// free. fn runs again when the transaction loses against a concurrent
+	// one, it must be safe to repeat.
+	Book(ctx context.Context, fn func(b Booking) error) error
+	// Transition moves the order to status to once allow accepts it as
+	// stored, the order stays locked meanwhile. A returned car stands at the
+	// branch it was dropped off at from then on.
+	Transition(ctx context.Context, id int, to orderstatus.Status, allow func(order *models.OrdersItem) error) error
+}
+
+// CarBlockRepository covers the periods cars are off the road. Bookings see
+// them through Occupancy.
+type CarBlockRepository interface {
+	// List lists the blocks of carId by their start.
+	List(ctx context.Context, carId int) ([]*models.CarBlocksItem, error)
+	// Create blocks the car for block's dates, ErrNotFound when the car is
+	// not there or deleted.
+	Create(ctx context.Context, block *models.CarBlocksItem) (int, error)
+	// Delete removes the block id of carId.
+	Delete(ctx context.Context, carId, id int) error
+}
+
+// Booking reads and writes inside the transaction of OrderRepository.Book.
+type Booking interface {
+	// Order reads a live order, ErrNotFound when there is none.
+	Order(ctx context.Context, id int) (*models.OrdersItem, error)
+	// Car reads a live car, ErrNotFound when there is none.
+	Car(ctx context.Context, id int) (*models.CarsItem, error)
+	Customer(ctx context.Context, id int) (*models.CustomersItem, error)
+	Location(ctx context.Context, id int) (*models.LocationsItem, error)
+	Occupancy(ctx context.Context, carId, excludeOrderId int, dateRange booking.Range) (*Occupancy, error)
+	// CarLocation is the branch carId stands at by at: the one the last order
+	// still holding it, but excludeOrderId, drops it off at, or where it is
+	// parked now when there is no such order. 0 when nobody recorded it.
+	CarLocation(ctx context.Context, carId, excludeOrderId int, at time.Time) (int, error)
+	// Insert stores order as reserved over dateRange and returns its id. The
+	// car is kept free until readyDate, ErrOverlap when it is not free.
+	Insert(ctx context.Context, order *models.OrdersItem, dateRange booking.Range, readyDate time.Time) (int, error)
+	// Update changes a live order, ErrNotFound when there is none and
+	// ErrOverlap when the car is not free for it.
+	Update(ctx context.Context, id int, update OrderUpdate) error
+}
+
+// Occupancy is what holds a car during a period.
+type Occupancy struct {
+	// Orders still holding the car, by their pickup
+	Orders []*models.OrdersItem
+	// Blocks taking the car off the road, by their start
+	Blocks []*models.CarBlocksItem
+}
+
+// OrderUpdate holds the fields to change, nil ones keep their stored value.
+type OrderUpdate struct {
+	CarId       *int
+	CustomerId  *int
+	OrderDate   *string
+	PickupDate  *time.Time
+	DropoffDate *time.Time
+	// ReadyDate is when the car is free again after the dropoff
+	ReadyDate *time.Time
+	// Pickup and Dropoff are the branches, their names also go into the
+	// older location columns
+	Pickup     *models.LocationsItem
+	Dropoff    *models.LocationsItem
+	TotalPrice *float64
+}
+
+// CustomerUpdate holds the fields to change, nil ones keep their stored
+// value.
+type CustomerUpdate struct {
+	Name          *string
+	Email         *string
+	Phone         *string
+	LicenceNumber *string
+	LicenceExpiry *string
+}
+
+type CustomerRepository interface {
+	List(ctx context.Context, q ListQuery) (*Page[models.CustomersItem], error)
+	Get(ctx context.Context, id int) (*models.CustomersItem, error)
+	Create(ctx context.Context, customer *models.CustomersItem) (int, error)
+	Update(ctx context.Context, id int, update CustomerUpdate) error
+	// Delete removes the customer, ErrInUse once orders were booked for them.
+	Delete(ctx context.Context, id int) error
+}
+
+// LocationUpdate holds the fields to change, nil ones keep their stored
+// value.
+type LocationUpdate struct {
+	Name      *string
+	Address   *string
+	Latitude  *float64
+	Longitude *float64
+	Timezone  *string
+	// OpeningHours replaces the whole week
+	OpeningHours map[string]string
+}
+
+type LocationRepository interface {
+	List(ctx context.Context, q ListQuery) (*Page[models.LocationsItem], error)
+	Get(ctx context.Context, id int) (*models.LocationsItem, error)
+	// GetByName finds the branch by its name, whatever the case.
+	GetByName(ctx context.Context, name string) (*models.LocationsItem, error)
+	Create(ctx context.Context, location *models.LocationsItem) (int, error)
+	Update(ctx context.Context, id int, update LocationUpdate) error
+	// Delete removes the branch, ErrInUse while cars or orders refer to it.
+	Delete(ctx context.Context, id int) error
+}
+
+// UserRepository covers the accounts that log in, by their email as stored:
+// lower case.
+type UserRepository interface {
+	GetByEmail(ctx context.Context, email string) (*models.UsersItem, error)
+	// Create stores user and returns its id, ErrDuplicate when the email is
+	// taken and ErrNotFound when its customer is not there.
+	Create(ctx context.Context, user *models.UsersItem) (int, error)
+	// Count counts every account.
+	Count(ctx context.Context) (int, error)
+}
+
+// OrderStats is the state of the fleet as the orders tell it.
+type OrderStats struct {
+	// ActiveRentals are the orders picked up and not returned yet
+	ActiveRentals int
+	// OccupiedCars are the cars out on a rental now, or reserved for now
+	OccupiedCars int
+	// CreatedLastHour are the orders booked within the last hour, deleted
+	// since or not
+	CreatedLastHour int
+}
+
+// carsFields reads the CarsSpec fields off a car, typed like filter values.
+// Memory filters and sorts with them, both implementations build cursors.
+var carsFields = map[string]func(car *models.CarsItem) any{
+	"id":                  func(car *models.CarsItem) any { return car.Id },
+	"car_name":            func(car *models.CarsItem) any { return car.CarName },
+	"day_rate":            func(car *models.CarsItem) any { return car.DayRate },
+	"month_rate":          func(car *models.CarsItem) any { return car.MonthRate },
+	"hour_rate":           func(car *models.CarsItem) any { return car.HourRate },
+	"home_location_id":    func(car *models.CarsItem) any { return car.HomeLocationId },
+	"current_location_id": func(car *models.CarsItem) any { return car.CurrentLocationId },
+	"make":                func(car *models.CarsItem) any { return car.Make },
+	"model":               func(car *models.CarsItem) any { return car.Model },
+	"year":                func(car *models.CarsItem) any { return car.Year },
+	"category":            func(car *models.CarsItem) any { return car.Category },
+	"seats":               func(car *models.CarsItem) any { return car.Seats },
+	"transmission":        func(car *models.CarsItem) any { return car.Transmission },
+	"fuel_type":           func(car *models.CarsItem) any { return car.FuelType },
+	"plate_number":        func(car *models.CarsItem) any { return car.PlateNumber },
+	"features":            func(car *models.CarsItem) any { return car.Features },
+}
+
+// ordersFields reads the OrdersSpec fields off an order, typed like filter
+// values.
+var ordersFields = map[string]func(order *models.OrdersItem) any{
+	"id":                  func(order *models.OrdersItem) any { return order.Id },
+	"car_id":              func(order *models.OrdersItem) any { return order.CarId },
+	"car_name":            func(order *models.OrdersItem) any { return order.CarName },
+	"customer_id":         func(order *models.OrdersItem) any { return order.CustomerId },
+	"customer_name":       func(order *models.OrdersItem) any { return order.CustomerName },
+	"order_date":          orderedAt,
+	"pickup_date":         func(order *models.OrdersItem) any { return parseDate(order.PickupDate) },
+	"dropoff_date":        func(order *models.OrdersItem) any { return parseDate(order.DropoffDate) },
+	"pickup_location":     func(order *models.OrdersItem) any { return order.PickupLocation },
+	"dropoff_location":    func(order *models.OrdersItem) any { return order.DropoffLocation },
+	"pickup_location_id":  func(order *models.OrdersItem) any { return order.PickupLocationId },
+	"dropoff_location_id": func(order *models.OrdersItem) any { return order.DropoffLocationId },
+	"total_price":         func(order *models.OrdersItem) any { return order.TotalPrice },
+	"status":              func(order *models.OrdersItem) any { return order.Status },
+}
+
+// customersFields reads the CustomersSpec fields off a customer, typed like
+// filter values.
+var customersFields = map[string]func(customer *models.CustomersItem) any{
+	"id":             func(customer *models.CustomersItem) any { return customer.Id },
+	"name":           func(customer *models.CustomersItem) any { return customer.Name },
+	"email":          func(customer *models.CustomersItem) any { return customer.Email },
+	"phone":          func(customer *models.CustomersItem) any { return customer.Phone },
+	"licence_number": func(customer *models.CustomersItem) any { return customer.LicenceNumber },
+	"licence_expiry": func(customer *models.CustomersItem) any { return parseDate(customer.LicenceExpiry) },
+}
+
+// locationsFields reads the LocationsSpec fields off a location, typed like
+// filter values.
+var locationsFields = map[string]func(location *models.LocationsItem) any{
+	"id":       func(location *models.LocationsItem) any { return location.Id },
+	"name":     func(location *models.LocationsItem) any { return location.Name },
+	"timezone": func(location *models.LocationsItem) any { return location.Timezone },
+}
+
+// withoutField drops the filters on field.
+func withoutField(filters []queryspec.Filter, field string) []queryspec.Filter {
+	var kept []queryspec.Filter
+	for _, filter := range filters {
+		if filter.Field != field {
+			kept = append(kept, filter)
+		}
+	}
+
+	return kept
+}
+
+// orderedAt is the time an order was placed, its day for orders that only
+// carry the displayed date.
+func orderedAt(order *models.OrdersItem) any {
+	if !order.OrderedAt.IsZero() {
+		return order.OrderedAt
+	}
+
+	return parseDate(order.OrderDate)
+}
+
+func parseDate(value string) time.Time {
+	t, _ := booking.ParseTime(value)
+	return t
+}
+
+// keysetPage turns the up to Limit+1 rows read for a keyset page, in the
+// direction of the cursor, into the page and the cursors around it.
+func keysetPage[T any](rows []*T, q ListQuery, spec *queryspec.Spec, fields map[string]func(*T) any) *Page[T] {
+	backward := q.Cursor != nil && q.Cursor.Backward
+	more := len(rows) > q.Limit
+	if more {
+		rows = rows[:q.Limit]
+	}
+	if backward {
+		slices.Reverse(rows)
+	}
+
+	page := &Page[T]{Items: rows}
+	if len(rows) == 0 {
+		return page
+	}
+
+	keys := spec.WithTiebreak(q.Sort)
+	cursorAt := func(row *T, backward bool) string {
+		values := make([]any, len(keys))
+		for i, key := range keys {
+			values[i] = fields[key.Field](row)
+		}
+		return spec.EncodeCursor(q.Sort, values, backward)
+	}
+
+	// coming back from a later page there is always a next one, and there is
+	// a previous one whenever this is not the first page
+	if more || backward {
+		page.NextCursor = cursorAt(rows[len(rows)-1], false)
+	}
+	if (more && backward) || (q.Cursor != nil && !backward) {
+		page.PrevCursor = cursorAt(rows[0], true)
+	}
+
+	return page
+}
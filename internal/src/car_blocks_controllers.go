@@ -0,0 +1,177 @@
+package src
+
+import (
+	"api/internal/apperr"
+	"api/internal/booking"
+	"api/internal/logging"
+	"api/internal/models"
+	"api/internal/repository"
+	"errors"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// blockTypes are the reasons a car can be taken off the road for.
+var blockTypes = []string{"maintenance", "inspection", "repair"}
+
+func parseCarId(id string) (int, error) {
+	if id == "" {
+		return 0, apperr.Validation("missing-cars-id", "id")
+	}
+
+	carId, err := strconv.Atoi(id)
+	if err != nil {
+		return 0, apperr.Validation("wrong-cars-id-type", "id")
+	}
+
+	return carId, nil
+}
+
+func (s *Server) listCarBlocksController(c *gin.Context, id string) (*models.CarBlocksResponseList, error) {
+	carId, err := parseCarId(id)
+	if err != nil {
+		logError(c, err)
+		return nil, err
+	}
+
+	_, err = s.cars.Get(c, carId)
+	if errors.Is(err, repository.ErrNotFound) {
+		errorMsg := "car-not-found"
+		logging.From(c).Info(errorMsg)
+		return nil, apperr.NotFound(errorMsg)
+	}
+	if err != nil {
+		logError(c, err)
+		return nil, err
+	}
+
+	blocks, err := s.blocks.List(c, carId)
+	if err != nil {
+		logError(c, err)
+		return nil, err
+	}
+
+	return &models.CarBlocksResponseList{
+		Items:   blocks,
+		Message: "success",
+	}, nil
+}
+
+func (s *Server) createCarBlocksController(c *gin.Context, req *models.CarBlocksRequestCreate) (*models.CarBlocksResponseCreate, error) {
+	carId, err := parseCarId(req.CarId)
+	if err != nil {
+		logError(c, err)
+		return nil, err
+	}
+
+	errMsg := ""
+	if req.StartDate == "" {
+		errMsg = "missing-start-date"
+		logging.From(c).Info(errMsg)
+		return nil, apperr.Validation(errMsg, "start_date")
+	}
+
+	if req.EndDate == "" {
+		errMsg = "missing-end-date"
+		logging.From(c).Info(errMsg)
+		return nil, apperr.Validation(errMsg, "end_date")
+	}
+
+	start, err := booking.ParseTime(req.StartDate)
+	if err != nil {
+		errMsg = "failed-parsing-start-date"
+		logError(c, err)
+		return nil, apperr.Validation(errMsg, "start_date")
+	}
+
+	end, err := booking.ParseTime(req.EndDate)
+	if err != nil {
+		errMsg = "failed-parsing-end-date"
+		logError(c, err)
+		return nil, apperr.Validation(errMsg, "end_date")
+	}
+
+	dateRange, err := booking.NewRange(start, end)
+	if err != nil {
+		errMsg = "invalid-date-range"
+		logging.From(c).Info(errMsg)
+		return nil, apperr.Validation(errMsg, "end_date")
+	}
+
+	req.Type = strings.ToLower(strings.TrimSpace(req.Type))
+	if req.Type == "" {
+		req.Type = "maintenance"
+	}
+
+	if !slices.Contains(blockTypes, req.Type) {
+		errMsg = "invalid-block-type"
+		logging.From(c).Info(errMsg)
+		return nil, apperr.Validation(errMsg, "type")
+	}
+
+	// the block goes in even when orders hold the car then, staff get them
+	// back to move them to another car or cancel them
+	blockId, err := s.blocks.Create(c, &models.CarBlocksItem{
+		CarId:     carId,
+		StartDate: booking.FormatTime(dateRange.Start),
+		EndDate:   booking.FormatTime(dateRange.End),
+		Type:      req.Type,
+		Reason:    strings.TrimSpace(req.Reason),
+	})
+	if errors.Is(err, repository.ErrNotFound) {
+		errMsg = "car-not-found"
+		logging.From(c).Info(errMsg)
+		return nil, apperr.NotFound(errMsg)
+	}
+	if err != nil {
+		logError(c, err)
+		return nil, err
+	}
+
+	occupancy, err := s.orders.Occupancy(c, carId, 0, dateRange.Pad(s.turnaround))
+	if err != nil {
+		logError(c, err)
+		return nil, err
+	}
+
+	return &models.CarBlocksResponseCreate{
+		Id:          blockId,
+		Overlapping: occupancy.Orders,
+		Message:     "success",
+	}, nil
+}
+
+func (s *Server) deleteCarBlocksController(c *gin.Context, id, blockId string) (*models.ResponseGeneral, error) {
+	carId, err := parseCarId(id)
+	if err != nil {
+		logError(c, err)
+		return nil, err
+	}
+
+	errorMsg := ""
+	blockIdNum, err := strconv.Atoi(blockId)
+	if err != nil {
+		errorMsg = "wrong-block-id-type"
+		logging.From(c).Info(errorMsg)
+		return nil, apperr.Validation(errorMsg, "block_id")
+	}
+
+	err = s.blocks.Delete(c, carId, blockIdNum)
+	if errors.Is(err, repository.ErrNotFound) {
+		errorMsg = "block-not-found"
+		logging.From(c).Info(errorMsg)
+		return nil, apperr.NotFound(errorMsg)
+	}
+	if err != nil {
+		logError(c, err)
+		return nil, err
+	}
+
+	return &models.ResponseGeneral{
+		Id:      blockIdNum,
+		Message: "success",
+	}, nil
+}
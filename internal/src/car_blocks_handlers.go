@@ -0,0 +1,47 @@
+package src
+
+import (
+	"api/internal/apperr"
+	"api/internal/models"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+func (s *Server) CarBlocksListHandler(c *gin.Context) {
+	resp, err := s.listCarBlocksController(c, c.Param("id"))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+func (s *Server) CarBlocksCreateHandler(c *gin.Context) {
+	var blocksItem models.CarBlocksRequestCreate
+	err := c.ShouldBindJSON(&blocksItem)
+	if err != nil {
+		c.Error(apperr.InvalidRequest(err))
+		return
+	}
+	blocksItem.CarId = c.Param("id")
+
+	resp, err := s.createCarBlocksController(c, &blocksItem)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+func (s *Server) CarBlocksDeleteHandler(c *gin.Context) {
+	resp, err := s.deleteCarBlocksController(c, c.Param("id"), c.Param("block_id"))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
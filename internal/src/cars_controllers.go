@@ -1,19 +1,80 @@
 package src
 
 import (
+	"api/internal/apperr"
+	"api/internal/booking"
+	"api/internal/database"
+	"api/internal/logging"
 	"api/internal/models"
-	"api/internal/utils"
-	"database/sql"
+	"api/internal/repository"
+	"bytes"
 	"errors"
 	"fmt"
-	"log"
+	"io"
+	"math"
+	"mime/multipart"
+	"net/http"
+	"slices"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 )
 
+// maxImageSize caps car photo uploads at 5 MiB.
+const maxImageSize = 5 << 20
+
+var imageExtensions = map[string]string{
+	"image/jpeg": ".jpg",
+	"image/png":  ".png",
+	"image/webp": ".webp",
+}
+
+var (
+	carCategories = []string{"economy", "compact", "midsize", "suv", "mpv", "van", "luxury", "pickup"}
+	transmissions = []string{"manual", "automatic"}
+	fuelTypes     = []string{"petrol", "diesel", "hybrid", "electric"}
+)
+
 func (s *Server) listCarsController(c *gin.Context, req *models.RequestListsGeneral) (*models.CarsResponseList, error) {
+	return s.queryCarsList(c, req, nil, false)
+}
+
+func (s *Server) listDeletedCarsController(c *gin.Context, req *models.RequestListsGeneral) (*models.CarsResponseList, error) {
+	return s.queryCarsList(c, req, nil, true)
+}
+
+func (s *Server) listAvailableCarsController(c *gin.Context, req *models.CarsRequestAvailable) (*models.CarsResponseList, error) {
+	errMsg := ""
+	if req.PickupDate == "" {
+		errMsg = "missing-pickup-date"
+		logging.From(c).Info(errMsg)
+		return nil, apperr.Validation(errMsg, "pickup_date")
+	}
+
+	if req.DropoffDate == "" {
+		errMsg = "missing-dropoff-date"
+		logging.From(c).Info(errMsg)
+		return nil, apperr.Validation(errMsg, "dropoff_date")
+	}
+
+	dateRange, err := booking.ParseRange(req.PickupDate, req.DropoffDate)
+	if err != nil {
+		logError(c, err)
+		return nil, err
+	}
+
+	// a car is only available once its turnaround around other rentals is over
+	dateRange = dateRange.Pad(s.turnaround)
+
+	return s.queryCarsList(c, &req.RequestListsGeneral, &dateRange, false)
+}
+
+// queryCarsList pages through cars applying the shared search, ordering and
+// paging of req. A non nil availableIn leaves out cars booked during it,
+// deleted lists the deleted cars instead.
+func (s *Server) queryCarsList(c *gin.Context, req *models.RequestListsGeneral, availableIn *booking.Range, deleted bool) (*models.CarsResponseList, error) {
 	if req.Page == 0 {
 		req.Page = 1
 	}
@@ -30,91 +91,45 @@ func (s *Server) listCarsController(c *gin.Context, req *models.RequestListsGene
 		req.OrderBy = "car_name"
 	}
 
-	query := `
-		SELECT
-			car_id,
-			car_name,
-			day_rate,
-			month_rate,
-			image
-		FROM cars
-	`
-	var params []interface{}
-
-	cmdQuery := ""
-	count := 0
-	if len(req.Search) > 0 {
-		count++
-		cmdQuery = fmt.Sprintf("%s WHERE LOWER(car_name) LIKE LOWER($%d)", cmdQuery, count)
-		params = append(params, "%"+utils.Sanitize(req.Search)+"%")
-	}
-
-	// count all of search result
-	total := 0
-	queryCounter := fmt.Sprintf("SELECT COUNT(*) AS total FROM cars %s", cmdQuery)
-	err := s.db.QueryRow(c, queryCounter, params...).Scan(&total)
+	q, err := parseListQuery(c, repository.CarsSpec, req)
 	if err != nil {
-		log.Println(err)
+		logError(c, err)
 		return nil, err
 	}
 
-	if req.OrderBy != "" {
-		cmdQuery = fmt.Sprintf("%s ORDER BY %s %s", cmdQuery, utils.Sanitize(req.OrderBy), req.Order)
+	q.Deleted = deleted
+	carQuery := repository.CarQuery{
+		ListQuery:   q,
+		AvailableIn: availableIn,
 	}
 
-	count++
-	cmdQuery = fmt.Sprintf("%s LIMIT $%d ", cmdQuery, count)
-	params = append(params, req.Limit)
-
-	count++
-	cmdQuery = fmt.Sprintf("%s OFFSET $%d ", cmdQuery, count)
-	params = append(params, (req.Page-1)*req.Limit)
-
-	finalQuery := fmt.Sprintf("%s %s", query, cmdQuery)
-	rows, err := s.db.Query(c, finalQuery, params...)
+	carsPage, err := s.cars.List(c, carQuery)
 	if err != nil {
-		log.Println(err)
+		logError(c, err)
 		return nil, err
 	}
-	defer rows.Close()
-
-	var id sql.NullInt64
-	var dayRate, monthRate sql.NullFloat64
-	var carName, image sql.NullString
-	carsData := []*models.CarsItem{}
-	for rows.Next() {
-		item := models.CarsItem{}
-
-		err = rows.Scan(
-			&id,
-			&carName,
-			&dayRate,
-			&monthRate,
-			&image,
-		)
-
-		item.Id = int(id.Int64)
-		item.CarName = strings.TrimSpace(carName.String)
-		item.DayRate = dayRate.Float64
-		item.MonthRate = monthRate.Float64
-		item.Image = strings.TrimSpace(image.String)
 
+	// facets cost about as much as the total and come along with it
+	var facets map[string]map[string]int
+	if q.CountTotal {
+		facets, err = s.cars.Facets(c, carQuery)
 		if err != nil {
-			log.Println(err)
+			logError(c, err)
 			return nil, err
 		}
-
-		carsData = append(carsData, &item)
 	}
 
 	return &models.CarsResponseList{
-		Total:   total,
-		OrderBy: req.OrderBy,
-		Order:   req.Order,
-		Page:    req.Page,
-		Limit:   req.Limit,
-		Items:   carsData,
-		Message: "success",
+		Total:      carsPage.Total,
+		OrderBy:    req.OrderBy,
+		Order:      req.Order,
+		Page:       req.Page,
+		Limit:      req.Limit,
+		NextCursor: carsPage.NextCursor,
+		PrevCursor: carsPage.PrevCursor,
+		Items:      carsPage.Items,
+		Facets:     facets,
+		Message:    "success",
 	}, nil
 }
 
@@ -122,47 +137,105 @@ func (s *Server) createCarsController(c *gin.Context, req *models.CarsRequestCre
 	errMsg := ""
 	if req.CarName == "" {
 		errMsg = "missing-car-name"
-		log.Println(errMsg)
-		return nil, errors.New(errMsg)
+		logging.From(c).Info(errMsg)
+		return nil, apperr.Validation(errMsg, "car_name")
 	}
 
 	if req.DayRate == "" {
 		errMsg = "missing-day-rate"
-		log.Println(errMsg)
-		return nil, errors.New(errMsg)
+		logging.From(c).Info(errMsg)
+		return nil, apperr.Validation(errMsg, "day_rate")
 	}
 
 	dayRateVal, err := strconv.ParseFloat(strings.TrimSpace(req.DayRate), 64)
 	if err != nil {
-		errMsg = "failed-parsing-month-rate"
-		log.Println(err)
-		return nil, errors.New(errMsg)
+		errMsg = "failed-parsing-day-rate"
+		logError(c, err)
+		return nil, apperr.Validation(errMsg, "day_rate")
+	}
+
+	if !validRate(dayRateVal) {
+		errMsg = "invalid-day-rate"
+		logging.From(c).Info(errMsg)
+		return nil, apperr.Validation(errMsg, "day_rate")
 	}
 
 	if req.MonthRate == "" {
 		errMsg = "missing-month-rate"
-		log.Println(errMsg)
-		return nil, errors.New(errMsg)
+		logging.From(c).Info(errMsg)
+		return nil, apperr.Validation(errMsg, "month_rate")
 	}
 
 	monthRateVal, err := strconv.ParseFloat(strings.TrimSpace(req.MonthRate), 64)
 	if err != nil {
 		errMsg = "failed-parsing-month-rate"
-		log.Println(err)
-		return nil, errors.New(errMsg)
+		logError(c, err)
+		return nil, apperr.Validation(errMsg, "month_rate")
 	}
 
-	if req.Image == "" {
-		errMsg = "missing-image"
-		log.Println(errMsg)
-		return nil, errors.New(errMsg)
+	if !validRate(monthRateVal) {
+		errMsg = "invalid-month-rate"
+		logging.From(c).Info(errMsg)
+		return nil, apperr.Validation(errMsg, "month_rate")
+	}
+
+	homeLocationId, err := parseLocationId(req.HomeLocationId, "home_location_id")
+	if err != nil {
+		logError(c, err)
+		return nil, err
 	}
 
-	// TODO: need image save provider
-	var carsId int
-	err = s.db.QueryRow(c, "INSERT INTO cars (car_name, day_rate, month_rate, image) VALUES ($1, $2, $3, $4) RETURNING car_id", req.CarName, dayRateVal, monthRateVal, req.Image).Scan(&carsId)
+	currentLocationId, err := parseLocationId(req.CurrentLocationId, "current_location_id")
 	if err != nil {
-		log.Println(err)
+		logError(c, err)
+		return nil, err
+	}
+
+	catalog, err := parseCarCatalog(req)
+	if err != nil {
+		logError(c, err)
+		return nil, err
+	}
+
+	// a new car starts out at its home branch unless told otherwise
+	if currentLocationId == 0 {
+		currentLocationId = homeLocationId
+	}
+
+	hourRateVal, err := parseHourRate(req.HourRate)
+	if err != nil {
+		logError(c, err)
+		return nil, err
+	}
+
+	// the image is optional here, it can be uploaded afterwards through
+	// POST /cars/:id/image
+	carsId, err := s.cars.Create(c, &models.CarsItem{
+		CarName:           req.CarName,
+		DayRate:           dayRateVal,
+		MonthRate:         monthRateVal,
+		HourRate:          hourRateVal,
+		Image:             req.Image,
+		HomeLocationId:    homeLocationId,
+		CurrentLocationId: currentLocationId,
+		Make:              catalog.Make,
+		Model:             catalog.Model,
+		Year:              catalog.Year,
+		Category:          catalog.Category,
+		Seats:             catalog.Seats,
+		Transmission:      catalog.Transmission,
+		FuelType:          catalog.FuelType,
+		PlateNumber:       catalog.PlateNumber,
+		Features:          catalog.Features,
+	})
+	if err != nil {
+		if database.IsForeignKeyViolation(err) {
+			err = apperr.NotFound("location-not-found").Wrap(err)
+		}
+		if database.IsUniqueViolation(err) {
+			err = apperr.Conflict("plate-number-taken").Wrap(err)
+		}
+		logError(c, err)
 		return nil, err
 	}
 
@@ -171,73 +244,257 @@ func (s *Server) createCarsController(c *gin.Context, req *models.CarsRequestCre
 	}, nil
 }
 
+// parseCarCatalog checks and normalises the catalog attributes of req, all
+// of them optional. Choices are lower cased, the plate upper cased and the
+// features deduplicated.
+func parseCarCatalog(req *models.CarsRequestCreate) (*models.CarsItem, error) {
+	var err error
+	catalog := &models.CarsItem{
+		Make:        strings.TrimSpace(req.Make),
+		Model:       strings.TrimSpace(req.Model),
+		PlateNumber: strings.ToUpper(strings.Join(strings.Fields(req.PlateNumber), " ")),
+		Features:    []string{},
+	}
+
+	catalog.Year, err = parseCarNumber(req.Year, 1900, 2100, "year")
+	if err != nil {
+		return nil, err
+	}
+
+	catalog.Seats, err = parseCarNumber(req.Seats, 1, 60, "seats")
+	if err != nil {
+		return nil, err
+	}
+
+	catalog.Category, err = parseChoice(req.Category, carCategories, "category")
+	if err != nil {
+		return nil, err
+	}
+
+	catalog.Transmission, err = parseChoice(req.Transmission, transmissions, "transmission")
+	if err != nil {
+		return nil, err
+	}
+
+	catalog.FuelType, err = parseChoice(req.FuelType, fuelTypes, "fuel_type")
+	if err != nil {
+		return nil, err
+	}
+
+	for _, feature := range req.Features {
+		feature = strings.ToLower(strings.TrimSpace(feature))
+		if feature != "" && !slices.Contains(catalog.Features, feature) {
+			catalog.Features = append(catalog.Features, feature)
+		}
+	}
+	slices.Sort(catalog.Features)
+
+	return catalog, nil
+}
+
+// parseCarNumber reads an optional whole number between low and high, 0 when
+// empty.
+func parseCarNumber(value string, low, high int, field string) (int, error) {
+	if value == "" {
+		return 0, nil
+	}
+
+	number, err := strconv.Atoi(strings.TrimSpace(value))
+	if err != nil {
+		return 0, apperr.Validation(fmt.Sprintf("failed-parsing-%s", strings.ReplaceAll(field, "_", "-")), field)
+	}
+
+	if number < low || number > high {
+		return 0, apperr.Validation(fmt.Sprintf("invalid-%s", strings.ReplaceAll(field, "_", "-")), field)
+	}
+
+	return number, nil
+}
+
+// parseChoice lower cases value and checks it is one of choices, an empty
+// value stays empty.
+func parseChoice(value string, choices []string, field string) (string, error) {
+	value = strings.ToLower(strings.TrimSpace(value))
+	if value != "" && !slices.Contains(choices, value) {
+		return "", apperr.Validation(fmt.Sprintf("invalid-%s", strings.ReplaceAll(field, "_", "-")), field)
+	}
+
+	return value, nil
+}
+
+// parseHourRate reads the optional hour_rate, 0 when empty.
+func parseHourRate(value string) (float64, error) {
+	if value == "" {
+		return 0, nil
+	}
+
+	hourRate, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+	if err != nil {
+		return 0, apperr.Validation("failed-parsing-hour-rate", "hour_rate")
+	}
+
+	if !validRate(hourRate) {
+		return 0, apperr.Validation("invalid-hour-rate", "hour_rate")
+	}
+
+	return hourRate, nil
+}
+
+// validRate tells whether rate can price a rental: ParseFloat also reads
+// NaN and Inf.
+func validRate(rate float64) bool {
+	return rate >= 0 && !math.IsInf(rate, 0)
+}
+
 func (s *Server) updateCarsController(c *gin.Context, req *models.CarsRequestUpdate) (*models.ResponseGeneral, error) {
 	errorMsg := ""
 	if req.Id == "" {
 		errorMsg = "missing-cars-id"
-		log.Println(errorMsg)
-		return nil, errors.New(errorMsg)
+		logging.From(c).Info(errorMsg)
+		return nil, apperr.Validation(errorMsg, "id")
 	}
 
 	carId, err := strconv.Atoi(req.Id)
 	if err != nil {
 		errorMsg = "wrong-cars-id-type"
-		log.Println(errorMsg)
-		return nil, errors.New(errorMsg)
+		logging.From(c).Info(errorMsg)
+		return nil, apperr.Validation(errorMsg, "id")
 	}
 
-	query := "UPDATE cars SET"
-	var params []interface{}
-	var set []string
-
-	count := 0
+	var update repository.CarUpdate
 	if req.CarName != "" {
-		count++
-		set = append(set, fmt.Sprintf("car_name=$%d", count))
-		params = append(params, req.CarName)
+		update.CarName = &req.CarName
 	}
 
 	if req.Image != "" {
-		count++
-		set = append(set, fmt.Sprintf("image=$%d", count))
-		params = append(params, req.Image)
+		update.Image = &req.Image
 	}
 
 	if req.DayRate != "" {
 		dayRateVal, err := strconv.ParseFloat(strings.TrimSpace(req.DayRate), 64)
 		if err != nil {
 			errorMsg = "failed-parsing-day-rate"
-			log.Println(err)
-			return nil, errors.New(errorMsg)
+			logError(c, err)
+			return nil, apperr.Validation(errorMsg, "day_rate")
+		}
+
+		if !validRate(dayRateVal) {
+			errorMsg = "invalid-day-rate"
+			logging.From(c).Info(errorMsg)
+			return nil, apperr.Validation(errorMsg, "day_rate")
 		}
 
-		count++
-		set = append(set, fmt.Sprintf("day_rate=$%d", count))
-		params = append(params, dayRateVal)
+		update.DayRate = &dayRateVal
 	}
 
 	if req.MonthRate != "" {
 		monthRateVal, err := strconv.ParseFloat(strings.TrimSpace(req.MonthRate), 64)
 		if err != nil {
 			errorMsg = "failed-parsing-month-rate"
-			log.Println(err)
-			return nil, errors.New(errorMsg)
+			logError(c, err)
+			return nil, apperr.Validation(errorMsg, "month_rate")
+		}
+
+		if !validRate(monthRateVal) {
+			errorMsg = "invalid-month-rate"
+			logging.From(c).Info(errorMsg)
+			return nil, apperr.Validation(errorMsg, "month_rate")
+		}
+
+		update.MonthRate = &monthRateVal
+	}
+
+	if req.HourRate != "" {
+		hourRateVal, err := parseHourRate(req.HourRate)
+		if err != nil {
+			logError(c, err)
+			return nil, err
+		}
+
+		update.HourRate = &hourRateVal
+	}
+
+	if req.HomeLocationId != "" {
+		homeLocationId, err := parseLocationId(req.HomeLocationId, "home_location_id")
+		if err != nil {
+			logError(c, err)
+			return nil, err
+		}
+
+		update.HomeLocationId = &homeLocationId
+	}
+
+	if req.CurrentLocationId != "" {
+		currentLocationId, err := parseLocationId(req.CurrentLocationId, "current_location_id")
+		if err != nil {
+			logError(c, err)
+			return nil, err
+		}
+
+		update.CurrentLocationId = &currentLocationId
+	}
+
+	// only the attributes sent are changed
+	catalog, err := parseCarCatalog(&models.CarsRequestCreate{
+		Make:         req.Make,
+		Model:        req.Model,
+		Year:         req.Year,
+		Category:     req.Category,
+		Seats:        req.Seats,
+		Transmission: req.Transmission,
+		FuelType:     req.FuelType,
+		PlateNumber:  req.PlateNumber,
+		Features:     req.Features,
+	})
+	if err != nil {
+		logError(c, err)
+		return nil, err
+	}
+
+	for _, field := range []struct {
+		sent   string
+		value  string
+		target **string
+	}{
+		{req.Make, catalog.Make, &update.Make},
+		{req.Model, catalog.Model, &update.Model},
+		{req.Category, catalog.Category, &update.Category},
+		{req.Transmission, catalog.Transmission, &update.Transmission},
+		{req.FuelType, catalog.FuelType, &update.FuelType},
+		{req.PlateNumber, catalog.PlateNumber, &update.PlateNumber},
+	} {
+		if field.sent != "" {
+			value := field.value
+			*field.target = &value
 		}
+	}
 
-		count++
-		set = append(set, fmt.Sprintf("month_rate=$%d", count))
-		params = append(params, monthRateVal)
+	if req.Year != "" {
+		update.Year = &catalog.Year
 	}
 
-	count++
-	query = fmt.Sprintf("%s %s WHERE car_id=$%d", query, strings.Join(set, ","), count)
-	params = append(params, carId)
+	if req.Seats != "" {
+		update.Seats = &catalog.Seats
+	}
+
+	if req.Features != nil {
+		update.Features = &catalog.Features
+	}
 
-	log.Println(query)
-	log.Println(params)
-	_, err = s.db.Exec(c, query, params...)
+	err = s.cars.Update(c, carId, update)
+	if errors.Is(err, repository.ErrNotFound) {
+		errorMsg = "car-not-found"
+		logging.From(c).Info(errorMsg)
+		return nil, apperr.NotFound(errorMsg)
+	}
 	if err != nil {
-		log.Println(err)
+		if database.IsForeignKeyViolation(err) {
+			err = apperr.NotFound("location-not-found").Wrap(err)
+		}
+		if database.IsUniqueViolation(err) {
+			err = apperr.Conflict("plate-number-taken").Wrap(err)
+		}
+		logError(c, err)
 		return nil, err
 	}
 
@@ -251,20 +508,56 @@ func (s *Server) deleteCarsController(c *gin.Context, id string) (*models.Respon
 	errorMsg := ""
 	if id == "" {
 		errorMsg = "missing-cars-id"
-		log.Println(errorMsg)
-		return nil, errors.New(errorMsg)
+		logging.From(c).Info(errorMsg)
+		return nil, apperr.Validation(errorMsg, "id")
 	}
 
 	carId, err := strconv.Atoi(id)
 	if err != nil {
 		errorMsg = "wrong-cars-id-type"
-		log.Println(errorMsg)
-		return nil, errors.New(errorMsg)
+		logging.From(c).Info(errorMsg)
+		return nil, apperr.Validation(errorMsg, "id")
 	}
 
-	_, err = s.db.Exec(c, "DELETE FROM cars WHERE car_id=$1", carId)
+	err = s.cars.Delete(c, carId)
+	if errors.Is(err, repository.ErrNotFound) {
+		errorMsg = "car-not-found"
+		logging.From(c).Info(errorMsg)
+		return nil, apperr.NotFound(errorMsg)
+	}
+	if errors.Is(err, repository.ErrInUse) {
+		err = apperr.Conflict("car-has-active-orders").Wrap(err)
+		logError(c, err)
+		return nil, err
+	}
 	if err != nil {
-		log.Println(err)
+		logError(c, err)
+		return nil, err
+	}
+
+	return &models.ResponseGeneral{
+		Id:      carId,
+		Message: "success",
+	}, nil
+}
+
+func (s *Server) restoreCarsController(c *gin.Context, id string) (*models.ResponseGeneral, error) {
+	carId, err := parseCarId(id)
+	if err != nil {
+		logError(c, err)
+		return nil, err
+	}
+
+	err = s.cars.Restore(c, carId)
+	if err != nil {
+		switch {
+		case errors.Is(err, repository.ErrNotFound):
+			err = apperr.NotFound("car-not-found")
+		case database.IsUniqueViolation(err):
+			// another car took the plate while this one was deleted
+			err = apperr.Conflict("plate-number-taken").Wrap(err)
+		}
+		logError(c, err)
 		return nil, err
 	}
 
@@ -278,47 +571,26 @@ func (s *Server) getCarsByIdController(c *gin.Context, id string) (*models.CarsR
 	errorMsg := ""
 	if id == "" {
 		errorMsg = "missing-cars-id"
-		log.Println(errorMsg)
-		return nil, errors.New(errorMsg)
+		logging.From(c).Info(errorMsg)
+		return nil, apperr.Validation(errorMsg, "id")
 	}
 
 	carId, err := strconv.Atoi(id)
 	if err != nil {
 		errorMsg = "wrong-cars-id-type"
-		log.Println(errorMsg)
-		return nil, errors.New(errorMsg)
+		logging.From(c).Info(errorMsg)
+		return nil, apperr.Validation(errorMsg, "id")
 	}
 
 	var resp models.CarsResponseGet
-	var idRes sql.NullInt64
-	var dayRate, monthRate sql.NullFloat64
-	var carName, image sql.NullString
-	err = s.db.QueryRow(c, `
-		SELECT 
-			car_id, 
-			car_name, 
-			day_rate, 
-			month_rate,
-			image
-		FROM cars WHERE car_id = $1
-		`, carId).Scan(
-		&idRes,
-		&carName,
-		&dayRate,
-		&monthRate,
-		&image,
-	)
-
-	resp.Item = &models.CarsItem{
-		Id:        int(idRes.Int64),
-		CarName:   strings.TrimSpace(carName.String),
-		DayRate:   dayRate.Float64,
-		MonthRate: monthRate.Float64,
-		Image:     strings.TrimSpace(image.String),
+	resp.Item, err = s.cars.Get(c, carId)
+	if errors.Is(err, repository.ErrNotFound) {
+		errorMsg = "car-not-found"
+		logging.From(c).Info(errorMsg)
+		return nil, apperr.NotFound(errorMsg)
 	}
-
 	if err != nil {
-		log.Println(err)
+		logError(c, err)
 		return nil, err
 	}
 
@@ -326,3 +598,92 @@ func (s *Server) getCarsByIdController(c *gin.Context, id string) (*models.CarsR
 
 	return &resp, nil
 }
+
+func (s *Server) uploadCarsImageController(c *gin.Context, id string, file *multipart.FileHeader) (*models.CarsResponseImage, error) {
+	errorMsg := ""
+	if id == "" {
+		errorMsg = "missing-cars-id"
+		logging.From(c).Info(errorMsg)
+		return nil, apperr.Validation(errorMsg, "id")
+	}
+
+	carId, err := strconv.Atoi(id)
+	if err != nil {
+		errorMsg = "wrong-cars-id-type"
+		logging.From(c).Info(errorMsg)
+		return nil, apperr.Validation(errorMsg, "id")
+	}
+
+	if file == nil {
+		errorMsg = "missing-image"
+		logging.From(c).Info(errorMsg)
+		return nil, apperr.Validation(errorMsg, "image")
+	}
+
+	if file.Size > maxImageSize {
+		errorMsg = "image-too-large"
+		logging.From(c).Info(errorMsg)
+		return nil, apperr.TooLarge(errorMsg)
+	}
+
+	_, err = s.cars.Get(c, carId)
+	if errors.Is(err, repository.ErrNotFound) {
+		errorMsg = "car-not-found"
+		logging.From(c).Info(errorMsg)
+		return nil, apperr.NotFound(errorMsg)
+	}
+	if err != nil {
+		logError(c, err)
+		return nil, err
+	}
+
+	src, err := file.Open()
+	if err != nil {
+		logError(c, err)
+		return nil, err
+	}
+	defer src.Close()
+
+	// trust the bytes, not the client supplied Content-Type header
+	head := make([]byte, 512)
+	n, err := io.ReadFull(src, head)
+	if errors.Is(err, io.EOF) {
+		errorMsg = "empty-image"
+		logging.From(c).Info(errorMsg)
+		return nil, apperr.Validation(errorMsg, "image")
+	}
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) {
+		logError(c, err)
+		return nil, err
+	}
+
+	contentType := http.DetectContentType(head[:n])
+	ext, ok := imageExtensions[contentType]
+	if !ok {
+		errorMsg = "unsupported-image-type"
+		logging.From(c).Info(errorMsg, "content_type", contentType)
+		return nil, apperr.Validation(errorMsg, "image")
+	}
+
+	key := fmt.Sprintf("cars/%d-%d%s", carId, time.Now().UnixNano(), ext)
+	url, err := s.images.Save(c, key, contentType, io.MultiReader(bytes.NewReader(head[:n]), src), file.Size)
+	if err != nil {
+		logError(c, err)
+		return nil, err
+	}
+
+	err = s.cars.Update(c, carId, repository.CarUpdate{Image: &url})
+	if err != nil {
+		logError(c, err)
+		if delErr := s.images.Delete(c, key); delErr != nil {
+			logError(c, delErr)
+		}
+		return nil, err
+	}
+
+	return &models.CarsResponseImage{
+		Id:      carId,
+		Image:   url,
+		Message: "success",
+	}, nil
+}
@@ -1,37 +1,59 @@
 package src
 
 import (
+	"api/internal/apperr"
 	"api/internal/models"
-	"log"
+	"errors"
 	"net/http"
-	"strings"
 
 	"github.com/gin-gonic/gin"
 )
 
 func (s *Server) CarsListHandler(c *gin.Context) {
 	var listRequest models.RequestListsGeneral
-	err := c.BindQuery(&listRequest)
+	err := c.ShouldBindQuery(&listRequest)
 	if err != nil {
-		log.Println(err)
-		c.JSON(http.StatusBadRequest, &models.CarsResponseList{
-			Message: err.Error(),
-		})
+		c.Error(apperr.InvalidRequest(err))
 		return
 	}
 
 	resp, err := s.listCarsController(c, &listRequest)
 	if err != nil {
-		if strings.Contains(err.Error(), "missing") {
-			c.JSON(http.StatusBadRequest, &models.CarsResponseList{
-				Message: err.Error(),
-			})
-			return
-		}
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
 
-		c.JSON(http.StatusInternalServerError, &models.CarsResponseList{
-			Message: err.Error(),
-		})
+func (s *Server) CarsListDeletedHandler(c *gin.Context) {
+	var listRequest models.RequestListsGeneral
+	err := c.ShouldBindQuery(&listRequest)
+	if err != nil {
+		c.Error(apperr.InvalidRequest(err))
+		return
+	}
+
+	resp, err := s.listDeletedCarsController(c, &listRequest)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+func (s *Server) CarsAvailableHandler(c *gin.Context) {
+	var availableRequest models.CarsRequestAvailable
+	err := c.ShouldBindQuery(&availableRequest)
+	if err != nil {
+		c.Error(apperr.InvalidRequest(err))
+		return
+	}
+
+	resp, err := s.listAvailableCarsController(c, &availableRequest)
+	if err != nil {
+		c.Error(err)
 		return
 	}
 
@@ -42,25 +64,13 @@ func (s *Server) CarsCreateHandler(c *gin.Context) {
 	var carsItem models.CarsRequestCreate
 	err := c.ShouldBindJSON(&carsItem)
 	if err != nil {
-		log.Println(err)
-		c.JSON(http.StatusBadRequest, &models.ResponseGeneral{
-			Message: err.Error(),
-		})
+		c.Error(apperr.InvalidRequest(err))
 		return
 	}
 
 	resp, err := s.createCarsController(c, &carsItem)
 	if err != nil {
-		if strings.Contains(err.Error(), "missing") {
-			c.JSON(http.StatusBadRequest, &models.ResponseGeneral{
-				Message: err.Error(),
-			})
-			return
-		}
-
-		c.JSON(http.StatusInternalServerError, &models.ResponseGeneral{
-			Message: err.Error(),
-		})
+		c.Error(err)
 		return
 	}
 
@@ -71,26 +81,14 @@ func (s *Server) CarsUpdateHandler(c *gin.Context) {
 	var carsItem models.CarsRequestUpdate
 	err := c.ShouldBindJSON(&carsItem)
 	if err != nil {
-		log.Println(err)
-		c.JSON(http.StatusBadRequest, &models.ResponseGeneral{
-			Message: err.Error(),
-		})
+		c.Error(apperr.InvalidRequest(err))
 		return
 	}
 	carsItem.Id = c.Param("id")
 
 	resp, err := s.updateCarsController(c, &carsItem)
 	if err != nil {
-		if strings.Contains(err.Error(), "missing") {
-			c.JSON(http.StatusBadRequest, &models.ResponseGeneral{
-				Message: err.Error(),
-			})
-			return
-		}
-
-		c.JSON(http.StatusInternalServerError, &models.ResponseGeneral{
-			Message: err.Error(),
-		})
+		c.Error(err)
 		return
 	}
 
@@ -102,16 +100,19 @@ func (s *Server) CarsDeleteHandler(c *gin.Context) {
 
 	resp, err := s.deleteCarsController(c, carId)
 	if err != nil {
-		if strings.Contains(err.Error(), "missing") {
-			c.JSON(http.StatusBadRequest, &models.ResponseGeneral{
-				Message: err.Error(),
-			})
-			return
-		}
+		c.Error(err)
+		return
+	}
 
-		c.JSON(http.StatusInternalServerError, &models.ResponseGeneral{
-			Message: err.Error(),
-		})
+	c.JSON(http.StatusOK, resp)
+}
+
+func (s *Server) CarsRestoreHandler(c *gin.Context) {
+	carId := c.Param("id")
+
+	resp, err := s.restoreCarsController(c, carId)
+	if err != nil {
+		c.Error(err)
 		return
 	}
 
@@ -120,20 +121,37 @@ func (s *Server) CarsDeleteHandler(c *gin.Context) {
 
 func (s *Server) CarsGetByIdHandler(c *gin.Context) {
 	carId := c.Param("id")
-	log.Println(carId)
 
 	resp, err := s.getCarsByIdController(c, carId)
 	if err != nil {
-		if strings.Contains(err.Error(), "missing") {
-			c.JSON(http.StatusBadRequest, &models.ResponseGeneral{
-				Message: err.Error(),
-			})
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+func (s *Server) CarsUploadImageHandler(c *gin.Context) {
+	carId := c.Param("id")
+
+	// leave room for the multipart envelope around the image itself
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxImageSize+1<<20)
+
+	file, err := c.FormFile("image")
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			c.Error(apperr.TooLarge("image-too-large").Wrap(err))
 			return
 		}
 
-		c.JSON(http.StatusInternalServerError, &models.ResponseGeneral{
-			Message: err.Error(),
-		})
+		c.Error(apperr.Validation("missing-image", "image").Wrap(err))
+		return
+	}
+
+	resp, err := s.uploadCarsImageController(c, carId, file)
+	if err != nil {
+		c.Error(err)
 		return
 	}
 
@@ -0,0 +1,275 @@
+package src
+
+import (
+	"api/internal/apperr"
+	"api/internal/booking"
+	"api/internal/database"
+	"api/internal/logging"
+	"api/internal/models"
+	"api/internal/repository"
+	"errors"
+	"net/mail"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func (s *Server) listCustomersController(c *gin.Context, req *models.RequestListsGeneral) (*models.CustomersResponseList, error) {
+	if req.Page == 0 {
+		req.Page = 1
+	}
+
+	if req.Limit == 0 {
+		req.Limit = 10
+	}
+
+	if req.Order == "" || strings.ToUpper(req.Order) != "DESC" {
+		req.Order = "ASC"
+	}
+
+	if req.OrderBy == "" {
+		req.OrderBy = "name"
+	}
+
+	q, err := parseListQuery(c, repository.CustomersSpec, req)
+	if err != nil {
+		logError(c, err)
+		return nil, err
+	}
+
+	// customers are few enough to page by number only
+	if q.Keyset {
+		errMsg := "invalid-paging"
+		logging.From(c).Info(errMsg)
+		return nil, apperr.Validation(errMsg, "paging")
+	}
+	q.CountTotal = true
+
+	customersPage, err := s.customers.List(c, q)
+	if err != nil {
+		logError(c, err)
+		return nil, err
+	}
+
+	return &models.CustomersResponseList{
+		Total:   *customersPage.Total,
+		OrderBy: req.OrderBy,
+		Order:   req.Order,
+		Page:    req.Page,
+		Limit:   req.Limit,
+		Items:   customersPage.Items,
+		Message: "success",
+	}, nil
+}
+
+// validEmail tells whether email is a single address, without a display
+// name around it.
+func validEmail(email string) bool {
+	address, err := mail.ParseAddress(email)
+	return err == nil && address.Address == email
+}
+
+func (s *Server) createCustomersController(c *gin.Context, req *models.CustomersRequestCreate) (*models.ResponseGeneral, error) {
+	errMsg := ""
+	if req.Name == "" {
+		errMsg = "missing-name"
+		logging.From(c).Info(errMsg)
+		return nil, apperr.Validation(errMsg, "name")
+	}
+
+	if req.Email == "" {
+		errMsg = "missing-email"
+		logging.From(c).Info(errMsg)
+		return nil, apperr.Validation(errMsg, "email")
+	}
+
+	if !validEmail(req.Email) {
+		errMsg = "invalid-email"
+		logging.From(c).Info(errMsg)
+		return nil, apperr.Validation(errMsg, "email")
+	}
+
+	if req.Phone == "" {
+		errMsg = "missing-phone"
+		logging.From(c).Info(errMsg)
+		return nil, apperr.Validation(errMsg, "phone")
+	}
+
+	if req.LicenceNumber == "" {
+		errMsg = "missing-licence-number"
+		logging.From(c).Info(errMsg)
+		return nil, apperr.Validation(errMsg, "licence_number")
+	}
+
+	if req.LicenceExpiry == "" {
+		errMsg = "missing-licence-expiry"
+		logging.From(c).Info(errMsg)
+		return nil, apperr.Validation(errMsg, "licence_expiry")
+	}
+
+	_, err := time.Parse(booking.DateLayout, req.LicenceExpiry)
+	if err != nil {
+		errMsg = "failed-parsing-licence-expiry"
+		logError(c, err)
+		return nil, apperr.Validation(errMsg, "licence_expiry")
+	}
+
+	customerId, err := s.customers.Create(c, &models.CustomersItem{
+		Name:          req.Name,
+		Email:         strings.ToLower(req.Email),
+		Phone:         req.Phone,
+		LicenceNumber: req.LicenceNumber,
+		LicenceExpiry: req.LicenceExpiry,
+	})
+	if err != nil {
+		if database.IsUniqueViolation(err) {
+			err = apperr.Conflict("email-already-registered").Wrap(err)
+		}
+		logError(c, err)
+		return nil, err
+	}
+
+	return &models.ResponseGeneral{
+		Id:      customerId,
+		Message: "success",
+	}, nil
+}
+
+func (s *Server) updateCustomersController(c *gin.Context, req *models.CustomersRequestUpdate) (*models.ResponseGeneral, error) {
+	errorMsg := ""
+	if req.Id == "" {
+		errorMsg = "missing-customer-id"
+		logging.From(c).Info(errorMsg)
+		return nil, apperr.Validation(errorMsg, "id")
+	}
+
+	customerId, err := strconv.Atoi(req.Id)
+	if err != nil {
+		errorMsg = "wrong-customer-id-type"
+		logging.From(c).Info(errorMsg)
+		return nil, apperr.Validation(errorMsg, "id")
+	}
+
+	var update repository.CustomerUpdate
+	if req.Name != "" {
+		update.Name = &req.Name
+	}
+
+	if req.Email != "" {
+		if !validEmail(req.Email) {
+			errorMsg = "invalid-email"
+			logging.From(c).Info(errorMsg)
+			return nil, apperr.Validation(errorMsg, "email")
+		}
+
+		email := strings.ToLower(req.Email)
+		update.Email = &email
+	}
+
+	if req.Phone != "" {
+		update.Phone = &req.Phone
+	}
+
+	if req.LicenceNumber != "" {
+		update.LicenceNumber = &req.LicenceNumber
+	}
+
+	if req.LicenceExpiry != "" {
+		_, err = time.Parse(booking.DateLayout, req.LicenceExpiry)
+		if err != nil {
+			errorMsg = "failed-parsing-licence-expiry"
+			logError(c, err)
+			return nil, apperr.Validation(errorMsg, "licence_expiry")
+		}
+
+		update.LicenceExpiry = &req.LicenceExpiry
+	}
+
+	err = s.customers.Update(c, customerId, update)
+	if errors.Is(err, repository.ErrNotFound) {
+		errorMsg = "customer-not-found"
+		logging.From(c).Info(errorMsg)
+		return nil, apperr.NotFound(errorMsg)
+	}
+	if err != nil {
+		if database.IsUniqueViolation(err) {
+			err = apperr.Conflict("email-already-registered").Wrap(err)
+		}
+		logError(c, err)
+		return nil, err
+	}
+
+	return &models.ResponseGeneral{
+		Id:      customerId,
+		Message: "success",
+	}, nil
+}
+
+func (s *Server) deleteCustomersController(c *gin.Context, id string) (*models.ResponseGeneral, error) {
+	errorMsg := ""
+	if id == "" {
+		errorMsg = "missing-customer-id"
+		logging.From(c).Info(errorMsg)
+		return nil, apperr.Validation(errorMsg, "id")
+	}
+
+	customerId, err := strconv.Atoi(id)
+	if err != nil {
+		errorMsg = "wrong-customer-id-type"
+		logging.From(c).Info(errorMsg)
+		return nil, apperr.Validation(errorMsg, "id")
+	}
+
+	err = s.customers.Delete(c, customerId)
+	if errors.Is(err, repository.ErrNotFound) {
+		errorMsg = "customer-not-found"
+		logging.From(c).Info(errorMsg)
+		return nil, apperr.NotFound(errorMsg)
+	}
+	if err != nil {
+		if errors.Is(err, repository.ErrInUse) {
+			err = apperr.Conflict("customer-has-orders").Wrap(err)
+		}
+		logError(c, err)
+		return nil, err
+	}
+
+	return &models.ResponseGeneral{
+		Id:      customerId,
+		Message: "success",
+	}, nil
+}
+
+func (s *Server) getCustomersByIdController(c *gin.Context, id string) (*models.CustomersResponseGet, error) {
+	errorMsg := ""
+	if id == "" {
+		errorMsg = "missing-customer-id"
+		logging.From(c).Info(errorMsg)
+		return nil, apperr.Validation(errorMsg, "id")
+	}
+
+	customerId, err := strconv.Atoi(id)
+	if err != nil {
+		errorMsg = "wrong-customer-id-type"
+		logging.From(c).Info(errorMsg)
+		return nil, apperr.Validation(errorMsg, "id")
+	}
+
+	var resp models.CustomersResponseGet
+	resp.Item, err = s.customers.Get(c, customerId)
+	if errors.Is(err, repository.ErrNotFound) {
+		errorMsg = "customer-not-found"
+		logging.From(c).Info(errorMsg)
+		return nil, apperr.NotFound(errorMsg)
+	}
+	if err != nil {
+		logError(c, err)
+		return nil, err
+	}
+
+	resp.Message = "success"
+
+	return &resp, nil
+}
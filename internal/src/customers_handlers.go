@@ -0,0 +1,85 @@
+package src
+
+import (
+	"api/internal/apperr"
+	"api/internal/models"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+func (s *Server) CustomersListHandler(c *gin.Context) {
+	var listRequest models.RequestListsGeneral
+	err := c.ShouldBindQuery(&listRequest)
+	if err != nil {
+		c.Error(apperr.InvalidRequest(err))
+		return
+	}
+
+	resp, err := s.listCustomersController(c, &listRequest)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+func (s *Server) CustomersCreateHandler(c *gin.Context) {
+	var customersItem models.CustomersRequestCreate
+	err := c.ShouldBindJSON(&customersItem)
+	if err != nil {
+		c.Error(apperr.InvalidRequest(err))
+		return
+	}
+
+	resp, err := s.createCustomersController(c, &customersItem)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+func (s *Server) CustomersUpdateHandler(c *gin.Context) {
+	var customersItem models.CustomersRequestUpdate
+	err := c.ShouldBindJSON(&customersItem)
+	if err != nil {
+		c.Error(apperr.InvalidRequest(err))
+		return
+	}
+	customersItem.Id = c.Param("id")
+
+	resp, err := s.updateCustomersController(c, &customersItem)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+func (s *Server) CustomersDeleteHandler(c *gin.Context) {
+	customerId := c.Param("id")
+
+	resp, err := s.deleteCustomersController(c, customerId)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+func (s *Server) CustomersGetByIdHandler(c *gin.Context) {
+	customerId := c.Param("id")
+
+	resp, err := s.getCustomersByIdController(c, customerId)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
@@ -0,0 +1,35 @@
+package src
+
+import (
+	"api/internal/openapi"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// swaggerUI renders the OpenAPI document served next to it, the assets come
+// from the swagger-ui-dist package on unpkg.
+const swaggerUI = `<!DOCTYPE html>
+<html lang="en">
+<head>
+	<meta charset="utf-8">
+	<title>car-rents-backend API</title>
+	<link rel="stylesheet" href="https://unpkg.com/swagger-ui-dist@5/swagger-ui.css">
+</head>
+<body>
+	<div id="swagger-ui"></div>
+	<script src="https://unpkg.com/swagger-ui-dist@5/swagger-ui-bundle.js"></script>
+	<script>
+		window.ui = SwaggerUIBundle({url: "openapi.json", dom_id: "#swagger-ui"});
+	</script>
+</body>
+</html>
+`
+
+func (s *Server) OpenAPIHandler(c *gin.Context) {
+	c.Data(http.StatusOK, "application/json", openapi.Document)
+}
+
+func (s *Server) DocsHandler(c *gin.Context) {
+	c.Data(http.StatusOK, "text/html; charset=utf-8", []byte(swaggerUI))
+}
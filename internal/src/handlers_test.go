@@ -0,0 +1,966 @@
+package src
+
+import (
+	"api/internal/auth"
+	"api/internal/database"
+	"api/internal/metrics"
+	"api/internal/models"
+	"api/internal/repository"
+	"api/internal/storage"
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestServer(t *testing.T) (*Server, *repository.Memory) {
+	gin.SetMode(gin.TestMode)
+
+	mem := repository.NewMemory()
+	s := &Server{
+		cars:      mem.Cars(),
+		orders:    mem.Orders(),
+		blocks:    mem.CarBlocks(),
+		customers: mem.Customers(),
+		locations: mem.Locations(),
+		users:     mem.Users(),
+		auth:      auth.NewManager("test-secret", time.Hour),
+	}
+
+	for _, car := range []*models.CarsItem{
+		{CarName: "Toyota Avanza", DayRate: 300000, MonthRate: 7000000},
+		{CarName: "Honda Jazz", DayRate: 250000, MonthRate: 6000000},
+		{CarName: "Toyota Innova", DayRate: 450000, MonthRate: 10000000},
+	} {
+		_, err := s.cars.Create(context.Background(), car)
+		assert.Nil(t, err)
+	}
+
+	return s, mem
+}
+
+func serve(s *Server, method, target, token string, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	w := httptest.NewRecorder()
+	s.RegisterRoutes().ServeHTTP(w, req)
+	return w
+}
+
+func issue(t *testing.T, s *Server, role auth.Role, customerId int) string {
+	token, _, err := s.auth.Issue(1, role, customerId)
+	assert.Nil(t, err)
+	return token
+}
+
+func Test_CarsHandlers(t *testing.T) {
+	s, mem := newTestServer(t)
+
+	w := serve(s, http.MethodGet, "/api/v1/cars?search=toyota&order_by=day_rate&order=desc", "", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var list models.CarsResponseList
+	assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &list))
+	assert.Equal(t, 2, *list.Total)
+	assert.Equal(t, "Toyota Innova", list.Items[0].CarName)
+	assert.Equal(t, "Toyota Avanza", list.Items[1].CarName)
+
+	w = serve(s, http.MethodGet, "/api/v1/cars?sort=-month_rate&day_rate[lt]=400000", "", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &list))
+	assert.Equal(t, 2, *list.Total)
+	assert.Equal(t, "Toyota Avanza", list.Items[0].CarName)
+	assert.Equal(t, "month_rate", list.OrderBy)
+	assert.Equal(t, "DESC", list.Order)
+
+	w = serve(s, http.MethodGet, "/api/v1/cars?order_by=car_name%3BDROP%20TABLE%20cars", "", "")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	// keyset paging hands out cursors instead of counting
+	w = serve(s, http.MethodGet, "/api/v1/cars?paging=cursor&limit=2&sort=car_name", "", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	list = models.CarsResponseList{}
+	assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &list))
+	assert.Nil(t, list.Total)
+	assert.Len(t, list.Items, 2)
+	assert.NotEmpty(t, list.NextCursor)
+
+	w = serve(s, http.MethodGet, "/api/v1/cars?limit=2&sort=car_name&include_total=true&cursor="+list.NextCursor, "", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	list = models.CarsResponseList{}
+	assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &list))
+	assert.Equal(t, 3, *list.Total)
+	assert.Len(t, list.Items, 1)
+	assert.Equal(t, "Toyota Innova", list.Items[0].CarName)
+	assert.Empty(t, list.NextCursor)
+
+	w = serve(s, http.MethodGet, "/api/v1/cars?sort=-day_rate&cursor="+list.PrevCursor, "", "")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	// a reserved order takes the Jazz out of the available list
+	mem.AddOrder(models.OrdersItem{CarId: 2, PickupDate: "2024-03-01", DropoffDate: "2024-03-05", Status: "reserved"})
+	w = serve(s, http.MethodGet, "/api/v1/cars/available?pickup_date=2024-03-04&dropoff_date=2024-03-06", "", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &list))
+	assert.Equal(t, 2, *list.Total)
+	for _, car := range list.Items {
+		assert.NotEqual(t, 2, car.Id)
+	}
+
+	w = serve(s, http.MethodGet, "/api/v1/cars/2", "", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w = serve(s, http.MethodGet, "/api/v1/cars/99", "", "")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	admin := issue(t, s, auth.RoleAdmin, 0)
+	w = serve(s, http.MethodPut, "/api/v1/cars/1", admin, `{"day_rate": "320000"}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	car, err := s.cars.Get(context.Background(), 1)
+	assert.Nil(t, err)
+	assert.Equal(t, 320000.0, car.DayRate)
+	assert.Equal(t, "Toyota Avanza", car.CarName)
+
+	w = serve(s, http.MethodPut, "/api/v1/cars/1", admin, `{"day_rate": "cheap"}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = serve(s, http.MethodPut, "/api/v1/cars/1", admin, `{"current_location_id": "2"}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	w = serve(s, http.MethodGet, "/api/v1/cars?current_location_id[eq]=2", "", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	list = models.CarsResponseList{}
+	assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &list))
+	assert.Len(t, list.Items, 1)
+	assert.Equal(t, 1, list.Items[0].Id)
+
+	w = serve(s, http.MethodPut, "/api/v1/cars/1", admin, `{"home_location_id": "north"}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = serve(s, http.MethodDelete, "/api/v1/cars/3", admin, "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	w = serve(s, http.MethodDelete, "/api/v1/cars/3", admin, "")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func Test_CarsAvailableTurnaround(t *testing.T) {
+	s, mem := newTestServer(t)
+	s.turnaround = 30 * time.Minute
+
+	mem.AddOrder(models.OrdersItem{CarId: 2, PickupDate: "2024-03-01T09:00:00Z", DropoffDate: "2024-03-05T10:00:00Z", Status: "reserved"})
+
+	available := func(pickup string) bool {
+		w := serve(s, http.MethodGet, "/api/v1/cars/available?pickup_date="+pickup+"&dropoff_date=2024-03-06T10:00:00Z", "", "")
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		var list models.CarsResponseList
+		assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &list))
+		for _, car := range list.Items {
+			if car.Id == 2 {
+				return true
+			}
+		}
+		return false
+	}
+
+	assert.False(t, available("2024-03-05T10:00:00Z"))
+	assert.False(t, available("2024-03-05T10:29:00Z"))
+	assert.True(t, available("2024-03-05T10:30:00Z"))
+
+	// a car dropped off on a day can be picked up that day
+	mem.AddOrder(models.OrdersItem{CarId: 3, PickupDate: "2024-04-01", DropoffDate: "2024-04-05", Status: "reserved"})
+	w := serve(s, http.MethodGet, "/api/v1/cars/available?pickup_date=2024-04-05&dropoff_date=2024-04-07", "", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	var list models.CarsResponseList
+	assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &list))
+	ids := []int{}
+	for _, car := range list.Items {
+		ids = append(ids, car.Id)
+	}
+	assert.Contains(t, ids, 3)
+
+	admin := issue(t, s, auth.RoleAdmin, 0)
+	w = serve(s, http.MethodPut, "/api/v1/cars/2", admin, `{"hour_rate": "20000"}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	car, err := s.cars.Get(context.Background(), 2)
+	assert.Nil(t, err)
+	assert.Equal(t, 20000.0, car.HourRate)
+
+	w = serve(s, http.MethodPut, "/api/v1/cars/2", admin, `{"hour_rate": "-1"}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func Test_OrdersHandlers(t *testing.T) {
+	s, mem := newTestServer(t)
+
+	mine := mem.AddOrder(models.OrdersItem{CarId: 1, CustomerId: 7, PickupLocation: "Jakarta", OrderDate: "2024-02-01", Status: "reserved"})
+	theirs := mem.AddOrder(models.OrdersItem{CarId: 2, CustomerId: 8, PickupLocation: "Bandung", OrderDate: "2024-02-02", Status: "reserved"})
+
+	w := serve(s, http.MethodGet, "/api/v1/orders", "", "")
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	// customers only see their own orders, whatever they ask for
+	customer := issue(t, s, auth.RoleCustomer, 7)
+	w = serve(s, http.MethodGet, "/api/v1/orders?customer_id=8", customer, "")
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var list models.OrdersResponseList
+	assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &list))
+	assert.Equal(t, 1, *list.Total)
+	assert.Equal(t, mine, list.Items[0].Id)
+	assert.Equal(t, "Toyota Avanza", list.Items[0].CarName)
+
+	w = serve(s, http.MethodGet, "/api/v1/orders/"+strconv.Itoa(theirs), customer, "")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	staff := issue(t, s, auth.RoleStaff, 0)
+	w = serve(s, http.MethodGet, "/api/v1/orders?search=band", staff, "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &list))
+	assert.Equal(t, 1, *list.Total)
+	assert.Equal(t, theirs, list.Items[0].Id)
+
+	w = serve(s, http.MethodGet, "/api/v1/orders?search_by=car_name&search=jazz", staff, "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &list))
+	assert.Equal(t, 1, *list.Total)
+	assert.Equal(t, theirs, list.Items[0].Id)
+
+	// any matches word prefixes across the car, the places and the customer
+	w = serve(s, http.MethodGet, "/api/v1/orders?search_by=any&search=toy%20jak", staff, "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &list))
+	assert.Equal(t, 1, *list.Total)
+	assert.Equal(t, mine, list.Items[0].Id)
+
+	w = serve(s, http.MethodGet, "/api/v1/orders?search_by=status&search=x", staff, "")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = serve(s, http.MethodDelete, "/api/v1/orders/"+strconv.Itoa(theirs), customer, "")
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	w = serve(s, http.MethodDelete, "/api/v1/orders/"+strconv.Itoa(theirs), staff, "")
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w = serve(s, http.MethodGet, "/api/v1/orders/"+strconv.Itoa(theirs), staff, "")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func Test_OrdersBooking(t *testing.T) {
+	s, mem := newTestServer(t)
+	ctx := context.Background()
+
+	jakarta, err := s.locations.Create(ctx, &models.LocationsItem{Name: "Jakarta", Timezone: "UTC"})
+	assert.Nil(t, err)
+	bandung, err := s.locations.Create(ctx, &models.LocationsItem{Name: "Bandung", Timezone: "UTC"})
+	assert.Nil(t, err)
+	customerId, err := s.customers.Create(ctx, &models.CustomersItem{Name: "Budi", LicenceExpiry: "2030-01-01"})
+	assert.Nil(t, err)
+	expiredId, err := s.customers.Create(ctx, &models.CustomersItem{Name: "Sari", LicenceExpiry: "2024-05-02"})
+	assert.Nil(t, err)
+
+	staff := issue(t, s, auth.RoleStaff, 0)
+	book := func(carId, customerId int, pickup, dropoff, from string) *httptest.ResponseRecorder {
+		body := fmt.Sprintf(`{"car_id": "%d", "customer_id": "%d", "order_date": "2024-04-20", "pickup_date": %q, "dropoff_date": %q, "pickup_location": %q, "dropoff_location_id": "%d"}`, carId, customerId, pickup, dropoff, from, bandung)
+		return serve(s, http.MethodPost, "/api/v1/orders", staff, body)
+	}
+
+	w := book(1, customerId, "2024-05-01", "2024-05-03", "jakarta")
+	assert.Equal(t, http.StatusOK, w.Code)
+	var created models.ResponseGeneral
+	assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &created))
+
+	w = serve(s, http.MethodGet, "/api/v1/orders/"+strconv.Itoa(created.Id), staff, "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	var got models.OrdersResponseGet
+	assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &got))
+	assert.Equal(t, "reserved", got.Item.Status)
+	assert.Equal(t, "Budi", got.Item.CustomerName)
+	assert.Equal(t, jakarta, got.Item.PickupLocationId)
+	assert.Equal(t, "Bandung", got.Item.DropoffLocation)
+	assert.Equal(t, float64(600000), got.Item.TotalPrice)
+
+	// the car is taken, and after the order it stands in Bandung
+	w = book(1, customerId, "2024-05-02", "2024-05-04", "jakarta")
+	assert.Equal(t, http.StatusConflict, w.Code)
+	assert.Contains(t, w.Body.String(), "car-already-occupied")
+
+	w = book(1, customerId, "2024-05-10", "2024-05-12", "jakarta")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "car-not-at-pickup-location")
+
+	w = serve(s, http.MethodGet, "/api/v1/check-occupied-cars/1/2024-05-02?dropoff_date=2024-05-04", "", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	var check models.OrdersResponseCheckOccupied
+	assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &check))
+	assert.Equal(t, "car-already-occupied", check.Message)
+	assert.Equal(t, created.Id, check.Conflicts[0].Id)
+	assert.Equal(t, "2024-05-01T00:00:00Z", check.Conflicts[0].PickupDate)
+	// anyone may check, who booked the car and for how much stays hidden
+	assert.NotContains(t, w.Body.String(), "Budi")
+	assert.NotContains(t, w.Body.String(), "total_price")
+
+	mem.AddBlock(models.CarBlocksItem{CarId: 2, StartDate: "2024-05-01T00:00:00Z", EndDate: "2024-05-05T00:00:00Z", Type: "repair"})
+	w = book(2, customerId, "2024-05-03", "2024-05-06", "jakarta")
+	assert.Equal(t, http.StatusConflict, w.Code)
+	assert.Contains(t, w.Body.String(), "car-blocked")
+
+	w = book(3, expiredId, "2024-05-01", "2024-05-03", "jakarta")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "customer-licence-expired")
+
+	w = book(3, customerId, "2024-05-01", "2024-05-03", "Surabaya")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	// nothing was stored by the refused bookings
+	w = serve(s, http.MethodGet, "/api/v1/orders", staff, "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	var list models.OrdersResponseList
+	assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &list))
+	assert.Equal(t, 1, *list.Total)
+
+	// moving the order a day on is checked and priced again
+	w = serve(s, http.MethodPut, "/api/v1/orders/"+strconv.Itoa(created.Id), staff, `{"dropoff_date": "2024-05-04"}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	w = serve(s, http.MethodGet, "/api/v1/orders/"+strconv.Itoa(created.Id), staff, "")
+	assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &got))
+	assert.Equal(t, float64(900000), got.Item.TotalPrice)
+	assert.Equal(t, "2024-05-04T00:00:00Z", got.Item.DropoffDate)
+}
+
+func Test_OrdersBackToBack(t *testing.T) {
+	s, _ := newTestServer(t)
+	s.turnaround = 30 * time.Minute
+	ctx := context.Background()
+
+	jakarta, err := s.locations.Create(ctx, &models.LocationsItem{Name: "Jakarta", Timezone: "UTC"})
+	assert.Nil(t, err)
+	customerId, err := s.customers.Create(ctx, &models.CustomersItem{Name: "Budi", LicenceExpiry: "2030-01-01"})
+	assert.Nil(t, err)
+
+	staff := issue(t, s, auth.RoleStaff, 0)
+	book := func(carId int, pickup, dropoff string) *httptest.ResponseRecorder {
+		body := fmt.Sprintf(`{"car_id": "%d", "customer_id": "%d", "order_date": "2024-03-20", "pickup_date": %q, "dropoff_date": %q, "pickup_location_id": "%d", "dropoff_location_id": "%d"}`, carId, customerId, pickup, dropoff, jakarta, jakarta)
+		return serve(s, http.MethodPost, "/api/v1/orders", staff, body)
+	}
+
+	// whole day rentals take no turnaround, the car goes out again the day
+	// it comes back
+	w := book(1, "2024-04-01", "2024-04-05")
+	assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	w = book(1, "2024-04-05", "2024-04-07")
+	assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	w = book(1, "2024-03-30", "2024-04-01")
+	assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	// so does the check, also when it defaults the dropoff to the next day
+	var check models.OrdersResponseCheckOccupied
+	w = serve(s, http.MethodGet, "/api/v1/check-occupied-cars/1/2024-04-07", "", "")
+	assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &check))
+	assert.Equal(t, "car-available", check.Message)
+	w = serve(s, http.MethodGet, "/api/v1/check-occupied-cars/1/2024-04-07T00:15:00Z", "", "")
+	assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &check))
+	assert.Equal(t, "car-already-occupied", check.Message)
+
+	// timed ones keep it free for the turnaround
+	w = book(2, "2024-04-01T10:00:00Z", "2024-04-01T12:00:00Z")
+	assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	w = book(2, "2024-04-01T12:15:00Z", "2024-04-01T14:00:00Z")
+	assert.Equal(t, http.StatusConflict, w.Code)
+	w = book(2, "2024-04-01T12:30:00Z", "2024-04-01T14:00:00Z")
+	assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+}
+
+func Test_OrdersTransitions(t *testing.T) {
+	s, mem := newTestServer(t)
+	ctx := context.Background()
+
+	bandung, err := s.locations.Create(ctx, &models.LocationsItem{Name: "Bandung"})
+	assert.Nil(t, err)
+
+	staff := issue(t, s, auth.RoleStaff, 0)
+	transition := func(id int, action, token string) *httptest.ResponseRecorder {
+		return serve(s, http.MethodPost, fmt.Sprintf("/api/v1/orders/%d/%s", id, action), token, "")
+	}
+	status := func(id int) string {
+		order, err := s.orders.Get(ctx, id)
+		assert.Nil(t, err)
+		return order.Status
+	}
+
+	rental := mem.AddOrder(models.OrdersItem{CarId: 1, CustomerId: 7, Status: "reserved", DropoffLocationId: bandung})
+	w := transition(rental, "return", staff)
+	assert.Equal(t, http.StatusConflict, w.Code)
+	assert.Contains(t, w.Body.String(), "cannot-move-from-reserved-to-returned")
+
+	w = transition(rental, "pickup", staff)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "picked_up", status(rental))
+
+	w = transition(rental, "return", staff)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "returned", status(rental))
+
+	// the car now stands where it was dropped off
+	car, err := s.cars.Get(ctx, 1)
+	assert.Nil(t, err)
+	assert.Equal(t, bandung, car.CurrentLocationId)
+
+	cancelled := mem.AddOrder(models.OrdersItem{CarId: 2, CustomerId: 7, Status: "reserved"})
+	w = transition(cancelled, "cancel", issue(t, s, auth.RoleCustomer, 8))
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	w = transition(cancelled, "cancel", issue(t, s, auth.RoleCustomer, 7))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "cancelled", status(cancelled))
+
+	w = transition(cancelled, "pickup", staff)
+	assert.Equal(t, http.StatusConflict, w.Code)
+
+	missed := mem.AddOrder(models.OrdersItem{CarId: 3, Status: "reserved"})
+	w = transition(missed, "no-show", staff)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "no_show", status(missed))
+
+	w = transition(99, "pickup", staff)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func Test_UsersHandlers(t *testing.T) {
+	s, _ := newTestServer(t)
+	ctx := context.Background()
+
+	// a fresh install gets its admin, and only once
+	s.bootstrapAdmin("Admin@Example.com", "admin-password")
+	s.bootstrapAdmin("other@example.com", "other-password")
+	users, err := s.users.Count(ctx)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, users)
+
+	w := serve(s, http.MethodPost, "/api/v1/auth/login", "", `{"email": "admin@example.com", "password": "admin-password"}`)
+	assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var login models.UsersResponseLogin
+	assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &login))
+	assert.Equal(t, "admin", login.Role)
+	admin := login.Token
+
+	customerId, err := s.customers.Create(ctx, &models.CustomersItem{Name: "Jane", Email: "jane@example.com", LicenceExpiry: "2030-01-01"})
+	assert.Nil(t, err)
+
+	body := fmt.Sprintf(`{"email": "Jane@Example.com", "password": "jane-password", "role": "customer", "customer_id": "%d"}`, customerId)
+	w = serve(s, http.MethodPost, "/api/v1/users", admin, body)
+	assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	w = serve(s, http.MethodPost, "/api/v1/users", admin, `{"email": "jane@example.com", "password": "another-password", "role": "staff"}`)
+	assert.Equal(t, http.StatusConflict, w.Code)
+	assert.Contains(t, w.Body.String(), "email-already-registered")
+
+	w = serve(s, http.MethodPost, "/api/v1/users", admin, `{"email": "ghost@example.com", "password": "ghost-password", "role": "customer", "customer_id": "99"}`)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.Contains(t, w.Body.String(), "customer-not-found")
+
+	for _, body := range []string{
+		`{"email": "jane@example.com", "password": "wrong-password"}`,
+		`{"email": "nobody@example.com", "password": "jane-password"}`,
+	} {
+		w = serve(s, http.MethodPost, "/api/v1/auth/login", "", body)
+		assert.Equal(t, http.StatusUnauthorized, w.Code, body)
+		assert.Contains(t, w.Body.String(), "invalid-credentials")
+	}
+
+	// the customer token is scoped to their own customer
+	w = serve(s, http.MethodPost, "/api/v1/auth/login", "", `{"email": "JANE@example.com", "password": "jane-password"}`)
+	assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	login = models.UsersResponseLogin{}
+	assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &login))
+	claims, err := s.auth.Verify(login.Token)
+	assert.Nil(t, err)
+	assert.Equal(t, auth.RoleCustomer, claims.Role)
+	assert.Equal(t, customerId, claims.CustomerId)
+}
+
+func Test_CustomersHandlers(t *testing.T) {
+	s, mem := newTestServer(t)
+	staff := issue(t, s, auth.RoleStaff, 0)
+
+	for _, email := range []string{"budi@", "@example.com", "budi example.com", "Budi <budi@example.com>"} {
+		body := fmt.Sprintf(`{"name": "Budi", "email": %q, "phone": "0812", "licence_number": "SIM-1", "licence_expiry": "2030-01-01"}`, email)
+		w := serve(s, http.MethodPost, "/api/v1/customers", staff, body)
+		assert.Equal(t, http.StatusBadRequest, w.Code, email)
+	}
+
+	w := serve(s, http.MethodPost, "/api/v1/customers", staff, `{"name": "Budi", "email": "Budi@Example.com", "phone": "0812", "licence_number": "SIM-1", "licence_expiry": "2030-01-01"}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	var created models.ResponseGeneral
+	assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &created))
+
+	w = serve(s, http.MethodPost, "/api/v1/customers", staff, `{"name": "Sari", "email": "sari@example.com", "phone": "0813", "licence_number": "SIM-2", "licence_expiry": "2031-01-01"}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	// without search_by the email is searched as well as the name
+	w = serve(s, http.MethodGet, "/api/v1/customers?search=budi@", staff, "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	var list models.CustomersResponseList
+	assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &list))
+	assert.Equal(t, 1, list.Total)
+	assert.Equal(t, "budi@example.com", list.Items[0].Email)
+
+	w = serve(s, http.MethodGet, "/api/v1/customers?licence_expiry[gte]=2031-01-01", staff, "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &list))
+	assert.Equal(t, 1, list.Total)
+	assert.Equal(t, "Sari", list.Items[0].Name)
+
+	target := "/api/v1/customers/" + strconv.Itoa(created.Id)
+	w = serve(s, http.MethodPut, target, staff, `{"email": "budi"}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = serve(s, http.MethodPut, target, staff, `{"phone": "0899"}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w = serve(s, http.MethodGet, target, staff, "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	var got models.CustomersResponseGet
+	assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &got))
+	assert.Equal(t, "0899", got.Item.Phone)
+	assert.Equal(t, "Budi", got.Item.Name)
+
+	w = serve(s, http.MethodPut, "/api/v1/customers/99", staff, `{"phone": "0899"}`)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	// customers with orders are kept for them
+	mem.AddOrder(models.OrdersItem{CarId: 1, CustomerId: created.Id, Status: "returned"})
+	w = serve(s, http.MethodDelete, target, staff, "")
+	assert.Equal(t, http.StatusConflict, w.Code)
+
+	w = serve(s, http.MethodGet, "/api/v1/orders?customer_id="+strconv.Itoa(created.Id), staff, "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	var orders models.OrdersResponseList
+	assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &orders))
+	assert.Equal(t, "Budi", orders.Items[0].CustomerName)
+
+	w = serve(s, http.MethodDelete, "/api/v1/customers/99", staff, "")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func Test_LocationsHandlers(t *testing.T) {
+	s, _ := newTestServer(t)
+	admin := issue(t, s, auth.RoleAdmin, 0)
+
+	// rejected before anything is stored
+	for _, body := range []string{
+		`{"address": "Jl. Sudirman 1"}`,
+		`{"name": "Jakarta", "timezone": "Mars/Olympus"}`,
+		`{"name": "Jakarta", "latitude": "-96.2", "longitude": "106.8"}`,
+		`{"name": "Jakarta", "opening_hours": {"monday": "08:00-18:00"}}`,
+		`{"name": "Jakarta", "opening_hours": {"mon": "8am-6pm"}}`,
+	} {
+		w := serve(s, http.MethodPost, "/api/v1/locations", admin, body)
+		assert.Equal(t, http.StatusBadRequest, w.Code, body)
+	}
+
+	w := serve(s, http.MethodPost, "/api/v1/locations", issue(t, s, auth.RoleStaff, 0), `{"name": "Jakarta"}`)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	w = serve(s, http.MethodPut, "/api/v1/locations/1", admin, `{}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = serve(s, http.MethodGet, "/api/v1/locations?paging=cursor", "", "")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = serve(s, http.MethodPost, "/api/v1/locations", admin, `{"name": " Jakarta ", "address": "Jl. Sudirman 1", "latitude": "-6.2", "longitude": "106.8", "opening_hours": {"mon": "08:00-18:00"}}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	var created models.ResponseGeneral
+	assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &created))
+
+	w = serve(s, http.MethodPost, "/api/v1/locations", admin, `{"name": "Bandung", "address": "Jl. Asia Afrika 8"}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	// any looks in the address too
+	w = serve(s, http.MethodGet, "/api/v1/locations?search_by=any&search=sudirman", "", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	var list models.LocationsResponseList
+	assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &list))
+	assert.Equal(t, 1, list.Total)
+	assert.Equal(t, "Jakarta", list.Items[0].Name)
+	assert.Equal(t, "UTC", list.Items[0].Timezone)
+
+	target := "/api/v1/locations/" + strconv.Itoa(created.Id)
+	w = serve(s, http.MethodPut, target, admin, `{"timezone": "Asia/Jakarta", "opening_hours": {}}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w = serve(s, http.MethodGet, target, "", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	var got models.LocationsResponseGet
+	assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &got))
+	assert.Equal(t, "Asia/Jakarta", got.Item.Timezone)
+	assert.Empty(t, got.Item.OpeningHours)
+	assert.Equal(t, -6.2, *got.Item.Latitude)
+
+	w = serve(s, http.MethodPut, "/api/v1/locations/99", admin, `{"address": "Jl. Braga 2"}`)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	// a branch cars stand at stays
+	w = serve(s, http.MethodPut, "/api/v1/cars/1", admin, fmt.Sprintf(`{"current_location_id": "%d"}`, created.Id))
+	assert.Equal(t, http.StatusOK, w.Code)
+	w = serve(s, http.MethodDelete, target, admin, "")
+	assert.Equal(t, http.StatusConflict, w.Code)
+
+	w = serve(s, http.MethodDelete, "/api/v1/locations/"+strconv.Itoa(created.Id+1), admin, "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	w = serve(s, http.MethodGet, "/api/v1/locations/"+strconv.Itoa(created.Id+1), "", "")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func Test_CarsImageUpload(t *testing.T) {
+	s, _ := newTestServer(t)
+	local, err := storage.NewLocalStore(t.TempDir(), "http://localhost:8080/uploads")
+	assert.Nil(t, err)
+	s.images = local
+	admin := issue(t, s, auth.RoleAdmin, 0)
+
+	upload := func(content []byte) *httptest.ResponseRecorder {
+		var body bytes.Buffer
+		form := multipart.NewWriter(&body)
+		part, err := form.CreateFormFile("image", "car.png")
+		assert.Nil(t, err)
+		_, err = part.Write(content)
+		assert.Nil(t, err)
+		assert.Nil(t, form.Close())
+
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/cars/1/image", &body)
+		req.Header.Set("Content-Type", form.FormDataContentType())
+		req.Header.Set("Authorization", "Bearer "+admin)
+		w := httptest.NewRecorder()
+		s.RegisterRoutes().ServeHTTP(w, req)
+		return w
+	}
+
+	w := upload(nil)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "empty-image")
+
+	w = upload([]byte("not an image"))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "unsupported-image-type")
+
+	w = upload([]byte("\x89PNG\r\n\x1a\n"))
+	assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	car, err := s.cars.Get(context.Background(), 1)
+	assert.Nil(t, err)
+	assert.True(t, strings.HasPrefix(car.Image, "http://localhost:8080/uploads/"), car.Image)
+}
+
+func Test_CarBlocksHandlers(t *testing.T) {
+	s, mem := newTestServer(t)
+
+	// the Innova is in the workshop for the first week of March
+	mem.AddBlock(models.CarBlocksItem{CarId: 3, StartDate: "2024-03-01T08:00:00Z", EndDate: "2024-03-08T08:00:00Z", Type: "repair"})
+
+	w := serve(s, http.MethodGet, "/api/v1/cars/available?pickup_date=2024-03-07&dropoff_date=2024-03-10", "", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	var list models.CarsResponseList
+	assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &list))
+	assert.Equal(t, 2, *list.Total)
+	for _, car := range list.Items {
+		assert.NotEqual(t, 3, car.Id)
+	}
+
+	w = serve(s, http.MethodGet, "/api/v1/cars/available?pickup_date=2024-03-08T08:00:00Z&dropoff_date=2024-03-10", "", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	list = models.CarsResponseList{}
+	assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &list))
+	assert.Equal(t, 3, *list.Total)
+
+	staff := issue(t, s, auth.RoleStaff, 0)
+	for _, body := range []string{
+		`{"end_date": "2024-03-08"}`,
+		`{"start_date": "2024-03-08", "end_date": "2024-03-01"}`,
+		`{"start_date": "2024-03-01", "end_date": "2024-03-08", "type": "holiday"}`,
+	} {
+		w = serve(s, http.MethodPost, "/api/v1/cars/3/blocks", staff, body)
+		assert.Equal(t, http.StatusBadRequest, w.Code, body)
+	}
+
+	w = serve(s, http.MethodGet, "/api/v1/cars/99/blocks", staff, "")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	w = serve(s, http.MethodPost, "/api/v1/cars/3/blocks", issue(t, s, auth.RoleCustomer, 1), `{"start_date": "2024-03-01", "end_date": "2024-03-08"}`)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	// the Jazz goes in for an inspection while an order still holds it
+	orderId := mem.AddOrder(models.OrdersItem{CarId: 2, PickupDate: "2024-04-01", DropoffDate: "2024-04-05", Status: "reserved"})
+	w = serve(s, http.MethodPost, "/api/v1/cars/2/blocks", staff, `{"start_date": "2024-04-04", "end_date": "2024-04-06", "type": "inspection", "reason": "brakes"}`)
+	assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var created models.CarBlocksResponseCreate
+	assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &created))
+	assert.Len(t, created.Overlapping, 1)
+	assert.Equal(t, orderId, created.Overlapping[0].Id)
+
+	w = serve(s, http.MethodPost, "/api/v1/cars/99/blocks", staff, `{"start_date": "2024-04-04", "end_date": "2024-04-06"}`)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	w = serve(s, http.MethodGet, "/api/v1/cars/2/blocks", staff, "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	var blocks models.CarBlocksResponseList
+	assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &blocks))
+	assert.Len(t, blocks.Items, 1)
+	assert.Equal(t, created.Id, blocks.Items[0].Id)
+	assert.Equal(t, "inspection", blocks.Items[0].Type)
+	assert.Equal(t, "brakes", blocks.Items[0].Reason)
+
+	// blocks are only removed through their own car
+	w = serve(s, http.MethodDelete, fmt.Sprintf("/api/v1/cars/3/blocks/%d", created.Id), staff, "")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	w = serve(s, http.MethodDelete, fmt.Sprintf("/api/v1/cars/2/blocks/%d", created.Id), staff, "")
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w = serve(s, http.MethodGet, "/api/v1/cars/2/blocks", staff, "")
+	blocks = models.CarBlocksResponseList{}
+	assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &blocks))
+	assert.Empty(t, blocks.Items)
+}
+
+func Test_CarsCatalog(t *testing.T) {
+	s, _ := newTestServer(t)
+	admin := issue(t, s, auth.RoleAdmin, 0)
+
+	for _, body := range []string{
+		`{"car_name": "Toyota Fortuner", "day_rate": "700000", "month_rate": "15000000", "make": "Toyota", "model": "Fortuner", "year": "2022", "category": "SUV", "seats": "7", "transmission": "automatic", "fuel_type": "diesel", "plate_number": "b 1234  xyz", "features": ["GPS", "bluetooth", "gps"]}`,
+		`{"car_name": "Mitsubishi Pajero", "day_rate": "750000", "month_rate": "16000000", "make": "Mitsubishi", "category": "suv", "seats": "7", "transmission": "manual", "features": ["gps"]}`,
+	} {
+		w := serve(s, http.MethodPost, "/api/v1/cars", admin, body)
+		assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	}
+
+	car, err := s.cars.Get(context.Background(), 4)
+	assert.Nil(t, err)
+	assert.Equal(t, "suv", car.Category)
+	assert.Equal(t, "B 1234 XYZ", car.PlateNumber)
+	assert.Equal(t, []string{"bluetooth", "gps"}, car.Features)
+
+	for _, body := range []string{
+		`{"car_name": "Tank", "day_rate": "1", "month_rate": "1", "category": "tank"}`,
+		`{"car_name": "Tank", "day_rate": "1", "month_rate": "1", "year": "1850"}`,
+		`{"car_name": "Tank", "day_rate": "1", "month_rate": "1", "seats": "many"}`,
+		`{"car_name": "Tank", "day_rate": "-1", "month_rate": "1"}`,
+		`{"car_name": "Tank", "day_rate": "1", "month_rate": "Inf"}`,
+		`{"car_name": "Tank", "day_rate": "NaN", "month_rate": "1"}`,
+	} {
+		w := serve(s, http.MethodPost, "/api/v1/cars", admin, body)
+		assert.Equal(t, http.StatusBadRequest, w.Code, body)
+	}
+
+	for _, body := range []string{`{"day_rate": "-300000"}`, `{"month_rate": "+Inf"}`} {
+		w := serve(s, http.MethodPut, "/api/v1/cars/4", admin, body)
+		assert.Equal(t, http.StatusBadRequest, w.Code, body)
+	}
+
+	w := serve(s, http.MethodPut, "/api/v1/cars/5", admin, `{"features": []}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	car, err = s.cars.Get(context.Background(), 5)
+	assert.Nil(t, err)
+	assert.Empty(t, car.Features)
+	assert.Equal(t, "manual", car.Transmission)
+
+	// the category facet still counts what the other categories hold
+	w = serve(s, http.MethodGet, "/api/v1/cars?category=suv&transmission[eq]=automatic", "", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	var list models.CarsResponseList
+	assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &list))
+	assert.Equal(t, 1, *list.Total)
+	assert.Equal(t, "Toyota Fortuner", list.Items[0].CarName)
+	assert.Equal(t, map[string]int{"suv": 1}, list.Facets["category"])
+	assert.Equal(t, map[string]int{"automatic": 1, "manual": 1}, list.Facets["transmission"])
+	assert.Equal(t, map[string]int{"bluetooth": 1, "gps": 1}, list.Facets["features"])
+
+	w = serve(s, http.MethodGet, "/api/v1/cars?features=gps,bluetooth", "", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	list = models.CarsResponseList{}
+	assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &list))
+	assert.Equal(t, 1, *list.Total)
+
+	w = serve(s, http.MethodGet, "/api/v1/cars?paging=cursor", "", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	list = models.CarsResponseList{}
+	assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &list))
+	assert.Nil(t, list.Facets)
+}
+
+func Test_SoftDelete(t *testing.T) {
+	s, mem := newTestServer(t)
+	admin := issue(t, s, auth.RoleAdmin, 0)
+
+	tomorrow := time.Now().AddDate(0, 0, 1).Format("2006-01-02")
+	mem.AddOrder(models.OrdersItem{CarId: 1, PickupDate: "2024-03-01", DropoffDate: tomorrow, Status: "picked_up"})
+	returned := mem.AddOrder(models.OrdersItem{CarId: 2, PickupDate: "2024-03-01", DropoffDate: "2024-03-03", Status: "returned"})
+
+	w := serve(s, http.MethodDelete, "/api/v1/cars/1", admin, "")
+	assert.Equal(t, http.StatusConflict, w.Code)
+	assert.Contains(t, w.Body.String(), "car-has-active-orders")
+
+	w = serve(s, http.MethodDelete, "/api/v1/cars/2", admin, "")
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w = serve(s, http.MethodGet, "/api/v1/cars/2", "", "")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	// the orders of a deleted car are still listed
+	w = serve(s, http.MethodGet, "/api/v1/orders/"+strconv.Itoa(returned), admin, "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), "Honda Jazz")
+
+	var cars models.CarsResponseList
+	w = serve(s, http.MethodGet, "/api/v1/cars/deleted", admin, "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &cars))
+	assert.Len(t, cars.Items, 1)
+	assert.Equal(t, 2, cars.Items[0].Id)
+	assert.NotEmpty(t, cars.Items[0].DeletedAt)
+
+	w = serve(s, http.MethodGet, "/api/v1/cars/deleted", issue(t, s, auth.RoleStaff, 0), "")
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	// an order of a deleted car only comes back with its car
+	w = serve(s, http.MethodDelete, "/api/v1/orders/"+strconv.Itoa(returned), admin, "")
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w = serve(s, http.MethodPost, "/api/v1/orders/"+strconv.Itoa(returned)+"/restore", admin, "")
+	assert.Equal(t, http.StatusConflict, w.Code)
+	assert.Contains(t, w.Body.String(), "car-deleted")
+
+	w = serve(s, http.MethodPost, "/api/v1/cars/2/restore", admin, "")
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w = serve(s, http.MethodPost, "/api/v1/cars/2/restore", admin, "")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	var orders models.OrdersResponseList
+	w = serve(s, http.MethodGet, "/api/v1/orders/deleted", admin, "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &orders))
+	assert.Len(t, orders.Items, 1)
+
+	w = serve(s, http.MethodPost, "/api/v1/orders/"+strconv.Itoa(returned)+"/restore", admin, "")
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w = serve(s, http.MethodGet, "/api/v1/cars/2", "", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w = serve(s, http.MethodGet, "/api/v1/orders/"+strconv.Itoa(returned), admin, "")
+	assert.Equal(t, http.StatusOK, w.Code)
+}
+
+// healthDB answers Health with health, the rest of database.Service is not
+// used by the health checks.
+type healthDB struct {
+	database.Service
+	health models.DatabaseHealth
+}
+
+func (db healthDB) Health(ctx context.Context) models.DatabaseHealth {
+	return db.health
+}
+
+func Test_HealthHandlers(t *testing.T) {
+	s, _ := newTestServer(t)
+	s.db = healthDB{health: models.DatabaseHealth{Status: "up", MigrationVersion: 12}}
+
+	w := serve(s, http.MethodGet, "/healthz", "", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var resp models.HealthResponse
+	assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, "ok", resp.Status)
+	assert.Nil(t, resp.Database)
+	assert.NotEmpty(t, resp.Build.Version)
+
+	w = serve(s, http.MethodGet, "/readyz", "", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, "ready", resp.Status)
+	assert.Equal(t, 12, resp.Database.MigrationVersion)
+
+	// a database that is down makes the instance not ready, the process lives on
+	s.db = healthDB{health: models.DatabaseHealth{Status: "down", Error: "database-unreachable"}}
+	w = serve(s, http.MethodGet, "/readyz", "", "")
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, "not-ready", resp.Status)
+	assert.Equal(t, "database-unreachable", resp.Database.Error)
+
+	w = serve(s, http.MethodGet, "/healthz", "", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+}
+
+func Test_PanicEnvelope(t *testing.T) {
+	s, _ := newTestServer(t)
+	// the embedded repository is nil, any call panics
+	s.cars = struct{ repository.CarRepository }{}
+
+	w := serve(s, http.MethodGet, "/api/v1/cars/1", "", "")
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	assert.JSONEq(t, `{"code": "internal", "message": "internal-error"}`, w.Body.String())
+}
+
+func Test_OpenAPIDocument(t *testing.T) {
+	s, mem := newTestServer(t)
+	// the routes only registered with metrics and local images are covered too
+	s.metrics = metrics.New(func() sql.DBStats { return sql.DBStats{} }, mem.Orders())
+	local, err := storage.NewLocalStore(t.TempDir(), "")
+	assert.Nil(t, err)
+	s.images = local
+
+	w := serve(s, http.MethodGet, "/api/v1/openapi.json", "", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var doc struct {
+		OpenAPI    string                                `json:"openapi"`
+		Paths      map[string]map[string]json.RawMessage `json:"paths"`
+		Components map[string]map[string]json.RawMessage `json:"components"`
+	}
+	assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &doc))
+	assert.Equal(t, "3.0.3", doc.OpenAPI)
+
+	// gin writes /cars/:id and /uploads/*filepath, OpenAPI /cars/{id}
+	param := regexp.MustCompile(`[:*]([^/]+)`)
+	registered := map[string]bool{}
+	for _, route := range s.RegisterRoutes().(*gin.Engine).Routes() {
+		path := param.ReplaceAllString(route.Path, "{$1}")
+		method := strings.ToLower(route.Method)
+		registered[method+" "+path] = true
+		assert.Contains(t, doc.Paths[path], method, "%s %s is missing from the OpenAPI document", route.Method, route.Path)
+	}
+
+	for path, operations := range doc.Paths {
+		for method := range operations {
+			assert.True(t, registered[method+" "+path], "%s %s is documented but not registered", method, path)
+		}
+	}
+
+	// every $ref points at a component that exists
+	refs := regexp.MustCompile(`"\$ref": "#/components/([^/]+)/([^"]+)"`)
+	for _, ref := range refs.FindAllStringSubmatch(w.Body.String(), -1) {
+		assert.Contains(t, doc.Components[ref[1]], ref[2], "%s/%s is referenced but not defined", ref[1], ref[2])
+	}
+
+	w = serve(s, http.MethodGet, "/api/v1/docs", "", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `url: "openapi.json"`)
+}
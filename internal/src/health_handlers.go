@@ -0,0 +1,52 @@
+package src
+
+import (
+	"api/internal/buildinfo"
+	"api/internal/models"
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// readyTimeout bounds the database checks of /readyz, a slow database makes
+// the instance not ready rather than hanging the probe.
+const readyTimeout = 2 * time.Second
+
+// LivenessHandler answers as long as the process serves requests at all.
+func (s *Server) LivenessHandler(c *gin.Context) {
+	c.JSON(http.StatusOK, models.HealthResponse{
+		Status: "ok",
+		Build:  buildinfo.Get(),
+	})
+}
+
+// ReadinessHandler tells whether this instance should get traffic: it is not
+// draining and the database answers with its migrations applied.
+func (s *Server) ReadinessHandler(c *gin.Context) {
+	resp := models.HealthResponse{
+		Status: "ready",
+		Build:  buildinfo.Get(),
+	}
+
+	// stop taking traffic as soon as shutdown starts
+	if s.lifecycle.Draining() {
+		resp.Status = "draining"
+		c.JSON(http.StatusServiceUnavailable, resp)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), readyTimeout)
+	defer cancel()
+
+	health := s.db.Health(ctx)
+	resp.Database = &health
+	if health.Status != "up" {
+		resp.Status = "not-ready"
+		c.JSON(http.StatusServiceUnavailable, resp)
+		return
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
@@ -0,0 +1,80 @@
+package src
+
+import (
+	"api/internal/apperr"
+	"api/internal/models"
+	"api/internal/queryspec"
+	"api/internal/repository"
+
+	"github.com/gin-gonic/gin"
+)
+
+// parseListQuery checks the search_by, sort, filters and paging of a list
+// request against spec. Without a sort the older order_by and order are used,
+// which callers have already defaulted together with page and limit. The
+// leading sort key is echoed back through them.
+func parseListQuery(c *gin.Context, spec *queryspec.Spec, req *models.RequestListsGeneral) (repository.ListQuery, error) {
+	searchBy, err := spec.ParseSearchBy(req.SearchBy)
+	if err != nil {
+		return repository.ListQuery{}, err
+	}
+	req.SearchBy = searchBy
+
+	q, err := spec.Parse(req.Sort, c.Request.URL.Query())
+	if err != nil {
+		return repository.ListQuery{}, err
+	}
+
+	if len(q.Sort) == 0 {
+		q.Sort, err = spec.ParseSort(queryspec.LegacySort(req.OrderBy, req.Order))
+		if err != nil {
+			return repository.ListQuery{}, err
+		}
+	}
+
+	req.OrderBy = q.Sort[0].Field
+	req.Order = "ASC"
+	if q.Sort[0].Desc {
+		req.Order = "DESC"
+	}
+
+	listQuery := repository.ListQuery{
+		Search:   req.Search,
+		SearchBy: req.SearchBy,
+		Filters:  q.Filters,
+		Sort:     q.Sort,
+		Limit:    req.Limit,
+		Offset:   (req.Page - 1) * req.Limit,
+	}
+
+	switch req.Paging {
+	case "", "offset":
+		listQuery.Keyset = req.Cursor != ""
+	case "cursor":
+		listQuery.Keyset = true
+	default:
+		return repository.ListQuery{}, apperr.Validation("invalid-paging", "paging")
+	}
+
+	// counting every row is what keyset paging saves, so it is opt-in there
+	listQuery.CountTotal = !listQuery.Keyset
+	if req.IncludeTotal != nil {
+		listQuery.CountTotal = *req.IncludeTotal
+	}
+
+	if listQuery.Keyset {
+		err = spec.CheckKeyset(spec.WithTiebreak(q.Sort))
+		if err != nil {
+			return repository.ListQuery{}, err
+		}
+	}
+
+	if req.Cursor != "" {
+		listQuery.Cursor, err = spec.DecodeCursor(q.Sort, req.Cursor)
+		if err != nil {
+			return repository.ListQuery{}, err
+		}
+	}
+
+	return listQuery, nil
+}
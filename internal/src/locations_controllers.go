@@ -0,0 +1,326 @@
+package src
+
+import (
+	"api/internal/apperr"
+	"api/internal/database"
+	"api/internal/logging"
+	"api/internal/models"
+	"api/internal/openinghours"
+	"api/internal/repository"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+func (s *Server) listLocationsController(c *gin.Context, req *models.RequestListsGeneral) (*models.LocationsResponseList, error) {
+	if req.Page == 0 {
+		req.Page = 1
+	}
+
+	if req.Limit == 0 {
+		req.Limit = 10
+	}
+
+	if req.Order == "" || strings.ToUpper(req.Order) != "DESC" {
+		req.Order = "ASC"
+	}
+
+	if req.OrderBy == "" {
+		req.OrderBy = "name"
+	}
+
+	q, err := parseListQuery(c, repository.LocationsSpec, req)
+	if err != nil {
+		logError(c, err)
+		return nil, err
+	}
+
+	// branches are few enough to page by number only
+	if q.Keyset {
+		errMsg := "invalid-paging"
+		logging.From(c).Info(errMsg)
+		return nil, apperr.Validation(errMsg, "paging")
+	}
+
+	q.CountTotal = true
+
+	locationsPage, err := s.locations.List(c, q)
+	if err != nil {
+		logError(c, err)
+		return nil, err
+	}
+
+	return &models.LocationsResponseList{
+		Total:   *locationsPage.Total,
+		OrderBy: req.OrderBy,
+		Order:   req.Order,
+		Page:    req.Page,
+		Limit:   req.Limit,
+		Items:   locationsPage.Items,
+		Message: "success",
+	}, nil
+}
+
+// parseCoordinate reads a latitude or longitude no further than limit
+// degrees from zero.
+func parseCoordinate(value string, limit float64, field string) (float64, error) {
+	coordinate, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+	if err != nil {
+		return 0, apperr.Validation(fmt.Sprintf("failed-parsing-%s", field), field)
+	}
+
+	if coordinate < -limit || coordinate > limit {
+		return 0, apperr.Validation(fmt.Sprintf("invalid-%s", field), field)
+	}
+
+	return coordinate, nil
+}
+
+// parseLocationId reads the optional branch id sent as field, 0 when empty.
+func parseLocationId(value string, field string) (int, error) {
+	if value == "" {
+		return 0, nil
+	}
+
+	locationId, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, apperr.Validation(fmt.Sprintf("wrong-%s-type", strings.ReplaceAll(field, "_", "-")), field)
+	}
+
+	return locationId, nil
+}
+
+func (s *Server) createLocationsController(c *gin.Context, req *models.LocationsRequestCreate) (*models.ResponseGeneral, error) {
+	errMsg := ""
+	if strings.TrimSpace(req.Name) == "" {
+		errMsg = "missing-name"
+		logging.From(c).Info(errMsg)
+		return nil, apperr.Validation(errMsg, "name")
+	}
+
+	var latitude, longitude *float64
+	if req.Latitude != "" || req.Longitude != "" {
+		lat, err := parseCoordinate(req.Latitude, 90, "latitude")
+		if err != nil {
+			logError(c, err)
+			return nil, err
+		}
+
+		lng, err := parseCoordinate(req.Longitude, 180, "longitude")
+		if err != nil {
+			logError(c, err)
+			return nil, err
+		}
+
+		latitude, longitude = &lat, &lng
+	}
+
+	if req.Timezone == "" {
+		req.Timezone = "UTC"
+	}
+
+	_, err := openinghours.LoadZone(req.Timezone)
+	if err != nil {
+		logError(c, err)
+		return nil, err
+	}
+
+	if req.OpeningHours == nil {
+		req.OpeningHours = map[string]string{}
+	}
+
+	err = openinghours.Hours(req.OpeningHours).Validate()
+	if err != nil {
+		logError(c, err)
+		return nil, err
+	}
+
+	locationId, err := s.locations.Create(c, &models.LocationsItem{
+		Name:         strings.TrimSpace(req.Name),
+		Address:      req.Address,
+		Latitude:     latitude,
+		Longitude:    longitude,
+		Timezone:     req.Timezone,
+		OpeningHours: req.OpeningHours,
+	})
+	if err != nil {
+		if database.IsUniqueViolation(err) {
+			err = apperr.Conflict("location-name-taken").Wrap(err)
+		}
+		logError(c, err)
+		return nil, err
+	}
+
+	return &models.ResponseGeneral{
+		Id:      locationId,
+		Message: "success",
+	}, nil
+}
+
+func (s *Server) updateLocationsController(c *gin.Context, req *models.LocationsRequestUpdate) (*models.ResponseGeneral, error) {
+	errorMsg := ""
+	if req.Id == "" {
+		errorMsg = "missing-location-id"
+		logging.From(c).Info(errorMsg)
+		return nil, apperr.Validation(errorMsg, "id")
+	}
+
+	locationId, err := strconv.Atoi(req.Id)
+	if err != nil {
+		errorMsg = "wrong-location-id-type"
+		logging.From(c).Info(errorMsg)
+		return nil, apperr.Validation(errorMsg, "id")
+	}
+
+	var update repository.LocationUpdate
+	updated := false
+	if name := strings.TrimSpace(req.Name); name != "" {
+		update.Name = &name
+		updated = true
+	}
+
+	if req.Address != "" {
+		update.Address = &req.Address
+		updated = true
+	}
+
+	if req.Latitude != "" {
+		latitude, err := parseCoordinate(req.Latitude, 90, "latitude")
+		if err != nil {
+			logError(c, err)
+			return nil, err
+		}
+
+		update.Latitude = &latitude
+		updated = true
+	}
+
+	if req.Longitude != "" {
+		longitude, err := parseCoordinate(req.Longitude, 180, "longitude")
+		if err != nil {
+			logError(c, err)
+			return nil, err
+		}
+
+		update.Longitude = &longitude
+		updated = true
+	}
+
+	if req.Timezone != "" {
+		_, err = openinghours.LoadZone(req.Timezone)
+		if err != nil {
+			logError(c, err)
+			return nil, err
+		}
+
+		update.Timezone = &req.Timezone
+		updated = true
+	}
+
+	// the whole week is replaced, send {} to open around the clock
+	if req.OpeningHours != nil {
+		err = openinghours.Hours(req.OpeningHours).Validate()
+		if err != nil {
+			logError(c, err)
+			return nil, err
+		}
+
+		update.OpeningHours = req.OpeningHours
+		updated = true
+	}
+
+	if !updated {
+		errorMsg = "nothing-to-update"
+		logging.From(c).Info(errorMsg)
+		return nil, apperr.Validation(errorMsg, "")
+	}
+
+	err = s.locations.Update(c, locationId, update)
+	if errors.Is(err, repository.ErrNotFound) {
+		errorMsg = "location-not-found"
+		logging.From(c).Info(errorMsg)
+		return nil, apperr.NotFound(errorMsg)
+	}
+	if err != nil {
+		if database.IsUniqueViolation(err) {
+			err = apperr.Conflict("location-name-taken").Wrap(err)
+		}
+		logError(c, err)
+		return nil, err
+	}
+
+	return &models.ResponseGeneral{
+		Id:      locationId,
+		Message: "success",
+	}, nil
+}
+
+func (s *Server) deleteLocationsController(c *gin.Context, id string) (*models.ResponseGeneral, error) {
+	errorMsg := ""
+	if id == "" {
+		errorMsg = "missing-location-id"
+		logging.From(c).Info(errorMsg)
+		return nil, apperr.Validation(errorMsg, "id")
+	}
+
+	locationId, err := strconv.Atoi(id)
+	if err != nil {
+		errorMsg = "wrong-location-id-type"
+		logging.From(c).Info(errorMsg)
+		return nil, apperr.Validation(errorMsg, "id")
+	}
+
+	err = s.locations.Delete(c, locationId)
+	if errors.Is(err, repository.ErrNotFound) {
+		errorMsg = "location-not-found"
+		logging.From(c).Info(errorMsg)
+		return nil, apperr.NotFound(errorMsg)
+	}
+	if err != nil {
+		if errors.Is(err, repository.ErrInUse) {
+			err = apperr.Conflict("location-in-use").Wrap(err)
+		}
+		logError(c, err)
+		return nil, err
+	}
+
+	return &models.ResponseGeneral{
+		Id:      locationId,
+		Message: "success",
+	}, nil
+}
+
+func (s *Server) getLocationsByIdController(c *gin.Context, id string) (*models.LocationsResponseGet, error) {
+	errorMsg := ""
+	if id == "" {
+		errorMsg = "missing-location-id"
+		logging.From(c).Info(errorMsg)
+		return nil, apperr.Validation(errorMsg, "id")
+	}
+
+	locationId, err := strconv.Atoi(id)
+	if err != nil {
+		errorMsg = "wrong-location-id-type"
+		logging.From(c).Info(errorMsg)
+		return nil, apperr.Validation(errorMsg, "id")
+	}
+
+	var resp models.LocationsResponseGet
+	resp.Item, err = s.locations.Get(c, locationId)
+	if errors.Is(err, repository.ErrNotFound) {
+		errorMsg = "location-not-found"
+		logging.From(c).Info(errorMsg)
+		return nil, apperr.NotFound(errorMsg)
+	}
+	if err != nil {
+		logError(c, err)
+		return nil, err
+	}
+
+	resp.Message = "success"
+
+	return &resp, nil
+}
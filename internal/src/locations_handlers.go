@@ -0,0 +1,85 @@
+package src
+
+import (
+	"api/internal/apperr"
+	"api/internal/models"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+func (s *Server) LocationsListHandler(c *gin.Context) {
+	var listRequest models.RequestListsGeneral
+	err := c.ShouldBindQuery(&listRequest)
+	if err != nil {
+		c.Error(apperr.InvalidRequest(err))
+		return
+	}
+
+	resp, err := s.listLocationsController(c, &listRequest)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+func (s *Server) LocationsCreateHandler(c *gin.Context) {
+	var locationsItem models.LocationsRequestCreate
+	err := c.ShouldBindJSON(&locationsItem)
+	if err != nil {
+		c.Error(apperr.InvalidRequest(err))
+		return
+	}
+
+	resp, err := s.createLocationsController(c, &locationsItem)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+func (s *Server) LocationsUpdateHandler(c *gin.Context) {
+	var locationsItem models.LocationsRequestUpdate
+	err := c.ShouldBindJSON(&locationsItem)
+	if err != nil {
+		c.Error(apperr.InvalidRequest(err))
+		return
+	}
+	locationsItem.Id = c.Param("id")
+
+	resp, err := s.updateLocationsController(c, &locationsItem)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+func (s *Server) LocationsDeleteHandler(c *gin.Context) {
+	locationId := c.Param("id")
+
+	resp, err := s.deleteLocationsController(c, locationId)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+func (s *Server) LocationsGetByIdHandler(c *gin.Context) {
+	locationId := c.Param("id")
+
+	resp, err := s.getLocationsByIdController(c, locationId)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
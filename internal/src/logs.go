@@ -0,0 +1,20 @@
+package src
+
+import (
+	"api/internal/apperr"
+	"api/internal/logging"
+
+	"github.com/gin-gonic/gin"
+)
+
+// logError logs err under the request, at info level when the client got
+// something wrong. Internal errors only go at debug level, apperr.Middleware
+// logs them once they reach it.
+func logError(c *gin.Context, err error) {
+	if apperr.From(err).Code == apperr.CodeInternal {
+		logging.From(c).Debug("request-error", "error", err)
+		return
+	}
+
+	logging.From(c).Info("request-rejected", "error", err)
+}
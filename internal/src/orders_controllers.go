@@ -1,12 +1,17 @@
 package src
 
 import (
+	"api/internal/apperr"
+	"api/internal/auth"
+	"api/internal/booking"
+	"api/internal/logging"
 	"api/internal/models"
-	"api/internal/utils"
-	"database/sql"
+	"api/internal/openinghours"
+	"api/internal/orderstatus"
+	"api/internal/pricing"
+	"api/internal/repository"
 	"errors"
 	"fmt"
-	"log"
 	"strconv"
 	"strings"
 	"time"
@@ -14,7 +19,17 @@ import (
 	"github.com/gin-gonic/gin"
 )
 
-func (s *Server) listOrdersController(c *gin.Context, req *models.RequestListsGeneral) (*models.OrdersResponseList, error) {
+func (s *Server) listOrdersController(c *gin.Context, req *models.OrdersRequestList) (*models.OrdersResponseList, error) {
+	return s.queryOrdersList(c, req, false)
+}
+
+func (s *Server) listDeletedOrdersController(c *gin.Context, req *models.OrdersRequestList) (*models.OrdersResponseList, error) {
+	return s.queryOrdersList(c, req, true)
+}
+
+// queryOrdersList pages through the orders, or the deleted ones, applying
+// the shared search, ordering and paging of req.
+func (s *Server) queryOrdersList(c *gin.Context, req *models.OrdersRequestList, deleted bool) (*models.OrdersResponseList, error) {
 	if req.Page == 0 {
 		req.Page = 1
 	}
@@ -31,184 +46,189 @@ func (s *Server) listOrdersController(c *gin.Context, req *models.RequestListsGe
 		req.OrderBy = "order_date"
 	}
 
-	query := `
-		SELECT
-			order_id,
-			orders.car_id,
-			cars.car_name,
-			order_date,
-			pickup_date,
-			dropoff_date,
-			pickup_location,
-			dropoff_location
-		FROM orders JOIN cars ON orders.car_id=cars.car_id
-	`
-	var params []interface{}
-
-	cmdQuery := ""
-	count := 0
-	if len(req.Search) > 0 {
-		count++
-		cmdQuery = fmt.Sprintf("%s WHERE LOWER(pickup_location) LIKE LOWER($%d)", cmdQuery, count)
-		params = append(params, "%"+utils.Sanitize(req.Search)+"%")
-	}
-
-	// count all of search result
-	total := 0
-	err := s.db.QueryRow(c, fmt.Sprintf("SELECT COUNT(*) AS total FROM orders %s", cmdQuery), params...).Scan(&total)
-	if err != nil {
-		log.Println(err)
-		return nil, err
-	}
-
-	if req.OrderBy != "" {
-		cmdQuery = fmt.Sprintf("%s ORDER BY %s %s", cmdQuery, utils.Sanitize(req.OrderBy), req.Order)
+	// customers only ever list their own orders
+	if customerId, ok := auth.CustomerScope(c); ok {
+		req.CustomerId = customerId
 	}
 
-	count++
-	cmdQuery = fmt.Sprintf("%s LIMIT $%d ", cmdQuery, count)
-	params = append(params, req.Limit)
-
-	count++
-	cmdQuery = fmt.Sprintf("%s OFFSET $%d ", cmdQuery, count)
-	params = append(params, (req.Page-1)*req.Limit)
-
-	rows, err := s.db.Query(c, fmt.Sprintf("%s %s", query, cmdQuery), params...)
+	q, err := parseListQuery(c, repository.OrdersSpec, &req.RequestListsGeneral)
 	if err != nil {
-		log.Println(err)
+		logError(c, err)
 		return nil, err
 	}
-	defer rows.Close()
-
-	var id, carId sql.NullInt64
-	var orderDate, pickupDate, dropoffDate sql.NullTime
-	var pickupLocation, dropoffLocation, carName sql.NullString
-	ordersData := []*models.OrdersItem{}
-	for rows.Next() {
-		item := models.OrdersItem{}
-
-		err = rows.Scan(
-			&id,
-			&carId,
-			&carName,
-			&orderDate,
-			&pickupDate,
-			&dropoffDate,
-			&pickupLocation,
-			&dropoffLocation,
-		)
-
-		item.Id = int(id.Int64)
-		item.CarId = int(carId.Int64)
-		item.CarName = strings.TrimSpace(carName.String)
-		item.OrderDate = orderDate.Time.Format("2006-01-02")
-		item.PickupDate = pickupDate.Time.Format("2006-01-02")
-		item.DropoffDate = dropoffDate.Time.Format("2006-01-02")
-		item.PickupLocation = strings.TrimSpace(pickupLocation.String)
-		item.DropoffLocation = strings.TrimSpace(dropoffLocation.String)
-
-		if err != nil {
-			log.Println(err)
-			return nil, err
-		}
 
-		ordersData = append(ordersData, &item)
+	q.Deleted = deleted
+	ordersPage, err := s.orders.List(c, repository.OrderQuery{
+		ListQuery:  q,
+		CustomerId: req.CustomerId,
+	})
+	if err != nil {
+		logError(c, err)
+		return nil, err
 	}
 
 	return &models.OrdersResponseList{
-		Total:   total,
-		OrderBy: req.OrderBy,
-		Order:   req.Order,
-		Page:    req.Page,
-		Limit:   req.Limit,
-		Items:   ordersData,
-		Message: "success",
+		Total:      ordersPage.Total,
+		OrderBy:    req.OrderBy,
+		Order:      req.Order,
+		Page:       req.Page,
+		Limit:      req.Limit,
+		NextCursor: ordersPage.NextCursor,
+		PrevCursor: ordersPage.PrevCursor,
+		Items:      ordersPage.Items,
+		Message:    "success",
 	}, nil
 }
 
 func (s *Server) createOrdersController(c *gin.Context, req *models.OrdersRequestCreate) (*models.ResponseGeneral, error) {
 	errMsg := ""
+
+	// customers book for themselves whatever customer_id they send
+	if customerId, ok := auth.CustomerScope(c); ok {
+		req.CustomerId = strconv.Itoa(customerId)
+	}
+
 	if req.CarId == "" {
 		errMsg = "missing-car-id"
-		log.Println(errMsg)
-		return nil, errors.New(errMsg)
+		logging.From(c).Info(errMsg)
+		return nil, apperr.Validation(errMsg, "car_id")
 	}
 
-	carIdNum, err := strconv.Atoi(req.CarId)
+	_, err := strconv.Atoi(req.CarId)
 	if err != nil {
 		errMsg = "wrong-cars-id-type"
-		log.Println(errMsg)
-		return nil, errors.New(errMsg)
+		logging.From(c).Info(errMsg)
+		return nil, apperr.Validation(errMsg, "car_id")
+	}
+
+	if req.CustomerId == "" {
+		errMsg = "missing-customer-id"
+		logging.From(c).Info(errMsg)
+		return nil, apperr.Validation(errMsg, "customer_id")
+	}
+
+	customerIdNum, err := strconv.Atoi(req.CustomerId)
+	if err != nil {
+		errMsg = "wrong-customer-id-type"
+		logging.From(c).Info(errMsg)
+		return nil, apperr.Validation(errMsg, "customer_id")
 	}
 
 	if req.OrderDate == "" {
 		errMsg = "missing-order-date"
-		log.Println(errMsg)
-		return nil, errors.New(errMsg)
+		logging.From(c).Info(errMsg)
+		return nil, apperr.Validation(errMsg, "order_date")
 	}
 
 	_, err = time.Parse("2006-01-02", req.OrderDate)
 	if err != nil {
 		errMsg = "failed-parsing-order-date"
-		log.Println(err)
-		return nil, errors.New(errMsg)
+		logError(c, err)
+		return nil, apperr.Validation(errMsg, "order_date")
 	}
 
 	if req.PickupDate == "" {
 		errMsg = "missing-pickup-date"
-		log.Println(errMsg)
-		return nil, errors.New(errMsg)
+		logging.From(c).Info(errMsg)
+		return nil, apperr.Validation(errMsg, "pickup_date")
 	}
 
-	_, err = time.Parse("2006-01-02", req.PickupDate)
+	_, err = booking.ParseTime(req.PickupDate)
 	if err != nil {
 		errMsg = "failed-parsing-pickup-date"
-		log.Println(err)
-		return nil, errors.New(errMsg)
+		logError(c, err)
+		return nil, apperr.Validation(errMsg, "pickup_date")
 	}
 
 	if req.DropoffDate == "" {
 		errMsg = "missing-dropoff-date"
-		log.Println(errMsg)
-		return nil, errors.New(errMsg)
+		logging.From(c).Info(errMsg)
+		return nil, apperr.Validation(errMsg, "dropoff_date")
 	}
 
-	_, err = time.Parse("2006-01-02", req.DropoffDate)
+	_, err = booking.ParseTime(req.DropoffDate)
 	if err != nil {
 		errMsg = "failed-parsing-dropoff-date"
-		log.Println(err)
-		return nil, errors.New(errMsg)
+		logError(c, err)
+		return nil, apperr.Validation(errMsg, "dropoff_date")
 	}
 
-	if req.PickupLocation == "" {
+	if req.PickupLocationId == "" && req.PickupLocation == "" {
 		errMsg = "missing-pickup-location"
-		log.Println(errMsg)
-		return nil, errors.New(errMsg)
+		logging.From(c).Info(errMsg)
+		return nil, apperr.Validation(errMsg, "pickup_location_id")
 	}
 
-	if req.DropoffLocation == "" {
+	if req.DropoffLocationId == "" && req.DropoffLocation == "" {
 		errMsg = "missing-dropoff-location"
-		log.Println(errMsg)
-		return nil, errors.New(errMsg)
+		logging.From(c).Info(errMsg)
+		return nil, apperr.Validation(errMsg, "dropoff_location_id")
 	}
 
-	resCheckCars, err := s.checkCarsIsAlreadyOccupied(c, &models.RequestOrdersCheckOcupiedCars{
-		CarId:      req.CarId,
-		PickupDate: req.PickupDate,
+	carIdNum, dateRange, err := parseOccupancyRequest(&models.RequestOrdersCheckOcupiedCars{
+		CarId:       req.CarId,
+		PickupDate:  req.PickupDate,
+		DropoffDate: req.DropoffDate,
 	})
 	if err != nil {
-		log.Println(err)
+		logError(c, err)
+		return nil, err
+	}
+
+	pickup, err := s.orderLocation(c, req.PickupLocationId, req.PickupLocation, "pickup_location")
+	if err != nil {
+		logError(c, err)
 		return nil, err
 	}
 
-	if resCheckCars.Message == "car-already-occupied" {
-		return nil, errors.New(resCheckCars.Message)
+	dropoff, err := s.orderLocation(c, req.DropoffLocationId, req.DropoffLocation, "dropoff_location")
+	if err != nil {
+		logError(c, err)
+		return nil, err
 	}
 
+	// the availability check and the insert share one serializable
+	// transaction so two concurrent bookings cannot both see the car free
 	var orderId int
-	err = s.db.QueryRow(c, "INSERT INTO orders (car_id, order_date, pickup_date, dropoff_date, pickup_location, dropoff_location) VALUES ($1, $2, $3, $4, $5, $6) RETURNING order_id", carIdNum, req.OrderDate, req.PickupDate, req.DropoffDate, req.PickupLocation, req.DropoffLocation).Scan(&orderId)
+	err = s.orders.Book(c, func(b repository.Booking) error {
+		err := s.checkCarFree(c, b, carIdNum, 0, dateRange)
+		if err != nil {
+			return err
+		}
+
+		err = checkCustomerCanRent(c, b, customerIdNum, dateRange)
+		if err != nil {
+			return err
+		}
+
+		err = checkPickupBranch(c, b, carIdNum, 0, pickup, dateRange)
+		if err != nil {
+			return err
+		}
+
+		// the price is frozen on the order, later rate changes do not affect it
+		quote, err := quoteRental(c, b, carIdNum, dateRange)
+		if err != nil {
+			return err
+		}
+
+		orderId, err = b.Insert(c, &models.OrdersItem{
+			CarId:             carIdNum,
+			CustomerId:        customerIdNum,
+			OrderDate:         req.OrderDate,
+			PickupLocation:    pickup.Name,
+			DropoffLocation:   dropoff.Name,
+			PickupLocationId:  pickup.Id,
+			DropoffLocationId: dropoff.Id,
+			TotalPrice:        quote.Total,
+		}, dateRange, dateRange.Pad(s.turnaround).End)
+		return err
+	})
 	if err != nil {
-		log.Println(err)
+		if errors.Is(err, repository.ErrOverlap) {
+			err = apperr.Conflict("car-already-occupied").Wrap(err)
+		}
+		logError(c, err)
 		return nil, err
 	}
 
@@ -222,107 +242,193 @@ func (s *Server) updateOrdersController(c *gin.Context, req *models.OrdersReques
 	errorMsg := ""
 	if req.Id == "" {
 		errorMsg = "missing-orders-id"
-		log.Println(errorMsg)
-		return nil, errors.New(errorMsg)
+		logging.From(c).Info(errorMsg)
+		return nil, apperr.Validation(errorMsg, "id")
 	}
 
 	orderId, err := strconv.Atoi(req.Id)
 	if err != nil {
 		errorMsg = "wrong-orders-id-type"
-		log.Println(errorMsg)
-		return nil, errors.New(errorMsg)
+		logging.From(c).Info(errorMsg)
+		return nil, apperr.Validation(errorMsg, "id")
 	}
 
-	query := "UPDATE orders SET"
-	var params []interface{}
-	var set []string
-	count := 0
-
+	var update repository.OrderUpdate
 	if req.CarId != "" {
 		carIdNum, err := strconv.Atoi(req.CarId)
 		if err != nil {
 			errorMsg = "wrong-car-id-type"
-			log.Println(errorMsg)
-			return nil, errors.New(errorMsg)
+			logging.From(c).Info(errorMsg)
+			return nil, apperr.Validation(errorMsg, "car_id")
 		}
 
-		count++
-		set = append(set, fmt.Sprintf("car_id=$%d", count))
-		params = append(params, carIdNum)
+		update.CarId = &carIdNum
+	}
+
+	if req.CustomerId != "" {
+		customerIdNum, err := strconv.Atoi(req.CustomerId)
+		if err != nil {
+			errorMsg = "wrong-customer-id-type"
+			logging.From(c).Info(errorMsg)
+			return nil, apperr.Validation(errorMsg, "customer_id")
+		}
+
+		update.CustomerId = &customerIdNum
 	}
 
 	if req.OrderDate != "" {
 		_, err = time.Parse("2006-01-02", req.OrderDate)
 		if err != nil {
 			errorMsg = "failed-parsing-order-date"
-			log.Println(err)
-			return nil, errors.New(errorMsg)
+			logError(c, err)
+			return nil, apperr.Validation(errorMsg, "order_date")
 		}
 
-		count++
-		set = append(set, fmt.Sprintf("order_date=$%d", count))
-		params = append(params, req.OrderDate)
+		update.OrderDate = &req.OrderDate
 	}
 
 	if req.PickupDate != "" {
-		_, err = time.Parse("2006-01-02", req.PickupDate)
+		pickupDate, err := booking.ParseTime(req.PickupDate)
 		if err != nil {
 			errorMsg = "failed-parsing-pickup-date"
-			log.Println(err)
-			return nil, errors.New(errorMsg)
+			logError(c, err)
+			return nil, apperr.Validation(errorMsg, "pickup_date")
 		}
 
-		count++
-		set = append(set, fmt.Sprintf("pickup_date=$%d", count))
-		params = append(params, req.PickupDate)
+		update.PickupDate = &pickupDate
 	}
 
 	if req.DropoffDate != "" {
-		_, err = time.Parse("2006-01-02", req.DropoffDate)
+		dropoffDate, err := booking.ParseTime(req.DropoffDate)
 		if err != nil {
 			errorMsg = "failed-parsing-dropoff-date"
-			log.Println(err)
-			return nil, errors.New(errorMsg)
+			logError(c, err)
+			return nil, apperr.Validation(errorMsg, "dropoff_date")
 		}
 
-		count++
-		set = append(set, fmt.Sprintf("dropoff_date=$%d", count))
-		params = append(params, req.DropoffDate)
-	}
-
-	if req.PickupLocation != "" {
-		count++
-		set = append(set, fmt.Sprintf("pickup_location=$%d", count))
-		params = append(params, req.PickupLocation)
+		update.DropoffDate = &dropoffDate
 	}
 
-	if req.DropoffLocation != "" {
-		count++
-		set = append(set, fmt.Sprintf("dropoff_location=$%d", count))
-		params = append(params, req.DropoffLocation)
+	if req.DropoffLocationId != "" || req.DropoffLocation != "" {
+		update.Dropoff, err = s.orderLocation(c, req.DropoffLocationId, req.DropoffLocation, "dropoff_location")
+		if err != nil {
+			logError(c, err)
+			return nil, err
+		}
 	}
 
-	count++
-	query = fmt.Sprintf("%s %s WHERE order_id=$%d", query, strings.Join(set, ","), count)
-	params = append(params, orderId)
-
-	if req.CarId != "" && req.PickupDate != "" {
-		resCheckCars, err := s.checkCarsIsAlreadyOccupied(c, &models.RequestOrdersCheckOcupiedCars{
-			CarId:      req.CarId,
-			PickupDate: req.PickupDate,
-		})
+	rebooked := req.CarId != "" || req.CustomerId != "" || req.PickupDate != "" || req.DropoffDate != ""
+	moved := req.PickupLocationId != "" || req.PickupLocation != ""
+	if moved {
+		update.Pickup, err = s.orderLocation(c, req.PickupLocationId, req.PickupLocation, "pickup_location")
 		if err != nil {
-			log.Println(err)
+			logError(c, err)
 			return nil, err
 		}
-		if resCheckCars.Message == "car-already-occupied" {
-			return nil, errors.New(resCheckCars.Message)
-		}
 	}
 
-	_, err = s.db.Exec(c, query, params...)
+	err = s.orders.Book(c, func(b repository.Booking) error {
+		if rebooked || moved {
+			// re-check the booking as it will look after the update, fields
+			// not sent keep their stored value
+			current, err := b.Order(c, orderId)
+			if errors.Is(err, repository.ErrNotFound) {
+				return apperr.NotFound("order-not-found")
+			}
+			if err != nil {
+				return err
+			}
+
+			checkReq := &models.RequestOrdersCheckOcupiedCars{
+				CarId:       strconv.Itoa(current.CarId),
+				PickupDate:  current.PickupDate,
+				DropoffDate: current.DropoffDate,
+			}
+			if req.CarId != "" {
+				checkReq.CarId = req.CarId
+			}
+			if req.PickupDate != "" {
+				checkReq.PickupDate = req.PickupDate
+			}
+			if req.DropoffDate != "" {
+				checkReq.DropoffDate = req.DropoffDate
+			}
+
+			carId, dateRange, err := parseOccupancyRequest(checkReq)
+			if err != nil {
+				return err
+			}
+
+			pickup := update.Pickup
+			if pickup == nil && current.PickupLocationId != 0 {
+				pickup, err = b.Location(c, current.PickupLocationId)
+				if errors.Is(err, repository.ErrNotFound) {
+					return apperr.NotFound("location-not-found")
+				}
+				if err != nil {
+					return err
+				}
+			}
+
+			// orders whose place matched no branch cannot be checked
+			if pickup != nil {
+				err = checkPickupBranch(c, b, carId, orderId, pickup, dateRange)
+				if err != nil {
+					return err
+				}
+			}
+
+			// the turnaround follows the period as it will be, whichever end
+			// moved
+			if req.PickupDate != "" || req.DropoffDate != "" {
+				readyDate := dateRange.Pad(s.turnaround).End
+				update.ReadyDate = &readyDate
+			}
+
+			if rebooked {
+				err = s.checkCarFree(c, b, carId, orderId, dateRange)
+				if err != nil {
+					return err
+				}
+
+				customerId := current.CustomerId
+				if update.CustomerId != nil {
+					customerId = *update.CustomerId
+				}
+
+				// orders booked before customers existed have none to check
+				if customerId != 0 {
+					err = checkCustomerCanRent(c, b, customerId, dateRange)
+					if err != nil {
+						return err
+					}
+				}
+
+				// a changed car or period is a new booking and is priced again
+				quote, err := quoteRental(c, b, carId, dateRange)
+				if err != nil {
+					return err
+				}
+
+				update.TotalPrice = &quote.Total
+			}
+		}
+
+		if update == (repository.OrderUpdate{}) {
+			return apperr.Validation("nothing-to-update", "")
+		}
+
+		err := b.Update(c, orderId, update)
+		if errors.Is(err, repository.ErrNotFound) {
+			return apperr.NotFound("order-not-found")
+		}
+		return err
+	})
 	if err != nil {
-		log.Println(err)
+		if errors.Is(err, repository.ErrOverlap) {
+			err = apperr.Conflict("car-already-occupied").Wrap(err)
+		}
+		logError(c, err)
 		return nil, err
 	}
 
@@ -336,20 +442,25 @@ func (s *Server) deleteOrderController(c *gin.Context, id string) (*models.Respo
 	errorMsg := ""
 	if id == "" {
 		errorMsg = "missing-order-id"
-		log.Println(errorMsg)
-		return nil, errors.New(errorMsg)
+		logging.From(c).Info(errorMsg)
+		return nil, apperr.Validation(errorMsg, "id")
 	}
 
 	orderId, err := strconv.Atoi(id)
 	if err != nil {
 		errorMsg = "wrong-order-id-type"
-		log.Println(errorMsg)
-		return nil, errors.New(errorMsg)
+		logging.From(c).Info(errorMsg)
+		return nil, apperr.Validation(errorMsg, "id")
 	}
 
-	_, err = s.db.Exec(c, "DELETE FROM orders WHERE order_id=$1", orderId)
+	err = s.orders.Delete(c, orderId)
+	if errors.Is(err, repository.ErrNotFound) {
+		errorMsg = "order-not-found"
+		logging.From(c).Info(errorMsg)
+		return nil, apperr.NotFound(errorMsg)
+	}
 	if err != nil {
-		log.Println(err)
+		logError(c, err)
 		return nil, err
 	}
 
@@ -359,105 +470,313 @@ func (s *Server) deleteOrderController(c *gin.Context, id string) (*models.Respo
 	}, nil
 }
 
-func (s *Server) checkCarsIsAlreadyOccupied(c *gin.Context, req *models.RequestOrdersCheckOcupiedCars) (*models.ResponseGeneral, error) {
+func (s *Server) restoreOrderController(c *gin.Context, id string) (*models.ResponseGeneral, error) {
 	errorMsg := ""
+	if id == "" {
+		errorMsg = "missing-order-id"
+		logging.From(c).Info(errorMsg)
+		return nil, apperr.Validation(errorMsg, "id")
+	}
+
+	orderId, err := strconv.Atoi(id)
+	if err != nil {
+		errorMsg = "wrong-order-id-type"
+		logging.From(c).Info(errorMsg)
+		return nil, apperr.Validation(errorMsg, "id")
+	}
+
+	err = s.orders.Restore(c, orderId)
+	if err != nil {
+		switch {
+		case errors.Is(err, repository.ErrNotFound):
+			err = apperr.NotFound("order-not-found")
+		case errors.Is(err, repository.ErrCarDeleted):
+			err = apperr.Conflict("car-deleted")
+		case errors.Is(err, repository.ErrOverlap):
+			// the car was booked for that time while the order was deleted
+			err = apperr.Conflict("car-already-occupied").Wrap(err)
+		}
+		logError(c, err)
+		return nil, err
+	}
+
+	return &models.ResponseGeneral{
+		Id:      orderId,
+		Message: "success",
+	}, nil
+}
+
+func (s *Server) checkCarsIsAlreadyOccupied(c *gin.Context, req *models.RequestOrdersCheckOcupiedCars) (*models.OrdersResponseCheckOccupied, error) {
+	carId, dateRange, err := parseOccupancyRequest(req)
+	if err != nil {
+		logError(c, err)
+		return nil, err
+	}
+
+	occupancy, err := s.orders.Occupancy(c, carId, req.ExcludeOrderId, dateRange.Pad(s.turnaround))
+	if err != nil {
+		logError(c, err)
+		return nil, err
+	}
+
+	// anyone may ask, so only the periods are told and not who booked the
+	// car, for how much or why it is blocked
+	resp := &models.OrdersResponseCheckOccupied{
+		Message:   "car-available",
+		Conflicts: []*models.OrdersPeriod{},
+		Blocks:    []*models.CarBlocksPeriod{},
+	}
+	for _, order := range occupancy.Orders {
+		resp.Conflicts = append(resp.Conflicts, &models.OrdersPeriod{
+			Id:          order.Id,
+			PickupDate:  order.PickupDate,
+			DropoffDate: order.DropoffDate,
+		})
+	}
+	for _, block := range occupancy.Blocks {
+		resp.Blocks = append(resp.Blocks, &models.CarBlocksPeriod{
+			Id:        block.Id,
+			StartDate: block.StartDate,
+			EndDate:   block.EndDate,
+		})
+	}
+
+	switch {
+	case len(resp.Conflicts) > 0:
+		resp.Message = "car-already-occupied"
+	case len(resp.Blocks) > 0:
+		resp.Message = "car-blocked"
+	}
 
+	return resp, nil
+}
+
+func parseOccupancyRequest(req *models.RequestOrdersCheckOcupiedCars) (int, booking.Range, error) {
 	if req.CarId == "" {
-		errorMsg = "missing-car-id"
-		log.Println(errorMsg)
-		return nil, errors.New(errorMsg)
+		return 0, booking.Range{}, apperr.Validation("missing-car-id", "car_id")
+	}
+
+	carId, err := strconv.Atoi(req.CarId)
+	if err != nil {
+		return 0, booking.Range{}, apperr.Validation("wrong-car-id-type", "car_id")
 	}
 
 	if req.PickupDate == "" {
-		errorMsg = "missing-pickup-date"
-		log.Println(errorMsg)
-		return nil, errors.New(errorMsg)
+		return 0, booking.Range{}, apperr.Validation("missing-pickup-date", "pickup_date")
+	}
+
+	if req.DropoffDate == "" {
+		return 0, booking.Range{}, apperr.Validation("missing-dropoff-date", "dropoff_date")
 	}
 
-	_, err := time.Parse("2006-01-02", req.PickupDate)
+	dateRange, err := booking.ParseRange(req.PickupDate, req.DropoffDate)
 	if err != nil {
-		errorMsg = "failed-parsing-pickup-date"
-		log.Println(err)
-		return nil, errors.New(errorMsg)
+		return 0, booking.Range{}, err
 	}
 
-	// check the is the cars is ordered in order_date ?
-	var usedCars int
-	err = s.db.QueryRow(c, "SELECT COUNT(*) FROM orders WHERE dropoff_date >= $1 AND car_id=$2", req.PickupDate, req.CarId).Scan(&usedCars)
+	return carId, dateRange, nil
+}
+
+// checkCarFree makes sure no order but excludeOrderId holds carId and no
+// block takes it off the road during dateRange or the turnaround around it.
+func (s *Server) checkCarFree(c *gin.Context, b repository.Booking, carId, excludeOrderId int, dateRange booking.Range) error {
+	occupancy, err := b.Occupancy(c, carId, excludeOrderId, dateRange.Pad(s.turnaround))
+	if err != nil {
+		return err
+	}
+
+	if len(occupancy.Orders) > 0 {
+		return apperr.Conflict("car-already-occupied")
+	}
+
+	if len(occupancy.Blocks) > 0 {
+		return apperr.Conflict("car-blocked")
+	}
+
+	return nil
+}
+
+// checkCustomerCanRent makes sure customerId exists and holds a driving
+// licence that stays valid until the car is dropped off.
+func checkCustomerCanRent(c *gin.Context, b repository.Booking, customerId int, dateRange booking.Range) error {
+	customer, err := b.Customer(c, customerId)
+	if errors.Is(err, repository.ErrNotFound) {
+		return apperr.NotFound("customer-not-found")
+	}
+	if err != nil {
+		return err
+	}
+
+	licenceExpiry, err := time.Parse(booking.DateLayout, customer.LicenceExpiry)
+	if err != nil {
+		return err
+	}
+
+	if licenceExpiry.Before(dateRange.End) {
+		return apperr.Validation("customer-licence-expired", "customer_id")
+	}
+
+	return nil
+}
+
+// quoteRental prices renting carId over dateRange at the rates the car has
+// in the booking.
+func quoteRental(c *gin.Context, b repository.Booking, carId int, dateRange booking.Range) (pricing.Quote, error) {
+	car, err := b.Car(c, carId)
+	if errors.Is(err, repository.ErrNotFound) {
+		return pricing.Quote{}, apperr.NotFound("car-not-found")
+	}
+	if err != nil {
+		return pricing.Quote{}, err
+	}
+
+	return rentalQuote(car, dateRange), nil
+}
+
+// orderLocation resolves the branch field of an order names, by its id or,
+// for clients that only send the location name, by that name.
+func (s *Server) orderLocation(c *gin.Context, id, name, field string) (*models.LocationsItem, error) {
+	locationId, err := parseLocationId(id, field+"_id")
 	if err != nil {
-		log.Println(err)
 		return nil, err
 	}
 
-	if usedCars > 0 {
-		return &models.ResponseGeneral{
-			Message: "car-already-occupied",
-		}, nil
+	var location *models.LocationsItem
+	switch {
+	case locationId != 0:
+		location, err = s.locations.Get(c, locationId)
+	case strings.TrimSpace(name) != "":
+		location, err = s.locations.GetByName(c, name)
+	default:
+		return nil, apperr.Validation(fmt.Sprintf("missing-%s", strings.ReplaceAll(field, "_", "-")), field+"_id")
 	}
 
-	return &models.ResponseGeneral{
-		Message: "car-available",
-	}, nil
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, apperr.NotFound("location-not-found")
+	}
+
+	return location, err
+}
+
+// checkPickupBranch makes sure pickup is open at the pickup time, or on the
+// pickup day for whole day rentals, and that carId
+// will be standing there: dropped off by its previous order still holding
+// it, or parked there now when there is none. Cars nobody recorded a branch
+// for are let through.
+func checkPickupBranch(c *gin.Context, b repository.Booking, carId, excludeOrderId int, pickup *models.LocationsItem, dateRange booking.Range) error {
+	hours := openinghours.Hours(pickup.OpeningHours)
+	open := hours.OpenOn(dateRange.Start)
+	if !dateRange.WholeDays {
+		zone, err := openinghours.LoadZone(pickup.Timezone)
+		if err != nil {
+			return err
+		}
+
+		open = hours.OpenAt(dateRange.Start, zone)
+	}
+
+	if !open {
+		return apperr.Validation("pickup-outside-opening-hours", "pickup_date")
+	}
+
+	locationId, err := b.CarLocation(c, carId, excludeOrderId, dateRange.Start)
+	if errors.Is(err, repository.ErrNotFound) {
+		return apperr.NotFound("car-not-found")
+	}
+	if err != nil {
+		return err
+	}
+
+	if locationId != 0 && locationId != pickup.Id {
+		return apperr.Validation("car-not-at-pickup-location", "pickup_location_id")
+	}
+
+	return nil
 }
 
 func (s *Server) getOrderByIdController(c *gin.Context, id string) (*models.OrdersResponseGet, error) {
 	errorMsg := ""
 	if id == "" {
 		errorMsg = "missing-order-id"
-		log.Println(errorMsg)
-		return nil, errors.New(errorMsg)
+		logging.From(c).Info(errorMsg)
+		return nil, apperr.Validation(errorMsg, "id")
 	}
 
-	carId, err := strconv.Atoi(id)
+	orderId, err := strconv.Atoi(id)
 	if err != nil {
 		errorMsg = "wrong-order-id-type"
-		log.Println(errorMsg)
-		return nil, errors.New(errorMsg)
+		logging.From(c).Info(errorMsg)
+		return nil, apperr.Validation(errorMsg, "id")
 	}
 
 	var resp models.OrdersResponseGet
-	var resId, resCarId sql.NullInt64
-	var orderDate, pickupDate, dropoffDate sql.NullTime
-	var pickupLocation, dropoffLocation, carName sql.NullString
-	err = s.db.QueryRow(c, `
-		SELECT 
-			order_id,
-			orders.car_id,
-			cars.car_name,
-			order_date, 
-			pickup_date, 
-			dropoff_date,
-			pickup_location,
-			dropoff_location
-		FROM orders JOIN cars ON orders.car_id=cars.car_id WHERE orders.order_id = $1
-		`, carId).Scan(
-		&resId,
-		&resCarId,
-		&carName,
-		&orderDate,
-		&pickupDate,
-		&dropoffDate,
-		&pickupLocation,
-		&dropoffLocation,
-	)
-
-	resp.Item = &models.OrdersItem{
-		Id:              int(resId.Int64),
-		CarId:           int(resCarId.Int64),
-		CarName:         strings.TrimSpace(carName.String),
-		OrderDate:       orderDate.Time.Format("2006-01-02"),
-		PickupDate:      pickupDate.Time.Format("2006-01-02"),
-		DropoffDate:     dropoffDate.Time.Format("2006-01-02"),
-		PickupLocation:  strings.TrimSpace(pickupLocation.String),
-		DropoffLocation: strings.TrimSpace(dropoffLocation.String),
+	resp.Item, err = s.orders.Get(c, orderId)
+	if errors.Is(err, repository.ErrNotFound) {
+		errorMsg = "order-not-found"
+		logging.From(c).Info(errorMsg)
+		return nil, apperr.NotFound(errorMsg)
 	}
-
 	if err != nil {
-		log.Println(err)
+		logError(c, err)
 		return nil, err
 	}
 
+	// another customer's order answers as if it did not exist
+	if scope, ok := auth.CustomerScope(c); ok && resp.Item.CustomerId != scope {
+		errorMsg = "order-not-found"
+		logging.From(c).Info(errorMsg)
+		return nil, apperr.NotFound(errorMsg)
+	}
+
 	resp.Message = "success"
 
 	return &resp, nil
 }
+
+func (s *Server) transitionOrderController(c *gin.Context, id string, to orderstatus.Status) (*models.ResponseGeneral, error) {
+	errorMsg := ""
+	if id == "" {
+		errorMsg = "missing-order-id"
+		logging.From(c).Info(errorMsg)
+		return nil, apperr.Validation(errorMsg, "id")
+	}
+
+	orderId, err := strconv.Atoi(id)
+	if err != nil {
+		errorMsg = "wrong-order-id-type"
+		logging.From(c).Info(errorMsg)
+		return nil, apperr.Validation(errorMsg, "id")
+	}
+
+	err = s.orders.Transition(c, orderId, to, func(order *models.OrdersItem) error {
+		// another customer's order answers as if it did not exist
+		if scope, ok := auth.CustomerScope(c); ok && order.CustomerId != scope {
+			return apperr.NotFound("order-not-found")
+		}
+
+		if !orderstatus.CanTransition(orderstatus.Status(order.Status), to) {
+			return &apperr.Error{
+				Code:    apperr.CodeConflict,
+				Message: "invalid-status-transition",
+				Fields: []apperr.FieldError{{
+					Field:  "status",
+					Reason: fmt.Sprintf("cannot-move-from-%s-to-%s", order.Status, to),
+				}},
+			}
+		}
+
+		return nil
+	})
+	if errors.Is(err, repository.ErrNotFound) {
+		err = apperr.NotFound("order-not-found")
+	}
+	if err != nil {
+		logError(c, err)
+		return nil, err
+	}
+
+	return &models.ResponseGeneral{
+		Id:      orderId,
+		Message: "success",
+	}, nil
+}
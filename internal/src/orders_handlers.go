@@ -1,37 +1,44 @@
 package src
 
 import (
+	"api/internal/apperr"
+	"api/internal/booking"
 	"api/internal/models"
-	"log"
+	"api/internal/orderstatus"
 	"net/http"
-	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 )
 
 func (s *Server) OrdersListHandler(c *gin.Context) {
-	var listRequest models.RequestListsGeneral
-	err := c.BindQuery(&listRequest)
+	var listRequest models.OrdersRequestList
+	err := c.ShouldBindQuery(&listRequest)
 	if err != nil {
-		log.Println(err)
-		c.JSON(http.StatusBadRequest, &models.OrdersResponseList{
-			Message: err.Error(),
-		})
+		c.Error(apperr.InvalidRequest(err))
 		return
 	}
 
 	resp, err := s.listOrdersController(c, &listRequest)
 	if err != nil {
-		if strings.Contains(err.Error(), "missing") {
-			c.JSON(http.StatusBadRequest, &models.OrdersResponseList{
-				Message: err.Error(),
-			})
-			return
-		}
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
 
-		c.JSON(http.StatusInternalServerError, &models.OrdersResponseList{
-			Message: err.Error(),
-		})
+func (s *Server) OrdersListDeletedHandler(c *gin.Context) {
+	var listRequest models.OrdersRequestList
+	err := c.ShouldBindQuery(&listRequest)
+	if err != nil {
+		c.Error(apperr.InvalidRequest(err))
+		return
+	}
+
+	resp, err := s.listDeletedOrdersController(c, &listRequest)
+	if err != nil {
+		c.Error(err)
 		return
 	}
 
@@ -42,25 +49,13 @@ func (s *Server) OrdersCreateHandler(c *gin.Context) {
 	var orderItems models.OrdersRequestCreate
 	err := c.ShouldBindJSON(&orderItems)
 	if err != nil {
-		log.Println(err)
-		c.JSON(http.StatusBadRequest, &models.ResponseGeneral{
-			Message: err.Error(),
-		})
+		c.Error(apperr.InvalidRequest(err))
 		return
 	}
 
 	resp, err := s.createOrdersController(c, &orderItems)
 	if err != nil {
-		if strings.Contains(err.Error(), "missing") {
-			c.JSON(http.StatusBadRequest, &models.ResponseGeneral{
-				Message: err.Error(),
-			})
-			return
-		}
-
-		c.JSON(http.StatusInternalServerError, &models.ResponseGeneral{
-			Message: err.Error(),
-		})
+		c.Error(err)
 		return
 	}
 
@@ -71,26 +66,14 @@ func (s *Server) OrdersUpdateHandler(c *gin.Context) {
 	var ordersItem models.OrdersRequestUpdate
 	err := c.ShouldBindJSON(&ordersItem)
 	if err != nil {
-		log.Println(err)
-		c.JSON(http.StatusBadRequest, &models.ResponseGeneral{
-			Message: err.Error(),
-		})
+		c.Error(apperr.InvalidRequest(err))
 		return
 	}
 	ordersItem.Id = c.Param("id")
 
 	resp, err := s.updateOrdersController(c, &ordersItem)
 	if err != nil {
-		if strings.Contains(err.Error(), "missing") {
-			c.JSON(http.StatusBadRequest, &models.ResponseGeneral{
-				Message: err.Error(),
-			})
-			return
-		}
-
-		c.JSON(http.StatusInternalServerError, &models.ResponseGeneral{
-			Message: err.Error(),
-		})
+		c.Error(err)
 		return
 	}
 
@@ -102,16 +85,19 @@ func (s *Server) OrdersDeleteHandler(c *gin.Context) {
 
 	resp, err := s.deleteOrderController(c, orderId)
 	if err != nil {
-		if strings.Contains(err.Error(), "missing") {
-			c.JSON(http.StatusBadRequest, &models.ResponseGeneral{
-				Message: err.Error(),
-			})
-			return
-		}
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+func (s *Server) OrdersRestoreHandler(c *gin.Context) {
+	orderId := c.Param("id")
 
-		c.JSON(http.StatusInternalServerError, &models.ResponseGeneral{
-			Message: err.Error(),
-		})
+	resp, err := s.restoreOrderController(c, orderId)
+	if err != nil {
+		c.Error(err)
 		return
 	}
 
@@ -123,16 +109,7 @@ func (s *Server) OrdersGetByIdHandler(c *gin.Context) {
 
 	resp, err := s.getOrderByIdController(c, orderId)
 	if err != nil {
-		if strings.Contains(err.Error(), "missing") {
-			c.JSON(http.StatusBadRequest, &models.ResponseGeneral{
-				Message: err.Error(),
-			})
-			return
-		}
-
-		c.JSON(http.StatusInternalServerError, &models.ResponseGeneral{
-			Message: err.Error(),
-		})
+		c.Error(err)
 		return
 	}
 
@@ -142,22 +119,55 @@ func (s *Server) OrdersGetByIdHandler(c *gin.Context) {
 func (s *Server) OrdersCheckCarsHandler(c *gin.Context) {
 	carId := c.Param("car_id")
 	pickupDate := c.Param("pickup_date")
+	dropoffDate := c.Query("dropoff_date")
+
+	// without a dropoff date the check covers a day from pickup, a whole day
+	// when pickup is a bare date
+	if dropoffDate == "" {
+		pickup, err := booking.ParseTime(pickupDate)
+		if err == nil {
+			dropoffDate = booking.FormatTime(pickup.AddDate(0, 0, 1))
+			if _, err := time.Parse(booking.DateLayout, pickupDate); err == nil {
+				dropoffDate = pickup.AddDate(0, 0, 1).Format(booking.DateLayout)
+			}
+		}
+	}
 
 	resp, err := s.checkCarsIsAlreadyOccupied(c, &models.RequestOrdersCheckOcupiedCars{
-		CarId:      carId,
-		PickupDate: pickupDate,
+		CarId:       carId,
+		PickupDate:  pickupDate,
+		DropoffDate: dropoffDate,
 	})
 	if err != nil {
-		if strings.Contains(err.Error(), "missing") {
-			c.JSON(http.StatusBadRequest, &models.ResponseGeneral{
-				Message: err.Error(),
-			})
-			return
-		}
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
 
-		c.JSON(http.StatusInternalServerError, &models.ResponseGeneral{
-			Message: err.Error(),
-		})
+func (s *Server) OrdersPickupHandler(c *gin.Context) {
+	s.ordersTransition(c, orderstatus.PickedUp)
+}
+
+func (s *Server) OrdersReturnHandler(c *gin.Context) {
+	s.ordersTransition(c, orderstatus.Returned)
+}
+
+func (s *Server) OrdersCancelHandler(c *gin.Context) {
+	s.ordersTransition(c, orderstatus.Cancelled)
+}
+
+func (s *Server) OrdersNoShowHandler(c *gin.Context) {
+	s.ordersTransition(c, orderstatus.NoShow)
+}
+
+func (s *Server) ordersTransition(c *gin.Context, to orderstatus.Status) {
+	orderId := c.Param("id")
+
+	resp, err := s.transitionOrderController(c, orderId, to)
+	if err != nil {
+		c.Error(err)
 		return
 	}
 
@@ -0,0 +1,64 @@
+package src
+
+import (
+	"api/internal/apperr"
+	"api/internal/booking"
+	"api/internal/logging"
+	"api/internal/models"
+	"api/internal/pricing"
+	"api/internal/repository"
+	"errors"
+
+	"github.com/gin-gonic/gin"
+)
+
+func (s *Server) createQuotesController(c *gin.Context, req *models.QuotesRequestCreate) (*models.QuotesResponseGet, error) {
+	carId, dateRange, err := parseOccupancyRequest(&models.RequestOrdersCheckOcupiedCars{
+		CarId:       req.CarId,
+		PickupDate:  req.PickupDate,
+		DropoffDate: req.DropoffDate,
+	})
+	if err != nil {
+		logError(c, err)
+		return nil, err
+	}
+
+	car, err := s.cars.Get(c, carId)
+	if errors.Is(err, repository.ErrNotFound) {
+		errMsg := "car-not-found"
+		logging.From(c).Info(errMsg)
+		return nil, apperr.NotFound(errMsg)
+	}
+	if err != nil {
+		logError(c, err)
+		return nil, err
+	}
+
+	quote := rentalQuote(car, dateRange)
+
+	return &models.QuotesResponseGet{
+		Message: "success",
+		Item: &models.QuotesItem{
+			CarId:       carId,
+			PickupDate:  booking.FormatTime(dateRange.Start),
+			DropoffDate: booking.FormatTime(dateRange.End),
+			Days:        quote.Days,
+			Months:      quote.Months,
+			ExtraDays:   quote.ExtraDays,
+			Hours:       quote.Hours,
+			DayRate:     quote.DayRate,
+			MonthRate:   quote.MonthRate,
+			HourRate:    quote.HourRate,
+			TotalPrice:  quote.Total,
+		},
+	}, nil
+}
+
+// rentalQuote prices renting car over dateRange at the car's current rates.
+func rentalQuote(car *models.CarsItem, dateRange booking.Range) pricing.Quote {
+	return pricing.Calculate(pricing.Rates{
+		DayRate:   car.DayRate,
+		MonthRate: car.MonthRate,
+		HourRate:  car.HourRate,
+	}, dateRange)
+}
@@ -0,0 +1,26 @@
+package src
+
+import (
+	"api/internal/apperr"
+	"api/internal/models"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+func (s *Server) QuotesCreateHandler(c *gin.Context) {
+	var quoteRequest models.QuotesRequestCreate
+	err := c.ShouldBindJSON(&quoteRequest)
+	if err != nil {
+		c.Error(apperr.InvalidRequest(err))
+		return
+	}
+
+	resp, err := s.createQuotesController(c, &quoteRequest)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
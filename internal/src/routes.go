@@ -1,36 +1,125 @@
 package src
 
 import (
+	"api/internal/apperr"
+	"api/internal/auth"
+	"api/internal/logging"
+	"api/internal/models"
+	"api/internal/storage"
+	"api/internal/tracing"
+	"log/slog"
 	"net/http"
+	"slices"
 
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
 )
 
 func (s *Server) RegisterRoutes() http.Handler {
-	r := gin.Default()
+	r := gin.New()
+	// handlers pass the gin context on to the database, it has to carry the
+	// request span
+	r.ContextWithFallback = true
+	r.Use(tracing.Middleware(s.serviceName))
+	r.Use(logging.Middleware(slog.Default()))
+	if s.metrics != nil {
+		r.Use(s.metrics.Middleware())
+		r.GET("/metrics", gin.WrapH(s.metrics.Handler()))
+	}
+	// a panic unwinds past apperr.Middleware, the envelope is written here
+	r.Use(gin.CustomRecovery(func(c *gin.Context, recovered any) {
+		logging.From(c).Error("panic", "panic", recovered)
+		c.AbortWithStatusJSON(http.StatusInternalServerError, &models.ResponseError{
+			Code:    string(apperr.CodeInternal),
+			Message: "internal-error",
+		})
+	}))
+
+	corsConfig := cors.DefaultConfig()
+	if len(s.corsOrigins) == 0 || slices.Contains(s.corsOrigins, "*") {
+		corsConfig.AllowAllOrigins = true
+	} else {
+		corsConfig.AllowOrigins = s.corsOrigins
+	}
+	// clients may send their own request id and read the one given back
+	corsConfig.AddAllowHeaders(logging.RequestIDHeader)
+	corsConfig.AddExposeHeaders(logging.RequestIDHeader)
+	r.Use(cors.New(corsConfig))
+	r.Use(apperr.Middleware())
+	r.GET("/healthz", s.LivenessHandler)
+	r.GET("/readyz", s.ReadinessHandler)
+	// probes set up before /readyz existed
+	r.GET("/health", s.ReadinessHandler)
+
+	if local, ok := s.images.(*storage.LocalStore); ok {
+		r.Static(storage.LocalURLPath, local.Dir())
+	}
 
-	r.Use(cors.Default())
-	r.GET("/health", s.healthHandler)
 	v1 := r.Group("/api/v1/")
 	{
+		v1.GET("/openapi.json", s.OpenAPIHandler)
+		v1.GET("/docs", s.DocsHandler)
+
+		v1.POST("/auth/login", s.AuthLoginHandler)
+
 		v1.GET("/cars", s.CarsListHandler)
+		v1.GET("/cars/available", s.CarsAvailableHandler)
 		v1.GET("/cars/:id", s.CarsGetByIdHandler)
-		v1.POST("/cars", s.CarsCreateHandler)
-		v1.PUT("/cars/:id", s.CarsUpdateHandler)
-		v1.DELETE("/cars/:id", s.CarsDeleteHandler)
 
-		v1.GET("/orders", s.OrdersListHandler)
-		v1.GET("/orders/:id", s.OrdersGetByIdHandler)
-		v1.POST("/orders", s.OrdersCreateHandler)
-		v1.PUT("/orders/:id", s.OrdersUpdateHandler)
-		v1.DELETE("/orders/:id", s.OrdersDeleteHandler)
+		v1.GET("/locations", s.LocationsListHandler)
+		v1.GET("/locations/:id", s.LocationsGetByIdHandler)
+
+		v1.POST("/quotes", s.QuotesCreateHandler)
 
 		v1.GET("/check-occupied-cars/:car_id/:pickup_date", s.OrdersCheckCarsHandler)
 	}
-	return r
-}
 
-func (s *Server) healthHandler(c *gin.Context) {
-	c.JSON(http.StatusOK, s.db.Health())
+	// any signed in user, controllers narrow customers down to their own orders
+	authenticated := v1.Group("/", s.auth.Middleware())
+	{
+		authenticated.GET("/orders", s.OrdersListHandler)
+		authenticated.GET("/orders/:id", s.OrdersGetByIdHandler)
+		authenticated.POST("/orders", s.OrdersCreateHandler)
+		authenticated.POST("/orders/:id/cancel", s.OrdersCancelHandler)
+	}
+
+	staff := authenticated.Group("/", auth.RequireRole(auth.RoleAdmin, auth.RoleStaff))
+	{
+		staff.GET("/customers", s.CustomersListHandler)
+		staff.GET("/customers/:id", s.CustomersGetByIdHandler)
+		staff.POST("/customers", s.CustomersCreateHandler)
+		staff.PUT("/customers/:id", s.CustomersUpdateHandler)
+		staff.DELETE("/customers/:id", s.CustomersDeleteHandler)
+
+		staff.GET("/cars/:id/blocks", s.CarBlocksListHandler)
+		staff.POST("/cars/:id/blocks", s.CarBlocksCreateHandler)
+		staff.DELETE("/cars/:id/blocks/:block_id", s.CarBlocksDeleteHandler)
+
+		staff.PUT("/orders/:id", s.OrdersUpdateHandler)
+		staff.DELETE("/orders/:id", s.OrdersDeleteHandler)
+		staff.POST("/orders/:id/pickup", s.OrdersPickupHandler)
+		staff.POST("/orders/:id/return", s.OrdersReturnHandler)
+		staff.POST("/orders/:id/no-show", s.OrdersNoShowHandler)
+	}
+
+	admin := authenticated.Group("/", auth.RequireRole(auth.RoleAdmin))
+	{
+		admin.POST("/cars", s.CarsCreateHandler)
+		admin.PUT("/cars/:id", s.CarsUpdateHandler)
+		admin.DELETE("/cars/:id", s.CarsDeleteHandler)
+		admin.POST("/cars/:id/image", s.CarsUploadImageHandler)
+		admin.GET("/cars/deleted", s.CarsListDeletedHandler)
+		admin.POST("/cars/:id/restore", s.CarsRestoreHandler)
+
+		admin.GET("/orders/deleted", s.OrdersListDeletedHandler)
+		admin.POST("/orders/:id/restore", s.OrdersRestoreHandler)
+
+		admin.POST("/locations", s.LocationsCreateHandler)
+		admin.PUT("/locations/:id", s.LocationsUpdateHandler)
+		admin.DELETE("/locations/:id", s.LocationsDeleteHandler)
+
+		admin.POST("/users", s.UsersCreateHandler)
+	}
+
+	return r
 }
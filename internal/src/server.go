@@ -1,36 +1,81 @@
 package src
 
 import (
+	"context"
 	"fmt"
+	"log"
 	"net/http"
-	"os"
-	"strconv"
 	"time"
 
+	"api/internal/auth"
+	"api/internal/config"
 	"api/internal/database"
-
-	_ "github.com/joho/godotenv/autoload"
+	"api/internal/lifecycle"
+	"api/internal/metrics"
+	"api/internal/repository"
+	"api/internal/storage"
 )
 
 type Server struct {
-	port int
-	db   database.Service
+	port      int
+	db        database.Service
+	cars      repository.CarRepository
+	orders    repository.OrderRepository
+	blocks    repository.CarBlockRepository
+	customers repository.CustomerRepository
+	locations repository.LocationRepository
+	users     repository.UserRepository
+	auth      *auth.Manager
+	images    storage.ImageStore
+	// turnaround is kept free between consecutive rentals of a car
+	turnaround time.Duration
+	lifecycle  *lifecycle.Manager
+	// corsOrigins are the origins browsers may call from, any when empty
+	corsOrigins []string
+	metrics     *metrics.Metrics
+	// serviceName names the server in the request spans
+	serviceName string
 }
 
-func NewServer() *http.Server {
-	port, _ := strconv.Atoi(os.Getenv("PORT"))
+// NewServer wires the API up from cfg and registers what has to be closed
+// on shutdown with lc.
+func NewServer(cfg *config.Config, lc *lifecycle.Manager) *http.Server {
+	images, err := storage.New(context.Background(), cfg.Images)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	db := database.New(cfg.Database)
+	lc.OnClose("database", db.Close)
+	repos := repository.NewPostgres(db)
+
 	NewServer := &Server{
-		port: port,
-		db:   database.New(),
+		port:      cfg.Server.Port,
+		db:        db,
+		cars:      repos.Cars(),
+		orders:    repos.Orders(),
+		blocks:    repos.CarBlocks(),
+		customers: repos.Customers(),
+		locations: repos.Locations(),
+		users:     repos.Users(),
+		auth:      auth.NewManager(cfg.Auth.JWTSecret, cfg.Auth.JWTTTL),
+		images:    images,
+
+		turnaround:  cfg.Rentals.Turnaround,
+		lifecycle:   lc,
+		corsOrigins: cfg.Server.CORSOrigins,
+		metrics:     metrics.New(db.Stats, repos.Orders()),
+		serviceName: cfg.Tracing.ServiceName,
 	}
+	NewServer.bootstrapAdmin(cfg.Auth.AdminEmail, cfg.Auth.AdminPassword)
 
 	// Declare Server config
 	server := &http.Server{
 		Addr:         fmt.Sprintf(":%d", NewServer.port),
 		Handler:      NewServer.RegisterRoutes(),
-		IdleTimeout:  time.Minute,
-		ReadTimeout:  10 * time.Second,
-		WriteTimeout: 30 * time.Second,
+		IdleTimeout:  cfg.Server.IdleTimeout,
+		ReadTimeout:  cfg.Server.ReadTimeout,
+		WriteTimeout: cfg.Server.WriteTimeout,
 	}
 
 	return server
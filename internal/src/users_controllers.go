@@ -0,0 +1,181 @@
+package src
+
+import (
+	"api/internal/apperr"
+	"api/internal/auth"
+	"api/internal/logging"
+	"api/internal/models"
+	"api/internal/repository"
+	"context"
+	"errors"
+	"log/slog"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const minPasswordLength = 8
+
+func (s *Server) loginController(c *gin.Context, req *models.UsersRequestLogin) (*models.UsersResponseLogin, error) {
+	errMsg := ""
+	if req.Email == "" {
+		errMsg = "missing-email"
+		logging.From(c).Info(errMsg)
+		return nil, apperr.Validation(errMsg, "email")
+	}
+
+	if req.Password == "" {
+		errMsg = "missing-password"
+		logging.From(c).Info(errMsg)
+		return nil, apperr.Validation(errMsg, "password")
+	}
+
+	user, err := s.users.GetByEmail(c, strings.ToLower(req.Email))
+	if err != nil && !errors.Is(err, repository.ErrNotFound) {
+		logError(c, err)
+		return nil, err
+	}
+
+	// unknown email and wrong password answer the same
+	if errors.Is(err, repository.ErrNotFound) || !auth.CheckPassword(user.PasswordHash, req.Password) {
+		errMsg = "invalid-credentials"
+		logging.From(c).Info(errMsg)
+		return nil, apperr.Unauthorized(errMsg)
+	}
+
+	token, expiresAt, err := s.auth.Issue(user.Id, auth.Role(user.Role), user.CustomerId)
+	if err != nil {
+		logError(c, err)
+		return nil, err
+	}
+
+	return &models.UsersResponseLogin{
+		Message:   "success",
+		Token:     token,
+		ExpiresAt: expiresAt.Format(time.RFC3339),
+		Role:      user.Role,
+	}, nil
+}
+
+func (s *Server) createUsersController(c *gin.Context, req *models.UsersRequestCreate) (*models.ResponseGeneral, error) {
+	errMsg := ""
+	if req.Email == "" {
+		errMsg = "missing-email"
+		logging.From(c).Info(errMsg)
+		return nil, apperr.Validation(errMsg, "email")
+	}
+
+	if req.Password == "" {
+		errMsg = "missing-password"
+		logging.From(c).Info(errMsg)
+		return nil, apperr.Validation(errMsg, "password")
+	}
+
+	if len(req.Password) < minPasswordLength {
+		errMsg = "password-too-short"
+		logging.From(c).Info(errMsg)
+		return nil, apperr.Validation(errMsg, "password")
+	}
+
+	if req.Role == "" {
+		errMsg = "missing-role"
+		logging.From(c).Info(errMsg)
+		return nil, apperr.Validation(errMsg, "role")
+	}
+
+	role, err := auth.ParseRole(req.Role)
+	if err != nil {
+		logError(c, err)
+		return nil, err
+	}
+
+	var customerId int
+	if role == auth.RoleCustomer {
+		if req.CustomerId == "" {
+			errMsg = "missing-customer-id"
+			logging.From(c).Info(errMsg)
+			return nil, apperr.Validation(errMsg, "customer_id")
+		}
+
+		customerId, err = strconv.Atoi(req.CustomerId)
+		if err != nil {
+			errMsg = "wrong-customer-id-type"
+			logging.From(c).Info(errMsg)
+			return nil, apperr.Validation(errMsg, "customer_id")
+		}
+	}
+
+	passwordHash, err := auth.HashPassword(req.Password)
+	if err != nil {
+		logError(c, err)
+		return nil, err
+	}
+
+	userId, err := s.users.Create(c, &models.UsersItem{
+		Email:        strings.ToLower(req.Email),
+		PasswordHash: passwordHash,
+		Role:         string(role),
+		CustomerId:   customerId,
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, repository.ErrDuplicate):
+			err = apperr.Conflict("email-already-registered").Wrap(err)
+		case errors.Is(err, repository.ErrNotFound):
+			err = apperr.NotFound("customer-not-found").Wrap(err)
+		}
+		logError(c, err)
+		return nil, err
+	}
+
+	return &models.ResponseGeneral{
+		Id:      userId,
+		Message: "success",
+	}, nil
+}
+
+// bootstrapAdmin creates the first admin account from email and password
+// when the users table is still empty, so a fresh install has someone able
+// to log in and create the other accounts.
+func (s *Server) bootstrapAdmin(email, password string) {
+	if email == "" {
+		return
+	}
+
+	// never fall back to a well known password
+	if password == "" {
+		slog.Warn("bootstrap-admin-skipped", "reason", "missing-admin-password")
+		return
+	}
+
+	ctx := context.Background()
+	users, err := s.users.Count(ctx)
+	if err != nil {
+		slog.Error("bootstrap-admin-failed", "error", err)
+		return
+	}
+
+	if users > 0 {
+		return
+	}
+
+	passwordHash, err := auth.HashPassword(password)
+	if err != nil {
+		slog.Error("bootstrap-admin-failed", "error", err)
+		return
+	}
+
+	_, err = s.users.Create(ctx, &models.UsersItem{
+		Email:        strings.ToLower(email),
+		PasswordHash: passwordHash,
+		Role:         string(auth.RoleAdmin),
+	})
+	if err != nil {
+		slog.Error("bootstrap-admin-failed", "error", err)
+		return
+	}
+
+	slog.Info("created-admin-user", "email", email)
+}
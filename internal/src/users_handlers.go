@@ -0,0 +1,43 @@
+package src
+
+import (
+	"api/internal/apperr"
+	"api/internal/models"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+func (s *Server) AuthLoginHandler(c *gin.Context) {
+	var loginRequest models.UsersRequestLogin
+	err := c.ShouldBindJSON(&loginRequest)
+	if err != nil {
+		c.Error(apperr.InvalidRequest(err))
+		return
+	}
+
+	resp, err := s.loginController(c, &loginRequest)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+func (s *Server) UsersCreateHandler(c *gin.Context) {
+	var usersItem models.UsersRequestCreate
+	err := c.ShouldBindJSON(&usersItem)
+	if err != nil {
+		c.Error(apperr.InvalidRequest(err))
+		return
+	}
+
+	resp, err := s.createUsersController(c, &usersItem)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
@@ -0,0 +1,33 @@
+package storage
+
+import (
+	"api/internal/config"
+	"context"
+	"errors"
+	"io"
+)
+
+// ImageStore keeps uploaded images and hands back the public URL they are
+// served from.
+type ImageStore interface {
+	Save(ctx context.Context, key, contentType string, r io.Reader, size int64) (string, error)
+	Delete(ctx context.Context, key string) error
+}
+
+// New builds the ImageStore selected by cfg.Store, "local" or "s3".
+func New(ctx context.Context, cfg config.ImagesConfig) (ImageStore, error) {
+	switch cfg.Store {
+	case "local":
+		return NewLocalStore(cfg.LocalDir, cfg.BaseURL)
+	case "s3":
+		return NewS3Store(ctx, S3Config{
+			Endpoint:  cfg.S3Endpoint,
+			AccessKey: cfg.S3AccessKey,
+			SecretKey: cfg.S3SecretKey,
+			Bucket:    cfg.S3Bucket,
+			UseSSL:    cfg.S3UseSSL,
+			PublicURL: cfg.BaseURL,
+		})
+	}
+	return nil, errors.New("unknown IMAGE_STORE " + cfg.Store)
+}
@@ -0,0 +1,96 @@
+package storage_test
+
+import (
+	"api/internal/storage"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ory/dockertest/v3"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_LocalStore(t *testing.T) {
+	dir := t.TempDir()
+	store, err := storage.NewLocalStore(dir, "http://localhost:8080/uploads/")
+	assert.Nil(t, err)
+
+	url, err := store.Save(context.Background(), "cars/1.png", "image/png", strings.NewReader("png"), 3)
+	assert.Nil(t, err)
+	assert.Equal(t, "http://localhost:8080/uploads/cars/1.png", url)
+
+	content, err := os.ReadFile(filepath.Join(dir, "cars", "1.png"))
+	assert.Nil(t, err)
+	assert.Equal(t, "png", string(content))
+
+	_, err = store.Save(context.Background(), "../escape.png", "image/png", strings.NewReader("png"), 3)
+	assert.NotNil(t, err)
+
+	assert.Nil(t, store.Delete(context.Background(), "cars/1.png"))
+	assert.Nil(t, store.Delete(context.Background(), "cars/1.png"))
+	_, err = os.Stat(filepath.Join(dir, "cars", "1.png"))
+	assert.True(t, os.IsNotExist(err))
+}
+
+func Test_S3Store(t *testing.T) {
+	pool, err := dockertest.NewPool("")
+	assert.Nil(t, err)
+
+	// MinIO stands in for S3, which needs a Docker daemon
+	if err = pool.Client.Ping(); err != nil {
+		t.Skipf("docker unavailable: %s", err)
+	}
+
+	user := "minio"
+	pass := "minio-secret"
+
+	resource, err := pool.RunWithOptions(&dockertest.RunOptions{
+		Repository: "minio/minio",
+		Tag:        "latest",
+		Cmd:        []string{"server", "/data"},
+		Env:        []string{"MINIO_ROOT_USER=" + user, "MINIO_ROOT_PASSWORD=" + pass},
+	})
+	assert.Nil(t, err)
+	defer pool.Purge(resource)
+
+	endpoint := fmt.Sprintf("localhost:%s", resource.GetPort("9000/tcp"))
+
+	var store *storage.S3Store
+	err = pool.Retry(func() error {
+		var err error
+		store, err = storage.NewS3Store(context.Background(), storage.S3Config{
+			Endpoint:  endpoint,
+			AccessKey: user,
+			SecretKey: pass,
+			Bucket:    "cars",
+		})
+		return err
+	})
+	assert.Nil(t, err)
+
+	url, err := store.Save(context.Background(), "1.png", "image/png", strings.NewReader("png"), 3)
+	assert.Nil(t, err)
+	assert.Equal(t, "http://"+endpoint+"/cars/1.png", url)
+
+	// browsers fetch the image without credentials
+	resp, err := http.Get(url)
+	assert.Nil(t, err)
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "png", string(body))
+
+	err = store.Delete(context.Background(), "1.png")
+	assert.Nil(t, err)
+
+	resp, err = http.Get(url)
+	assert.Nil(t, err)
+	io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+}
@@ -0,0 +1,77 @@
+package tracing
+
+import (
+	"api/internal/buildinfo"
+	"api/internal/config"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"slices"
+
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/gin-gonic/gin/otelgin"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.24.0"
+)
+
+// untraced are the paths polled by probes and scrapers, a trace each would
+// drown out the ones of actual traffic.
+var untraced = []string{"/healthz", "/readyz", "/health", "/metrics"}
+
+// Setup installs the tracer provider exporting the spans as cfg says and
+// returns the function flushing and stopping it. With the "none" exporter
+// the default no-op provider stays and spans cost next to nothing.
+func Setup(ctx context.Context, cfg config.TracingConfig) (func(context.Context) error, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+
+	var exporter sdktrace.SpanExporter
+	var err error
+	switch cfg.Exporter {
+	case "otlp":
+		exporter, err = otlptracehttp.New(ctx, otlptracehttp.WithEndpointURL(cfg.Endpoint))
+	case "stdout":
+		exporter, err = NewStdoutExporter(os.Stdout)
+	default:
+		return func(context.Context) error { return nil }, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("creating %s trace exporter: %w", cfg.Exporter, err)
+	}
+
+	provider := NewProvider(exporter, cfg.ServiceName)
+	otel.SetTracerProvider(provider)
+
+	return provider.Shutdown, nil
+}
+
+// NewStdoutExporter writes every span to w as JSON, for tests and local
+// debugging.
+func NewStdoutExporter(w io.Writer) (sdktrace.SpanExporter, error) {
+	return stdouttrace.New(stdouttrace.WithWriter(w))
+}
+
+// NewProvider batches the spans of service to exporter.
+func NewProvider(exporter sdktrace.SpanExporter, service string) *sdktrace.TracerProvider {
+	return sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(resource.NewSchemaless(
+			semconv.ServiceName(service),
+			semconv.ServiceVersion(buildinfo.Get().Version),
+		)),
+	)
+}
+
+// Middleware starts a span per request, named after its route and
+// continuing the trace of the caller when it sends a traceparent header.
+func Middleware(service string) gin.HandlerFunc {
+	return otelgin.Middleware(service, otelgin.WithFilter(func(r *http.Request) bool {
+		return !slices.Contains(untraced, r.URL.Path)
+	}))
+}
@@ -0,0 +1,103 @@
+package tracing_test
+
+import (
+	"api/internal/database"
+	"api/internal/tracing"
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel"
+
+	_ "github.com/jackc/pgx/v5/stdlib"
+)
+
+// span is the part of the stdout exporter output the test looks at.
+type span struct {
+	Name        string
+	SpanContext struct{ TraceID, SpanID string }
+	Parent      struct{ TraceID, SpanID string }
+	Attributes  []struct {
+		Key   string
+		Value struct{ Value any }
+	}
+	Status struct{ Code string }
+}
+
+func (s span) attribute(key string) any {
+	for _, attr := range s.Attributes {
+		if attr.Key == key {
+			return attr.Value.Value
+		}
+	}
+	return nil
+}
+
+func Test_RequestAndStatementSpans(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	var out bytes.Buffer
+	exporter, err := tracing.NewStdoutExporter(&out)
+	assert.Nil(t, err)
+	provider := tracing.NewProvider(exporter, "test")
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(provider)
+	defer otel.SetTracerProvider(previous)
+
+	// nothing listens there, the statement fails and its span tells so
+	db, err := sql.Open("pgx", "postgres://test@127.0.0.1:1/test?connect_timeout=1")
+	assert.Nil(t, err)
+	service := database.NewMock(db)
+
+	r := gin.New()
+	r.ContextWithFallback = true
+	r.Use(tracing.Middleware("test"))
+	r.GET("/cars/:id", func(c *gin.Context) {
+		var name string
+		_ = service.QueryRow(c, "SELECT name FROM cars WHERE plate = 'AB 123' AND car_id = $1", c.Param("id")).Scan(&name)
+		c.Status(http.StatusOK)
+	})
+	r.GET("/healthz", func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/cars/7", nil))
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/healthz", nil))
+	// nor do statements outside of a request get a trace of their own
+	_, _ = service.Exec(context.Background(), "DELETE FROM sessions")
+	assert.Nil(t, provider.Shutdown(context.Background()))
+
+	var spans []span
+	decoder := json.NewDecoder(&out)
+	for {
+		var s span
+		err := decoder.Decode(&s)
+		if err == io.EOF {
+			break
+		}
+		assert.Nil(t, err)
+		spans = append(spans, s)
+	}
+
+	// the probe and the statement without a request are left out
+	assert.Len(t, spans, 2)
+	if len(spans) != 2 {
+		return
+	}
+
+	statement, request := spans[0], spans[1]
+	assert.Equal(t, "/cars/:id", request.Name)
+	assert.Equal(t, "SELECT", statement.Name)
+	assert.Equal(t, request.SpanContext.TraceID, statement.Parent.TraceID)
+	assert.Equal(t, request.SpanContext.SpanID, statement.Parent.SpanID)
+	assert.Equal(t, "postgresql", statement.attribute("db.system"))
+	assert.Equal(t, "SELECT name FROM cars WHERE plate = ? AND car_id = $1", statement.attribute("db.statement"))
+	assert.Equal(t, "Error", statement.Status.Code)
+}
@@ -0,0 +1,14 @@
+-- periods a car is off the road, bookings and available-car searches treat
+-- them like orders holding the car
+CREATE TABLE car_blocks (
+    block_id SERIAL PRIMARY KEY NOT NULL,
+    car_id int NOT NULL REFERENCES cars (car_id) ON DELETE CASCADE,
+    start_date TIMESTAMPTZ NOT NULL,
+    end_date TIMESTAMPTZ NOT NULL,
+    block_type VARCHAR(16) NOT NULL CHECK (block_type IN ('maintenance', 'inspection', 'repair')),
+    reason TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    CHECK (end_date > start_date)
+);
+
+CREATE INDEX car_blocks_car_id_idx ON car_blocks (car_id, start_date);
@@ -0,0 +1,27 @@
+ALTER TABLE cars
+    ADD COLUMN make VARCHAR(50) NOT NULL DEFAULT '',
+    ADD COLUMN model VARCHAR(50) NOT NULL DEFAULT '',
+    ADD COLUMN year int CHECK (year BETWEEN 1900 AND 2100),
+    ADD COLUMN category VARCHAR(16) NOT NULL DEFAULT ''
+        CHECK (category IN ('', 'economy', 'compact', 'midsize', 'suv', 'mpv', 'van', 'luxury', 'pickup')),
+    ADD COLUMN seats int CHECK (seats > 0),
+    ADD COLUMN transmission VARCHAR(16) NOT NULL DEFAULT ''
+        CHECK (transmission IN ('', 'manual', 'automatic')),
+    ADD COLUMN fuel_type VARCHAR(16) NOT NULL DEFAULT ''
+        CHECK (fuel_type IN ('', 'petrol', 'diesel', 'hybrid', 'electric')),
+    ADD COLUMN plate_number VARCHAR(20),
+    ADD COLUMN features TEXT[] NOT NULL DEFAULT '{}';
+
+-- plates are stored upper case without surrounding blanks, NULL until known
+CREATE UNIQUE INDEX cars_plate_number_idx ON cars (plate_number);
+
+CREATE INDEX cars_category_idx ON cars (category);
+CREATE INDEX cars_features_idx ON cars USING GIN (features);
+
+-- search_by=any covers the new text columns as well, dropping the column
+-- drops its index too
+ALTER TABLE cars DROP COLUMN search_vector;
+ALTER TABLE cars
+    ADD COLUMN search_vector tsvector
+    GENERATED ALWAYS AS (to_tsvector('simple', car_name || ' ' || make || ' ' || model || ' ' || COALESCE(plate_number, ''))) STORED;
+CREATE INDEX cars_search_vector_idx ON cars USING gin (search_vector);
@@ -0,0 +1,27 @@
+ALTER TABLE cars ADD COLUMN deleted_at TIMESTAMPTZ;
+ALTER TABLE orders ADD COLUMN deleted_at TIMESTAMPTZ;
+
+-- orders whose car was deleted before there was a foreign key get a deleted
+-- stand-in car back, so the key can go on and the orders show in lists again
+INSERT INTO cars (car_id, car_name, day_rate, month_rate, image, deleted_at)
+SELECT DISTINCT orders.car_id, 'Deleted car #' || orders.car_id, 0, 0, '', NOW()
+FROM orders LEFT JOIN cars ON orders.car_id = cars.car_id
+WHERE cars.car_id IS NULL;
+
+SELECT setval(pg_get_serial_sequence('cars', 'car_id'), GREATEST(MAX(car_id), 1)) FROM cars;
+
+ALTER TABLE orders
+    ADD CONSTRAINT orders_car_id_fkey FOREIGN KEY (car_id) REFERENCES cars (car_id);
+CREATE INDEX orders_car_id_idx ON orders (car_id);
+
+-- a deleted car gives its plate up, restoring it fails while another car
+-- holds the plate
+DROP INDEX cars_plate_number_idx;
+CREATE UNIQUE INDEX cars_plate_number_idx ON cars (plate_number) WHERE deleted_at IS NULL;
+
+-- deleted orders no longer hold their car
+ALTER TABLE orders DROP CONSTRAINT orders_no_overlap;
+ALTER TABLE orders
+    ADD CONSTRAINT orders_no_overlap
+    EXCLUDE USING gist (car_id WITH =, tstzrange(pickup_date, ready_date) WITH &&)
+    WHERE (status IN ('reserved', 'picked_up') AND deleted_at IS NULL);
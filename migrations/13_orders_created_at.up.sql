@@ -0,0 +1,9 @@
+-- orders booked before there was a column count as created on their order
+-- date, not all at once when this migration runs
+ALTER TABLE orders ADD COLUMN created_at TIMESTAMPTZ;
+UPDATE orders SET created_at = COALESCE(order_date, NOW());
+ALTER TABLE orders
+    ALTER COLUMN created_at SET DEFAULT NOW(),
+    ALTER COLUMN created_at SET NOT NULL;
+
+CREATE INDEX orders_created_at_idx ON orders (created_at);
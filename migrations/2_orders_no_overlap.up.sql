@@ -0,0 +1,7 @@
+CREATE EXTENSION IF NOT EXISTS btree_gist;
+
+-- tstzrange defaults to '[)' bounds, matching the half-open ranges used by the
+-- availability check: a car dropped off on a day can be picked up that day.
+ALTER TABLE orders
+    ADD CONSTRAINT orders_no_overlap
+    EXCLUDE USING gist (car_id WITH =, tstzrange(pickup_date, dropoff_date) WITH &&);
@@ -0,0 +1,2 @@
+-- price frozen at booking time, orders created before pricing existed stay NULL
+ALTER TABLE orders ADD COLUMN total_price decimal;
@@ -0,0 +1,12 @@
+ALTER TABLE orders
+    ADD COLUMN status VARCHAR(16) NOT NULL DEFAULT 'reserved'
+    CHECK (status IN ('reserved', 'picked_up', 'returned', 'cancelled', 'no_show'));
+
+UPDATE orders SET status = 'returned' WHERE dropoff_date < NOW();
+
+-- only orders that still hold their car take part in the overlap guard
+ALTER TABLE orders DROP CONSTRAINT orders_no_overlap;
+ALTER TABLE orders
+    ADD CONSTRAINT orders_no_overlap
+    EXCLUDE USING gist (car_id WITH =, tstzrange(pickup_date, dropoff_date) WITH &&)
+    WHERE (status IN ('reserved', 'picked_up'));
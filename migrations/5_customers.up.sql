@@ -0,0 +1,12 @@
+CREATE TABLE customers (
+    customer_id SERIAL PRIMARY KEY NOT NULL,
+    name VARCHAR(100) NOT NULL,
+    email VARCHAR(254) NOT NULL UNIQUE,
+    phone VARCHAR(32) NOT NULL,
+    licence_number VARCHAR(32) NOT NULL,
+    licence_expiry DATE NOT NULL
+);
+
+-- orders booked before customers existed keep a NULL customer
+ALTER TABLE orders ADD COLUMN customer_id int REFERENCES customers (customer_id);
+CREATE INDEX orders_customer_id_idx ON orders (customer_id);
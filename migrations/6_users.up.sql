@@ -0,0 +1,8 @@
+CREATE TABLE users (
+    user_id SERIAL PRIMARY KEY NOT NULL,
+    email VARCHAR(254) NOT NULL UNIQUE,
+    password_hash VARCHAR(72) NOT NULL,
+    role VARCHAR(16) NOT NULL CHECK (role IN ('admin', 'staff', 'customer')),
+    customer_id int REFERENCES customers (customer_id),
+    CHECK (role <> 'customer' OR customer_id IS NOT NULL)
+);
@@ -0,0 +1,16 @@
+-- search_by=any matches these documents, the 'simple' configuration keeps
+-- place and car names as typed instead of stemming them as English
+ALTER TABLE cars
+    ADD COLUMN search_vector tsvector
+    GENERATED ALWAYS AS (to_tsvector('simple', car_name)) STORED;
+CREATE INDEX cars_search_vector_idx ON cars USING gin (search_vector);
+
+ALTER TABLE orders
+    ADD COLUMN search_vector tsvector
+    GENERATED ALWAYS AS (to_tsvector('simple', pickup_location || ' ' || dropoff_location)) STORED;
+CREATE INDEX orders_search_vector_idx ON orders USING gin (search_vector);
+
+ALTER TABLE customers
+    ADD COLUMN search_vector tsvector
+    GENERATED ALWAYS AS (to_tsvector('simple', name || ' ' || email)) STORED;
+CREATE INDEX customers_search_vector_idx ON customers USING gin (search_vector);
@@ -0,0 +1,40 @@
+CREATE TABLE locations (
+    location_id SERIAL PRIMARY KEY NOT NULL,
+    -- orders copy the name into their CHAR(50) location columns
+    name VARCHAR(50) NOT NULL,
+    address VARCHAR(255) NOT NULL DEFAULT '',
+    latitude DOUBLE PRECISION CHECK (latitude BETWEEN -90 AND 90),
+    longitude DOUBLE PRECISION CHECK (longitude BETWEEN -180 AND 180),
+    timezone VARCHAR(64) NOT NULL DEFAULT 'UTC',
+    opening_hours JSONB NOT NULL DEFAULT '{}'
+);
+
+-- "Jakarta" and "jakarta " are the same branch
+CREATE UNIQUE INDEX locations_name_idx ON locations (LOWER(name));
+
+-- one branch per place orders were booked with so far, aliases such as "JKT"
+-- still come out as their own branch and have to be merged by hand
+INSERT INTO locations (name)
+SELECT INITCAP(MIN(TRIM(place)))
+FROM (
+    SELECT pickup_location AS place FROM orders
+    UNION ALL
+    SELECT dropoff_location FROM orders
+) places
+WHERE TRIM(place) <> ''
+GROUP BY LOWER(TRIM(place));
+
+ALTER TABLE orders
+    ADD COLUMN pickup_location_id int REFERENCES locations (location_id),
+    ADD COLUMN dropoff_location_id int REFERENCES locations (location_id);
+
+UPDATE orders SET pickup_location_id = locations.location_id
+FROM locations WHERE LOWER(locations.name) = LOWER(TRIM(orders.pickup_location));
+
+UPDATE orders SET dropoff_location_id = locations.location_id
+FROM locations WHERE LOWER(locations.name) = LOWER(TRIM(orders.dropoff_location));
+
+-- NULL until staff record where the car is kept and where it stands now
+ALTER TABLE cars
+    ADD COLUMN home_location_id int REFERENCES locations (location_id),
+    ADD COLUMN current_location_id int REFERENCES locations (location_id);
@@ -0,0 +1,14 @@
+ALTER TABLE cars ADD COLUMN hour_rate decimal;
+
+-- ready_date is the dropoff plus the turnaround buffer the car needed when the
+-- order was written, the overlap guard keeps that time free as well
+ALTER TABLE orders ADD COLUMN ready_date TIMESTAMPTZ;
+UPDATE orders SET ready_date = dropoff_date;
+ALTER TABLE orders ALTER COLUMN ready_date SET NOT NULL;
+ALTER TABLE orders ADD CONSTRAINT orders_ready_after_dropoff CHECK (ready_date >= dropoff_date);
+
+ALTER TABLE orders DROP CONSTRAINT orders_no_overlap;
+ALTER TABLE orders
+    ADD CONSTRAINT orders_no_overlap
+    EXCLUDE USING gist (car_id WITH =, tstzrange(pickup_date, ready_date) WITH &&)
+    WHERE (status IN ('reserved', 'picked_up'));